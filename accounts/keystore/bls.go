@@ -0,0 +1,93 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/types"
+)
+
+// blsKeyDir is the directory of the keystore holding the BLS keys. The account
+// scan skips directories, so the BLS keys are never taken for account keys.
+const blsKeyDir = "bls"
+
+// encryptedBLSKeyJSON is the encrypted BLS secret key a deposited account signs
+// the state roots of blocks with.
+type encryptedBLSKeyJSON struct {
+	Address string     `json:"address"`
+	Pubkey  string     `json:"pubkey"`
+	Crypto  CryptoJSON `json:"crypto"`
+	Version int        `json:"version"`
+}
+
+// BLSKeyFile returns the path of the BLS key of an account in the keystore.
+func BLSKeyFile(keydir string, addr types.Address) string {
+	return filepath.Join(keydir, blsKeyDir, hex.EncodeToString(addr[:]))
+}
+
+// StoreBLSKey encrypts the BLS secret key of an account with the passphrase and
+// writes it to the keystore, replacing an earlier key of the account.
+func StoreBLSKey(keydir string, addr types.Address, key bls.SecretKey, auth string, scryptN, scryptP int) (string, error) {
+	cryptoStruct, err := EncryptDataV3(key.Marshal(), []byte(auth), scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(encryptedBLSKeyJSON{
+		Address: hex.EncodeToString(addr[:]),
+		Pubkey:  hex.EncodeToString(key.PublicKey().Marshal()),
+		Crypto:  cryptoStruct,
+		Version: version,
+	})
+	if err != nil {
+		return "", err
+	}
+	file := BLSKeyFile(keydir, addr)
+	if err := writeKeyFile(file, content); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// LoadBLSKey reads the BLS secret key of an account from the keystore and
+// decrypts it with the passphrase.
+func LoadBLSKey(keydir string, addr types.Address, auth string) (bls.SecretKey, error) {
+	content, err := os.ReadFile(BLSKeyFile(keydir, addr))
+	if err != nil {
+		return nil, err
+	}
+	var keyJSON encryptedBLSKeyJSON
+	if err := json.Unmarshal(content, &keyJSON); err != nil {
+		return nil, err
+	}
+	if keyJSON.Version != version {
+		return nil, fmt.Errorf("version not supported: %v", keyJSON.Version)
+	}
+	if keyJSON.Address != hex.EncodeToString(addr[:]) {
+		return nil, fmt.Errorf("BLS key file holds the key of %s, want %x", keyJSON.Address, addr)
+	}
+	data, err := DecryptDataV3(keyJSON.Crypto, auth)
+	if err != nil {
+		return nil, err
+	}
+	return bls.SecretKeyFromBytes(data)
+}
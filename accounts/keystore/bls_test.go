@@ -0,0 +1,58 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/amazechain/amc/common/crypto/bls"
+	common "github.com/amazechain/amc/common/types"
+)
+
+func TestBLSKeyStoreLoad(t *testing.T) {
+	dir := t.TempDir()
+	addr := common.HexToAddress("45dea0fb0bba44f4fcf290bba71fd57d7117cbb8")
+	key, err := bls.RandKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StoreBLSKey(dir, addr, key, "foo", veryLightScryptN, veryLightScryptP); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadBLSKey(dir, addr, "bar"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("wrong password: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := LoadBLSKey(dir, common.Address{0x01}, "foo"); err == nil {
+		t.Error("loaded the BLS key of an account without one")
+	}
+	loaded, err := LoadBLSKey(dir, addr, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Marshal(), key.Marshal()) {
+		t.Errorf("have key %x, want %x", loaded.Marshal(), key.Marshal())
+	}
+
+	// The BLS keys are not taken for accounts
+	ks := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if accs := ks.Accounts(); len(accs) != 0 {
+		t.Errorf("have accounts %v, want none", accs)
+	}
+}
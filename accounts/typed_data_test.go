@@ -0,0 +1,137 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/math"
+)
+
+// mailTypedData returns the example message of EIP-712 with the addresses
+// formatted by the given function.
+func mailTypedData(format func(string) string) TypedData {
+	return TypedData{
+		Types: Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": {
+				{Name: "name", Type: "string"},
+				{Name: "wallet", Type: "address"},
+			},
+			"Mail": {
+				{Name: "from", Type: "Person"},
+				{Name: "to", Type: "Person"},
+				{Name: "contents", Type: "string"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(1),
+			VerifyingContract: format("0xCCCccccccccccccccccccccccccccccccccccccC"),
+		},
+		Message: TypedDataMessage{
+			"from": map[string]interface{}{
+				"name":   "Cow",
+				"wallet": format("0xcD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"),
+			},
+			"to": map[string]interface{}{
+				"name":   "Bob",
+				"wallet": format("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"),
+			},
+			"contents": "Hello, Bob!",
+		},
+	}
+}
+
+func TestTypedDataHash(t *testing.T) {
+	formats := map[string]func(string) string{
+		"hex": func(addr string) string { return addr },
+		"amc": func(addr string) string { return amcAddressPrefix + addr[2:] },
+	}
+	for name, format := range formats {
+		typedData := mailTypedData(format)
+
+		domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := hexutil.MustDecode("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); !bytes.Equal(domainSeparator, want) {
+			t.Errorf("%s: domain separator mismatch: have %x, want %x", name, domainSeparator, want)
+		}
+		messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := hexutil.MustDecode("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"); !bytes.Equal(messageHash, want) {
+			t.Errorf("%s: message hash mismatch: have %x, want %x", name, messageHash, want)
+		}
+		hash, _, err := TypedDataAndHash(typedData)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := hexutil.MustDecode("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); !bytes.Equal(hash, want) {
+			t.Errorf("%s: hash mismatch: have %x, want %x", name, hash, want)
+		}
+	}
+}
+
+func TestTypedDataInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*TypedData)
+		err    string
+	}{
+		{
+			name: "bad address",
+			modify: func(td *TypedData) {
+				td.Message["from"].(map[string]interface{})["wallet"] = "AMC1234"
+			},
+			err: "doesn't match type 'address'",
+		},
+		{
+			name: "undefined type",
+			modify: func(td *TypedData) {
+				td.Types["Mail"][2].Type = "Message"
+			},
+			err: "is undefined",
+		},
+		{
+			name: "extra data",
+			modify: func(td *TypedData) {
+				td.Message["subject"] = "Hi"
+			},
+			err: "extra data",
+		},
+	}
+	for _, tt := range tests {
+		typedData := mailTypedData(func(addr string) string { return addr })
+		tt.modify(&typedData)
+		if _, _, err := TypedDataAndHash(typedData); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
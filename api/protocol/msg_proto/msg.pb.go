@@ -211,6 +211,10 @@ type ProtocolHandshakeMessage struct {
 	Version       string         `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
 	GenesisHash   *types_pb.H256 `protobuf:"bytes,2,opt,name=genesisHash,proto3" json:"genesisHash,omitempty"`
 	CurrentHeight *types_pb.H256 `protobuf:"bytes,3,opt,name=currentHeight,proto3" json:"currentHeight,omitempty"`
+	ChainID       uint64         `protobuf:"varint,4,opt,name=chainID,proto3" json:"chainID,omitempty"`
+	ForkID        *ForkID        `protobuf:"bytes,5,opt,name=forkID,proto3" json:"forkID,omitempty"`
+	Td            *types_pb.H256 `protobuf:"bytes,6,opt,name=td,proto3" json:"td,omitempty"`
+	Capabilities  []string       `protobuf:"bytes,7,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
 }
 
 func (x *ProtocolHandshakeMessage) Reset() {
@@ -266,6 +270,89 @@ func (x *ProtocolHandshakeMessage) GetCurrentHeight() *types_pb.H256 {
 	return nil
 }
 
+func (x *ProtocolHandshakeMessage) GetChainID() uint64 {
+	if x != nil {
+		return x.ChainID
+	}
+	return 0
+}
+
+func (x *ProtocolHandshakeMessage) GetForkID() *ForkID {
+	if x != nil {
+		return x.ForkID
+	}
+	return nil
+}
+
+func (x *ProtocolHandshakeMessage) GetTd() *types_pb.H256 {
+	if x != nil {
+		return x.Td
+	}
+	return nil
+}
+
+func (x *ProtocolHandshakeMessage) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type ForkID struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`  // CRC32 checksum of the genesis hash and passed fork blocks
+	Next uint64 `protobuf:"varint,2,opt,name=next,proto3" json:"next,omitempty"` // next upcoming fork block, 0 if none
+}
+
+func (x *ForkID) Reset() {
+	*x = ForkID{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_msg_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ForkID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForkID) ProtoMessage() {}
+
+func (x *ForkID) ProtoReflect() protoreflect.Message {
+	mi := &file_msg_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForkID.ProtoReflect.Descriptor instead.
+func (*ForkID) Descriptor() ([]byte, []int) {
+	return file_msg_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ForkID) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *ForkID) GetNext() uint64 {
+	if x != nil {
+		return x.Next
+	}
+	return 0
+}
+
 var File_msg_proto protoreflect.FileDescriptor
 
 var file_msg_proto_rawDesc = []byte{
@@ -294,7 +381,7 @@ var file_msg_proto_rawDesc = []byte{
 	0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x4e, 0x75, 0x6d, 0x62,
 	0x65, 0x72, 0x12, 0x25, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x03, 0x20, 0x01, 0x28,
 	0x0b, 0x32, 0x0f, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x42, 0x6c, 0x6f,
-	0x63, 0x6b, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x9c, 0x01, 0x0a, 0x18, 0x50, 0x72,
+	0x63, 0x6b, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0xa5, 0x02, 0x0a, 0x18, 0x50, 0x72,
 	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x4d,
 	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
 	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
@@ -304,11 +391,22 @@ var file_msg_proto_rawDesc = []byte{
 	0x73, 0x68, 0x12, 0x34, 0x0a, 0x0d, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x48, 0x65, 0x69,
 	0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65,
 	0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x0d, 0x63, 0x75, 0x72, 0x72, 0x65,
-	0x6e, 0x74, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x32, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6d, 0x61, 0x7a, 0x65, 0x63, 0x68, 0x61, 0x69,
-	0x6e, 0x2f, 0x61, 0x6d, 0x63, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63,
-	0x6f, 0x6c, 0x2f, 0x6d, 0x73, 0x67, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x6e, 0x74, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x49, 0x44, 0x12, 0x29, 0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6b, 0x49, 0x44, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6d, 0x73, 0x67, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46,
+	0x6f, 0x72, 0x6b, 0x49, 0x44, 0x52, 0x06, 0x66, 0x6f, 0x72, 0x6b, 0x49, 0x44, 0x12, 0x1e, 0x0a,
+	0x02, 0x74, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x02, 0x74, 0x64, 0x12, 0x22, 0x0a,
+	0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x18, 0x07, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x22, 0x30, 0x0a, 0x06, 0x46, 0x6f, 0x72, 0x6b, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x68,
+	0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x65, 0x78, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x6e,
+	0x65, 0x78, 0x74, 0x42, 0x32, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x61, 0x6d, 0x61, 0x7a, 0x65, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2f, 0x61, 0x6d, 0x63,
+	0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f, 0x6d, 0x73,
+	0x67, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -323,25 +421,28 @@ func file_msg_proto_rawDescGZIP() []byte {
 	return file_msg_proto_rawDescData
 }
 
-var file_msg_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_msg_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_msg_proto_goTypes = []interface{}{
 	(*MessageData)(nil),              // 0: msg_proto.MessageData
 	(*NewBlockMessageData)(nil),      // 1: msg_proto.NewBlockMessageData
 	(*ProtocolHandshakeMessage)(nil), // 2: msg_proto.ProtocolHandshakeMessage
-	(*types_pb.H256)(nil),            // 3: types_pb.H256
-	(*types_pb.Block)(nil),           // 4: types_pb.Block
+	(*ForkID)(nil),                   // 3: msg_proto.ForkID
+	(*types_pb.H256)(nil),            // 4: types_pb.H256
+	(*types_pb.Block)(nil),           // 5: types_pb.Block
 }
 var file_msg_proto_depIdxs = []int32{
-	3, // 0: msg_proto.NewBlockMessageData.hash:type_name -> types_pb.H256
-	3, // 1: msg_proto.NewBlockMessageData.Number:type_name -> types_pb.H256
-	4, // 2: msg_proto.NewBlockMessageData.block:type_name -> types_pb.Block
-	3, // 3: msg_proto.ProtocolHandshakeMessage.genesisHash:type_name -> types_pb.H256
-	3, // 4: msg_proto.ProtocolHandshakeMessage.currentHeight:type_name -> types_pb.H256
-	5, // [5:5] is the sub-list for method output_type
-	5, // [5:5] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	4, // 0: msg_proto.NewBlockMessageData.hash:type_name -> types_pb.H256
+	4, // 1: msg_proto.NewBlockMessageData.Number:type_name -> types_pb.H256
+	5, // 2: msg_proto.NewBlockMessageData.block:type_name -> types_pb.Block
+	4, // 3: msg_proto.ProtocolHandshakeMessage.genesisHash:type_name -> types_pb.H256
+	4, // 4: msg_proto.ProtocolHandshakeMessage.currentHeight:type_name -> types_pb.H256
+	3, // 5: msg_proto.ProtocolHandshakeMessage.forkID:type_name -> msg_proto.ForkID
+	4, // 6: msg_proto.ProtocolHandshakeMessage.td:type_name -> types_pb.H256
+	7, // [7:7] is the sub-list for method output_type
+	7, // [7:7] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_msg_proto_init() }
@@ -386,6 +487,18 @@ func file_msg_proto_init() {
 				return nil
 			}
 		}
+		file_msg_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ForkID); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -393,7 +506,7 @@ func file_msg_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_msg_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
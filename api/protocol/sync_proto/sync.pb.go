@@ -40,17 +40,18 @@ const (
 type SyncType int32
 
 const (
-	SyncType_FINDReq           SyncType = 0
-	SyncType_FindRes           SyncType = 1
-	SyncType_HeaderReq         SyncType = 2
-	SyncType_HeaderRes         SyncType = 3
-	SyncType_BodyReq           SyncType = 4
-	SyncType_BodyRes           SyncType = 5
-	SyncType_StateReq          SyncType = 6
-	SyncType_StateRes          SyncType = 7
-	SyncType_TransactionReq    SyncType = 8
-	SyncType_TransactionRes    SyncType = 9
-	SyncType_PeerInfoBroadcast SyncType = 10
+	SyncType_FINDReq             SyncType = 0
+	SyncType_FindRes             SyncType = 1
+	SyncType_HeaderReq           SyncType = 2
+	SyncType_HeaderRes           SyncType = 3
+	SyncType_BodyReq             SyncType = 4
+	SyncType_BodyRes             SyncType = 5
+	SyncType_StateReq            SyncType = 6
+	SyncType_StateRes            SyncType = 7
+	SyncType_TransactionReq      SyncType = 8
+	SyncType_TransactionRes      SyncType = 9
+	SyncType_PeerInfoBroadcast   SyncType = 10
+	SyncType_TransactionAnnounce SyncType = 11
 )
 
 // Enum value maps for SyncType.
@@ -67,19 +68,21 @@ var (
 		8:  "TransactionReq",
 		9:  "TransactionRes",
 		10: "PeerInfoBroadcast",
+		11: "TransactionAnnounce",
 	}
 	SyncType_value = map[string]int32{
-		"FINDReq":           0,
-		"FindRes":           1,
-		"HeaderReq":         2,
-		"HeaderRes":         3,
-		"BodyReq":           4,
-		"BodyRes":           5,
-		"StateReq":          6,
-		"StateRes":          7,
-		"TransactionReq":    8,
-		"TransactionRes":    9,
-		"PeerInfoBroadcast": 10,
+		"FINDReq":             0,
+		"FindRes":             1,
+		"HeaderReq":           2,
+		"HeaderRes":           3,
+		"BodyReq":             4,
+		"BodyRes":             5,
+		"StateReq":            6,
+		"StateRes":            7,
+		"TransactionReq":      8,
+		"TransactionRes":      9,
+		"PeerInfoBroadcast":   10,
+		"TransactionAnnounce": 11,
 	}
 )
 
@@ -404,7 +407,8 @@ type SyncTransactionRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Bloom []byte `protobuf:"bytes,1,opt,name=bloom,proto3" json:"bloom,omitempty"`
+	Bloom  []byte           `protobuf:"bytes,1,opt,name=bloom,proto3" json:"bloom,omitempty"`
+	Hashes []*types_pb.H256 `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"` // transactions to retrieve by hash
 }
 
 func (x *SyncTransactionRequest) Reset() {
@@ -446,6 +450,13 @@ func (x *SyncTransactionRequest) GetBloom() []byte {
 	return nil
 }
 
+func (x *SyncTransactionRequest) GetHashes() []*types_pb.H256 {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
 type SyncTransactionResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -493,6 +504,53 @@ func (x *SyncTransactionResponse) GetTransactions() []*types_pb.Transaction {
 	return nil
 }
 
+type SyncTransactionAnnounce struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hashes []*types_pb.H256 `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (x *SyncTransactionAnnounce) Reset() {
+	*x = SyncTransactionAnnounce{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sync_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyncTransactionAnnounce) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncTransactionAnnounce) ProtoMessage() {}
+
+func (x *SyncTransactionAnnounce) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncTransactionAnnounce.ProtoReflect.Descriptor instead.
+func (*SyncTransactionAnnounce) Descriptor() ([]byte, []int) {
+	return file_sync_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SyncTransactionAnnounce) GetHashes() []*types_pb.H256 {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
 type SyncPeerInfoBroadcast struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -505,7 +563,7 @@ type SyncPeerInfoBroadcast struct {
 func (x *SyncPeerInfoBroadcast) Reset() {
 	*x = SyncPeerInfoBroadcast{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sync_proto_msgTypes[8]
+		mi := &file_sync_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -518,7 +576,7 @@ func (x *SyncPeerInfoBroadcast) String() string {
 func (*SyncPeerInfoBroadcast) ProtoMessage() {}
 
 func (x *SyncPeerInfoBroadcast) ProtoReflect() protoreflect.Message {
-	mi := &file_sync_proto_msgTypes[8]
+	mi := &file_sync_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -531,7 +589,7 @@ func (x *SyncPeerInfoBroadcast) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncPeerInfoBroadcast.ProtoReflect.Descriptor instead.
 func (*SyncPeerInfoBroadcast) Descriptor() ([]byte, []int) {
-	return file_sync_proto_rawDescGZIP(), []int{8}
+	return file_sync_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SyncPeerInfoBroadcast) GetDifficulty() *types_pb.H256 {
@@ -548,6 +606,156 @@ func (x *SyncPeerInfoBroadcast) GetNumber() *types_pb.H256 {
 	return nil
 }
 
+type SyncStateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Number *types_pb.H256 `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"` // pivot block, zero for the peer's current state snapshot
+	Table  string         `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+	Origin []byte         `protobuf:"bytes,3,opt,name=origin,proto3" json:"origin,omitempty"`
+	Limit  uint64         `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *SyncStateRequest) Reset() {
+	*x = SyncStateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sync_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyncStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncStateRequest) ProtoMessage() {}
+
+func (x *SyncStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncStateRequest.ProtoReflect.Descriptor instead.
+func (*SyncStateRequest) Descriptor() ([]byte, []int) {
+	return file_sync_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SyncStateRequest) GetNumber() *types_pb.H256 {
+	if x != nil {
+		return x.Number
+	}
+	return nil
+}
+
+func (x *SyncStateRequest) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *SyncStateRequest) GetOrigin() []byte {
+	if x != nil {
+		return x.Origin
+	}
+	return nil
+}
+
+func (x *SyncStateRequest) GetLimit() uint64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type SyncStateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Number *types_pb.H256 `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"` // block the state snapshot was taken at
+	Table  string         `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+	Keys   [][]byte       `protobuf:"bytes,3,rep,name=keys,proto3" json:"keys,omitempty"`
+	Values [][]byte       `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	Next   []byte         `protobuf:"bytes,5,opt,name=next,proto3" json:"next,omitempty"` // first key of the next range, empty once the table is complete
+}
+
+func (x *SyncStateResponse) Reset() {
+	*x = SyncStateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sync_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyncStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncStateResponse) ProtoMessage() {}
+
+func (x *SyncStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sync_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncStateResponse.ProtoReflect.Descriptor instead.
+func (*SyncStateResponse) Descriptor() ([]byte, []int) {
+	return file_sync_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SyncStateResponse) GetNumber() *types_pb.H256 {
+	if x != nil {
+		return x.Number
+	}
+	return nil
+}
+
+func (x *SyncStateResponse) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *SyncStateResponse) GetKeys() [][]byte {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *SyncStateResponse) GetValues() [][]byte {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *SyncStateResponse) GetNext() []byte {
+	if x != nil {
+		return x.Next
+	}
+	return nil
+}
+
 type SyncTask struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -564,13 +772,16 @@ type SyncTask struct {
 	//	*SyncTask_SyncTransactionRequest
 	//	*SyncTask_SyncTransactionResponse
 	//	*SyncTask_SyncPeerInfoBroadcast
+	//	*SyncTask_SyncStateRequest
+	//	*SyncTask_SyncStateResponse
+	//	*SyncTask_SyncTransactionAnnounce
 	Payload isSyncTask_Payload `protobuf_oneof:"payload"`
 }
 
 func (x *SyncTask) Reset() {
 	*x = SyncTask{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_sync_proto_msgTypes[9]
+		mi := &file_sync_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -583,7 +794,7 @@ func (x *SyncTask) String() string {
 func (*SyncTask) ProtoMessage() {}
 
 func (x *SyncTask) ProtoReflect() protoreflect.Message {
-	mi := &file_sync_proto_msgTypes[9]
+	mi := &file_sync_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -596,7 +807,7 @@ func (x *SyncTask) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncTask.ProtoReflect.Descriptor instead.
 func (*SyncTask) Descriptor() ([]byte, []int) {
-	return file_sync_proto_rawDescGZIP(), []int{9}
+	return file_sync_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *SyncTask) GetId() uint64 {
@@ -676,6 +887,27 @@ func (x *SyncTask) GetSyncPeerInfoBroadcast() *SyncPeerInfoBroadcast {
 	return nil
 }
 
+func (x *SyncTask) GetSyncStateRequest() *SyncStateRequest {
+	if x, ok := x.GetPayload().(*SyncTask_SyncStateRequest); ok {
+		return x.SyncStateRequest
+	}
+	return nil
+}
+
+func (x *SyncTask) GetSyncStateResponse() *SyncStateResponse {
+	if x, ok := x.GetPayload().(*SyncTask_SyncStateResponse); ok {
+		return x.SyncStateResponse
+	}
+	return nil
+}
+
+func (x *SyncTask) GetSyncTransactionAnnounce() *SyncTransactionAnnounce {
+	if x, ok := x.GetPayload().(*SyncTask_SyncTransactionAnnounce); ok {
+		return x.SyncTransactionAnnounce
+	}
+	return nil
+}
+
 type isSyncTask_Payload interface {
 	isSyncTask_Payload()
 }
@@ -712,6 +944,20 @@ type SyncTask_SyncPeerInfoBroadcast struct {
 	SyncPeerInfoBroadcast *SyncPeerInfoBroadcast `protobuf:"bytes,10,opt,name=syncPeerInfoBroadcast,proto3,oneof"`
 }
 
+type SyncTask_SyncStateRequest struct {
+	//state
+	SyncStateRequest *SyncStateRequest `protobuf:"bytes,11,opt,name=syncStateRequest,proto3,oneof"`
+}
+
+type SyncTask_SyncStateResponse struct {
+	SyncStateResponse *SyncStateResponse `protobuf:"bytes,12,opt,name=syncStateResponse,proto3,oneof"`
+}
+
+type SyncTask_SyncTransactionAnnounce struct {
+	//Transaction announcement
+	SyncTransactionAnnounce *SyncTransactionAnnounce `protobuf:"bytes,13,opt,name=syncTransactionAnnounce,proto3,oneof"`
+}
+
 func (*SyncTask_SyncHeaderRequest) isSyncTask_Payload() {}
 
 func (*SyncTask_SyncHeaderResponse) isSyncTask_Payload() {}
@@ -726,6 +972,12 @@ func (*SyncTask_SyncTransactionResponse) isSyncTask_Payload() {}
 
 func (*SyncTask_SyncPeerInfoBroadcast) isSyncTask_Payload() {}
 
+func (*SyncTask_SyncStateRequest) isSyncTask_Payload() {}
+
+func (*SyncTask_SyncStateResponse) isSyncTask_Payload() {}
+
+func (*SyncTask_SyncTransactionAnnounce) isSyncTask_Payload() {}
+
 var File_sync_proto protoreflect.FileDescriptor
 
 var file_sync_proto_rawDesc = []byte{
@@ -754,82 +1006,123 @@ var file_sync_proto_rawDesc = []byte{
 	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64,
 	0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74, 0x79, 0x70, 0x65,
 	0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x68, 0x65, 0x61,
-	0x64, 0x65, 0x72, 0x73, 0x22, 0x2e, 0x0a, 0x16, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e,
+	0x64, 0x65, 0x72, 0x73, 0x22, 0x56, 0x0a, 0x16, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e,
 	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14,
 	0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x62,
-	0x6c, 0x6f, 0x6f, 0x6d, 0x22, 0x54, 0x0a, 0x17, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
-	0x39, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62,
-	0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x74, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x6f, 0x0a, 0x15, 0x53, 0x79,
-	0x6e, 0x63, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63,
-	0x61, 0x73, 0x74, 0x12, 0x2e, 0x0a, 0x0a, 0x44, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74,
-	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f,
-	0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x0a, 0x44, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75,
-	0x6c, 0x74, 0x79, 0x12, 0x26, 0x0a, 0x06, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48,
-	0x32, 0x35, 0x36, 0x52, 0x06, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0xbd, 0x05, 0x0a, 0x08,
-	0x53, 0x79, 0x6e, 0x63, 0x54, 0x61, 0x73, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x12, 0x30, 0x0a, 0x08, 0x73, 0x79, 0x6e, 0x63,
-	0x54, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x73, 0x79, 0x6e,
-	0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x79, 0x70, 0x65,
-	0x52, 0x08, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x4d, 0x0a, 0x11, 0x73, 0x79,
-	0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64,
-	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x50, 0x0a, 0x12, 0x73, 0x79, 0x6e,
-	0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x12, 0x73, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61,
-	0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a, 0x10, 0x73,
-	0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18,
-	0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x10, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4d, 0x0a, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x42,
-	0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x07, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x53, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x48, 0x00, 0x52, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5c, 0x0a, 0x16, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x16, 0x73, 0x79,
-	0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x12, 0x5f, 0x0a, 0x17, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18,
-	0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f,
+	0x6c, 0x6f, 0x6f, 0x6d, 0x12, 0x26, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e,
+	0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x54, 0x0a, 0x17,
+	0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e,
+	0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x22, 0x41, 0x0a, 0x17, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x12, 0x26, 0x0a,
+	0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
+	0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x68,
+	0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x6f, 0x0a, 0x15, 0x53, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12, 0x2e,
+	0x0a, 0x0a, 0x44, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32,
+	0x35, 0x36, 0x52, 0x0a, 0x44, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x12, 0x26,
+	0x0a, 0x06, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e,
+	0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06,
+	0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0x7e, 0x0a, 0x10, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x06, 0x6e, 0x75,
+	0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74, 0x79, 0x70,
+	0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x72, 0x69, 0x67,
+	0x69, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x91, 0x01, 0x0a, 0x11, 0x53, 0x79, 0x6e, 0x63, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x26, 0x0a, 0x06,
+	0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x74,
+	0x79, 0x70, 0x65, 0x73, 0x5f, 0x70, 0x62, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x6e, 0x75,
+	0x6d, 0x62, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6b, 0x65,
+	0x79, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x04, 0x6b, 0x65, 0x79, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x65, 0x78, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x6e, 0x65, 0x78, 0x74, 0x22, 0xb9, 0x07, 0x0a, 0x08, 0x53,
+	0x79, 0x6e, 0x63, 0x54, 0x61, 0x73, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x02, 0x69, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x12, 0x30, 0x0a, 0x08, 0x73, 0x79, 0x6e, 0x63, 0x54,
+	0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x73, 0x79, 0x6e, 0x63,
+	0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x79, 0x70, 0x65, 0x52,
+	0x08, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x4d, 0x0a, 0x11, 0x73, 0x79, 0x6e,
+	0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x50, 0x0a, 0x12, 0x73, 0x79, 0x6e, 0x63,
+	0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x12, 0x73, 0x79, 0x6e, 0x63, 0x48, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4a, 0x0a, 0x10, 0x73, 0x79,
+	0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x48, 0x00, 0x52, 0x10, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4d, 0x0a, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53,
+	0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x48, 0x00, 0x52, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5c, 0x0a, 0x16, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x17, 0x73, 0x79,
-	0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x15, 0x73, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65,
-	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x18, 0x0a,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72,
-	0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x48, 0x00, 0x52, 0x15, 0x73, 0x79, 0x6e, 0x63, 0x50,
-	0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74,
-	0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2a, 0xb7, 0x01, 0x0a, 0x08,
-	0x53, 0x79, 0x6e, 0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x49, 0x4e, 0x44,
-	0x52, 0x65, 0x71, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x65, 0x73,
-	0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x10,
-	0x02, 0x12, 0x0d, 0x0a, 0x09, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x10, 0x03,
-	0x12, 0x0b, 0x0a, 0x07, 0x42, 0x6f, 0x64, 0x79, 0x52, 0x65, 0x71, 0x10, 0x04, 0x12, 0x0b, 0x0a,
-	0x07, 0x42, 0x6f, 0x64, 0x79, 0x52, 0x65, 0x73, 0x10, 0x05, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x74,
-	0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x10, 0x06, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x74,
-	0x65, 0x52, 0x65, 0x73, 0x10, 0x07, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x10, 0x08, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x10, 0x09, 0x12, 0x15,
-	0x0a, 0x11, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63,
-	0x61, 0x73, 0x74, 0x10, 0x0a, 0x42, 0x33, 0x5a, 0x31, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6d, 0x61, 0x7a, 0x65, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2f, 0x61,
-	0x6d, 0x63, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f,
-	0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x16, 0x73, 0x79, 0x6e,
+	0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x5f, 0x0a, 0x17, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x17, 0x73, 0x79, 0x6e,
+	0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x15, 0x73, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65, 0x72,
+	0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x53, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f,
+	0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x48, 0x00, 0x52, 0x15, 0x73, 0x79, 0x6e, 0x63, 0x50, 0x65,
+	0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12,
+	0x4a, 0x0a, 0x10, 0x73, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x79, 0x6e, 0x63,
+	0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x10, 0x73, 0x79, 0x6e, 0x63, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4d, 0x0a, 0x11, 0x73,
+	0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x11, 0x73, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5f, 0x0a, 0x17, 0x73, 0x79,
+	0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x6e, 0x6e,
+	0x6f, 0x75, 0x6e, 0x63, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x73, 0x79,
+	0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65,
+	0x48, 0x00, 0x52, 0x17, 0x73, 0x79, 0x6e, 0x63, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x2a, 0xd0, 0x01, 0x0a, 0x08, 0x53, 0x79, 0x6e, 0x63, 0x54,
+	0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x46, 0x49, 0x4e, 0x44, 0x52, 0x65, 0x71, 0x10, 0x00,
+	0x12, 0x0b, 0x0a, 0x07, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x65, 0x73, 0x10, 0x01, 0x12, 0x0d, 0x0a,
+	0x09, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x71, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09,
+	0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x65, 0x73, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x42,
+	0x6f, 0x64, 0x79, 0x52, 0x65, 0x71, 0x10, 0x04, 0x12, 0x0b, 0x0a, 0x07, 0x42, 0x6f, 0x64, 0x79,
+	0x52, 0x65, 0x73, 0x10, 0x05, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x71, 0x10, 0x06, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x10,
+	0x07, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x10, 0x08, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x10, 0x09, 0x12, 0x15, 0x0a, 0x11, 0x50, 0x65, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x10, 0x0a,
+	0x12, 0x17, 0x0a, 0x13, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x41,
+	0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x10, 0x0b, 0x42, 0x33, 0x5a, 0x31, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6d, 0x61, 0x7a, 0x65, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x2f, 0x61, 0x6d, 0x63, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x2f, 0x73, 0x79, 0x6e, 0x63, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -845,7 +1138,7 @@ func file_sync_proto_rawDescGZIP() []byte {
 }
 
 var file_sync_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_sync_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_sync_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_sync_proto_goTypes = []interface{}{
 	(SyncType)(0),                   // 0: sync_proto.SyncType
 	(*SyncProtocol)(nil),            // 1: sync_proto.SyncProtocol
@@ -856,35 +1149,45 @@ var file_sync_proto_goTypes = []interface{}{
 	(*SyncHeaderResponse)(nil),      // 6: sync_proto.SyncHeaderResponse
 	(*SyncTransactionRequest)(nil),  // 7: sync_proto.SyncTransactionRequest
 	(*SyncTransactionResponse)(nil), // 8: sync_proto.SyncTransactionResponse
-	(*SyncPeerInfoBroadcast)(nil),   // 9: sync_proto.SyncPeerInfoBroadcast
-	(*SyncTask)(nil),                // 10: sync_proto.SyncTask
-	(*types_pb.H256)(nil),           // 11: types_pb.H256
-	(*types_pb.Block)(nil),          // 12: types_pb.Block
-	(*types_pb.Header)(nil),         // 13: types_pb.Header
-	(*types_pb.Transaction)(nil),    // 14: types_pb.Transaction
+	(*SyncTransactionAnnounce)(nil), // 9: sync_proto.SyncTransactionAnnounce
+	(*SyncPeerInfoBroadcast)(nil),   // 10: sync_proto.SyncPeerInfoBroadcast
+	(*SyncStateRequest)(nil),        // 11: sync_proto.SyncStateRequest
+	(*SyncStateResponse)(nil),       // 12: sync_proto.SyncStateResponse
+	(*SyncTask)(nil),                // 13: sync_proto.SyncTask
+	(*types_pb.H256)(nil),           // 14: types_pb.H256
+	(*types_pb.Block)(nil),          // 15: types_pb.Block
+	(*types_pb.Header)(nil),         // 16: types_pb.Header
+	(*types_pb.Transaction)(nil),    // 17: types_pb.Transaction
 }
 var file_sync_proto_depIdxs = []int32{
-	11, // 0: sync_proto.SyncBlockRequest.number:type_name -> types_pb.H256
-	12, // 1: sync_proto.SyncBlockResponse.blocks:type_name -> types_pb.Block
-	11, // 2: sync_proto.SyncHeaderRequest.number:type_name -> types_pb.H256
-	11, // 3: sync_proto.SyncHeaderRequest.amount:type_name -> types_pb.H256
-	13, // 4: sync_proto.SyncHeaderResponse.headers:type_name -> types_pb.Header
-	14, // 5: sync_proto.SyncTransactionResponse.transactions:type_name -> types_pb.Transaction
-	11, // 6: sync_proto.SyncPeerInfoBroadcast.Difficulty:type_name -> types_pb.H256
-	11, // 7: sync_proto.SyncPeerInfoBroadcast.Number:type_name -> types_pb.H256
-	0,  // 8: sync_proto.SyncTask.syncType:type_name -> sync_proto.SyncType
-	5,  // 9: sync_proto.SyncTask.syncHeaderRequest:type_name -> sync_proto.SyncHeaderRequest
-	6,  // 10: sync_proto.SyncTask.syncHeaderResponse:type_name -> sync_proto.SyncHeaderResponse
-	3,  // 11: sync_proto.SyncTask.syncBlockRequest:type_name -> sync_proto.SyncBlockRequest
-	4,  // 12: sync_proto.SyncTask.syncBlockResponse:type_name -> sync_proto.SyncBlockResponse
-	7,  // 13: sync_proto.SyncTask.syncTransactionRequest:type_name -> sync_proto.SyncTransactionRequest
-	8,  // 14: sync_proto.SyncTask.syncTransactionResponse:type_name -> sync_proto.SyncTransactionResponse
-	9,  // 15: sync_proto.SyncTask.syncPeerInfoBroadcast:type_name -> sync_proto.SyncPeerInfoBroadcast
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	14, // 0: sync_proto.SyncBlockRequest.number:type_name -> types_pb.H256
+	15, // 1: sync_proto.SyncBlockResponse.blocks:type_name -> types_pb.Block
+	14, // 2: sync_proto.SyncHeaderRequest.number:type_name -> types_pb.H256
+	14, // 3: sync_proto.SyncHeaderRequest.amount:type_name -> types_pb.H256
+	16, // 4: sync_proto.SyncHeaderResponse.headers:type_name -> types_pb.Header
+	14, // 5: sync_proto.SyncTransactionRequest.hashes:type_name -> types_pb.H256
+	17, // 6: sync_proto.SyncTransactionResponse.transactions:type_name -> types_pb.Transaction
+	14, // 7: sync_proto.SyncTransactionAnnounce.hashes:type_name -> types_pb.H256
+	14, // 8: sync_proto.SyncPeerInfoBroadcast.Difficulty:type_name -> types_pb.H256
+	14, // 9: sync_proto.SyncPeerInfoBroadcast.Number:type_name -> types_pb.H256
+	14, // 10: sync_proto.SyncStateRequest.number:type_name -> types_pb.H256
+	14, // 11: sync_proto.SyncStateResponse.number:type_name -> types_pb.H256
+	0,  // 12: sync_proto.SyncTask.syncType:type_name -> sync_proto.SyncType
+	5,  // 13: sync_proto.SyncTask.syncHeaderRequest:type_name -> sync_proto.SyncHeaderRequest
+	6,  // 14: sync_proto.SyncTask.syncHeaderResponse:type_name -> sync_proto.SyncHeaderResponse
+	3,  // 15: sync_proto.SyncTask.syncBlockRequest:type_name -> sync_proto.SyncBlockRequest
+	4,  // 16: sync_proto.SyncTask.syncBlockResponse:type_name -> sync_proto.SyncBlockResponse
+	7,  // 17: sync_proto.SyncTask.syncTransactionRequest:type_name -> sync_proto.SyncTransactionRequest
+	8,  // 18: sync_proto.SyncTask.syncTransactionResponse:type_name -> sync_proto.SyncTransactionResponse
+	10, // 19: sync_proto.SyncTask.syncPeerInfoBroadcast:type_name -> sync_proto.SyncPeerInfoBroadcast
+	11, // 20: sync_proto.SyncTask.syncStateRequest:type_name -> sync_proto.SyncStateRequest
+	12, // 21: sync_proto.SyncTask.syncStateResponse:type_name -> sync_proto.SyncStateResponse
+	9,  // 22: sync_proto.SyncTask.syncTransactionAnnounce:type_name -> sync_proto.SyncTransactionAnnounce
+	23, // [23:23] is the sub-list for method output_type
+	23, // [23:23] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_sync_proto_init() }
@@ -990,7 +1293,7 @@ func file_sync_proto_init() {
 			}
 		}
 		file_sync_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SyncPeerInfoBroadcast); i {
+			switch v := v.(*SyncTransactionAnnounce); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1002,6 +1305,42 @@ func file_sync_proto_init() {
 			}
 		}
 		file_sync_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SyncPeerInfoBroadcast); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sync_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SyncStateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sync_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SyncStateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sync_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SyncTask); i {
 			case 0:
 				return &v.state
@@ -1014,7 +1353,7 @@ func file_sync_proto_init() {
 			}
 		}
 	}
-	file_sync_proto_msgTypes[9].OneofWrappers = []interface{}{
+	file_sync_proto_msgTypes[12].OneofWrappers = []interface{}{
 		(*SyncTask_SyncHeaderRequest)(nil),
 		(*SyncTask_SyncHeaderResponse)(nil),
 		(*SyncTask_SyncBlockRequest)(nil),
@@ -1022,6 +1361,9 @@ func file_sync_proto_init() {
 		(*SyncTask_SyncTransactionRequest)(nil),
 		(*SyncTask_SyncTransactionResponse)(nil),
 		(*SyncTask_SyncPeerInfoBroadcast)(nil),
+		(*SyncTask_SyncStateRequest)(nil),
+		(*SyncTask_SyncStateResponse)(nil),
+		(*SyncTask_SyncTransactionAnnounce)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1029,7 +1371,7 @@ func file_sync_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_sync_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   10,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
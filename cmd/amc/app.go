@@ -55,6 +55,15 @@ func appRun(ctx *cli.Context) error {
 		}
 	}
 
+	if ctx.IsSet(P2PAnnounceFlag.Name) {
+		DefaultConfig.NetworkCfg.AnnounceAddresses = strings.Split(ctx.String(P2PAnnounceFlag.Name), ",")
+	}
+	if ctx.IsSet(P2PRelaysFlag.Name) {
+		DefaultConfig.NetworkCfg.Relays = strings.Split(ctx.String(P2PRelaysFlag.Name), ",")
+	}
+	// The --txpool.* flags override the config file
+	setTxPool(ctx, &DefaultConfig.TxPoolCfg)
+
 	log.Init(DefaultConfig.NodeCfg, DefaultConfig.LoggerCfg)
 	//log.SetLogger(log.WithContext(c, log.With(zap.NewLogger(zapLog), "caller", log.DefaultCaller)))
 
@@ -190,6 +199,43 @@ func unlockAccounts(ctx *cli.Context, stack *node.Node, cfg *conf.Config) {
 	}
 }
 
+// setTxPool applies the transaction pool flags set on the command line to cfg.
+func setTxPool(ctx *cli.Context, cfg *conf.TxPoolConfig) {
+	if ctx.IsSet(TxPoolLocalsFlag.Name) {
+		cfg.Locals = strings.Split(ctx.String(TxPoolLocalsFlag.Name), ",")
+	}
+	if ctx.IsSet(TxPoolNoLocalsFlag.Name) {
+		cfg.NoLocals = ctx.Bool(TxPoolNoLocalsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.String(TxPoolJournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
+		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.Uint64(TxPoolPriceBumpFlag.Name)
+	}
+	if ctx.IsSet(TxPoolAccountSlotsFlag.Name) {
+		cfg.AccountSlots = ctx.Uint64(TxPoolAccountSlotsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolGlobalSlotsFlag.Name) {
+		cfg.GlobalSlots = ctx.Uint64(TxPoolGlobalSlotsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolAccountQueueFlag.Name) {
+		cfg.AccountQueue = ctx.Uint64(TxPoolAccountQueueFlag.Name)
+	}
+	if ctx.IsSet(TxPoolGlobalQueueFlag.Name) {
+		cfg.GlobalQueue = ctx.Uint64(TxPoolGlobalQueueFlag.Name)
+	}
+	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
+		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
+	}
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.Path(PasswordFileFlag.Name)
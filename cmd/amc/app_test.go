@@ -0,0 +1,47 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amazechain/amc/conf"
+	"github.com/urfave/cli/v2"
+)
+
+func TestSetTxPool(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range txPoolFlags {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse([]string{"--txpool.pricelimit", "5", "--txpool.locals", "0x01,0x02", "--txpool.lifetime", "1h"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The values read from a config file are only replaced by the flags given
+	cfg := conf.TxPoolConfig{PriceLimit: 2, PriceBump: 20, GlobalSlots: 100, Lifetime: time.Minute}
+	setTxPool(cli.NewContext(nil, set, nil), &cfg)
+	want := conf.TxPoolConfig{Locals: []string{"0x01", "0x02"}, PriceLimit: 5, PriceBump: 20, GlobalSlots: 100, Lifetime: time.Hour}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("have config %+v, want %+v", cfg, want)
+	}
+}
@@ -63,6 +63,51 @@ var networkFlags = []cli.Flag{
 		Value:       "",
 		Destination: &DefaultConfig.NetworkCfg.LocalPeerKey,
 	},
+
+	&cli.StringFlag{
+		Name:        "nat",
+		Usage:       "NAT port mapping mechanism (any|none|extip:<IP>)",
+		Value:       DefaultConfig.NetworkCfg.NAT,
+		Destination: &DefaultConfig.NetworkCfg.NAT,
+	},
+
+	P2PAnnounceFlag,
+	P2PRelaysFlag,
+
+	&cli.BoolFlag{
+		Name:        "p2p.autonat",
+		Usage:       "Serve AutoNAT reachability checks to other peers",
+		Value:       DefaultConfig.NetworkCfg.AutoNAT,
+		Destination: &DefaultConfig.NetworkCfg.AutoNAT,
+	},
+
+	&cli.BoolFlag{
+		Name:        "p2p.holepunch",
+		Usage:       "Upgrade relayed connections to direct ones by hole punching",
+		Value:       DefaultConfig.NetworkCfg.HolePunching,
+		Destination: &DefaultConfig.NetworkCfg.HolePunching,
+	},
+
+	&cli.IntFlag{
+		Name:        "p2p.maxpeers",
+		Usage:       "Maximum number of network peers (0 for no limit)",
+		Value:       DefaultConfig.NetworkCfg.MaxPeers,
+		Destination: &DefaultConfig.NetworkCfg.MaxPeers,
+	},
+
+	&cli.IntFlag{
+		Name:        "p2p.maxpendpeers",
+		Usage:       "Maximum number of concurrent peer handshakes (0 for no limit)",
+		Value:       DefaultConfig.NetworkCfg.MaxPendingPeers,
+		Destination: &DefaultConfig.NetworkCfg.MaxPendingPeers,
+	},
+
+	&cli.DurationFlag{
+		Name:        "p2p.banduration",
+		Usage:       "Duration peers violating the protocol are banned for (0 disables banning)",
+		Value:       DefaultConfig.NetworkCfg.BanDuration,
+		Destination: &DefaultConfig.NetworkCfg.BanDuration,
+	},
 }
 
 var nodeFlg = []cli.Flag{
@@ -101,6 +146,24 @@ var rpcFlags = []cli.Flag{
 		Value:       "20012",
 		Destination: &DefaultConfig.NodeCfg.HTTPPort,
 	},
+	&cli.StringFlag{
+		Name:        "http.api",
+		Usage:       "API's offered over the HTTP-RPC interface",
+		Value:       DefaultConfig.NodeCfg.HTTPApi,
+		Destination: &DefaultConfig.NodeCfg.HTTPApi,
+	},
+	&cli.StringFlag{
+		Name:        "http.corsdomain",
+		Usage:       "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value:       DefaultConfig.NodeCfg.HTTPCors,
+		Destination: &DefaultConfig.NodeCfg.HTTPCors,
+	},
+	&cli.StringFlag{
+		Name:        "http.vhosts",
+		Usage:       "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value:       DefaultConfig.NodeCfg.HTTPVirtualHosts,
+		Destination: &DefaultConfig.NodeCfg.HTTPVirtualHosts,
+	},
 
 	&cli.BoolFlag{
 		Name:        "ws",
@@ -120,6 +183,19 @@ var rpcFlags = []cli.Flag{
 		Value:       "20013",
 		Destination: &DefaultConfig.NodeCfg.WSPort,
 	},
+	&cli.StringFlag{
+		Name:        "ws.api",
+		Usage:       "API's offered over the WS-RPC interface",
+		Value:       DefaultConfig.NodeCfg.WSApi,
+		Destination: &DefaultConfig.NodeCfg.WSApi,
+	},
+	&cli.StringFlag{
+		Name:        "ws.origins",
+		Usage:       "Origins from which to accept websockets requests",
+		Value:       DefaultConfig.NodeCfg.WSOrigins,
+		Destination: &DefaultConfig.NodeCfg.WSOrigins,
+	},
+	AuthRPCJWTSecretFlag,
 }
 
 var consensusFlag = []cli.Flag{
@@ -227,12 +303,43 @@ var loggerFlag = []cli.Flag{
 }
 
 var (
+	AuthRPCJWTSecretFlag = &cli.StringFlag{
+		Name:        "authrpc.jwtsecret",
+		Usage:       "Path to a JWT secret to use for authenticated WS-RPC connections, created if missing",
+		Value:       DefaultConfig.NodeCfg.JWTSecret,
+		Destination: &DefaultConfig.NodeCfg.JWTSecret,
+	}
 	DataDirFlag = &cli.StringFlag{
 		Name:        "data.dir",
 		Usage:       "data save dir",
 		Value:       "./amc/",
 		Destination: &DefaultConfig.NodeCfg.DataDir,
 	}
+	SyncModeFlag = &cli.StringFlag{
+		Name:        "syncmode",
+		Usage:       `Blockchain sync mode ("full" or "snap"). Snap sync only verifies the state of chains past their Merkle fork (merkleBlock in the chain config) and needs peers keeping all receipts, otherwise the node syncs in full`,
+		Value:       "full",
+		Destination: &DefaultConfig.NodeCfg.SyncMode,
+	}
+	PruneFlag = &cli.StringFlag{
+		Name: "prune",
+		Usage: `Choose which block data to prune, keeping the last 90000 blocks. Empty keeps everything (archive). Letters:
+	h - prune history (account, storage and deposit change sets, history indexes and state tries)
+	r - prune receipts and logs
+	Example: --prune=hr`,
+		Value:       "",
+		Destination: &DefaultConfig.DatabaseCfg.Prune,
+	}
+	PruneHistoryOlderFlag = &cli.Uint64Flag{
+		Name:        "prune.h.older",
+		Usage:       "Prune the history older than this number of blocks, overrides the default distance of --prune=h",
+		Destination: &DefaultConfig.DatabaseCfg.PruneHistoryOlder,
+	}
+	PruneReceiptsOlderFlag = &cli.Uint64Flag{
+		Name:        "prune.r.older",
+		Usage:       "Prune the receipts and logs older than this number of blocks, overrides the default distance of --prune=r",
+		Destination: &DefaultConfig.DatabaseCfg.PruneReceiptsOlder,
+	}
 )
 
 var (
@@ -329,9 +436,95 @@ var (
 	}
 )
 
+var (
+	// Network settings
+	P2PAnnounceFlag = &cli.StringFlag{
+		Name:  "p2p.announce",
+		Usage: "Comma separated external multiaddrs announced instead of the listen addresses",
+	}
+	P2PRelaysFlag = &cli.StringFlag{
+		Name:  "p2p.relays",
+		Usage: "Comma separated multiaddrs of the circuit relays used when not reachable",
+	}
+)
+
+var (
+	// Transaction pool settings, applied over the config file by setTxPool
+	TxPoolLocalsFlag = &cli.StringFlag{
+		Name:  "txpool.locals",
+		Usage: "Comma separated accounts to treat as locals (no flush, priority inclusion)",
+	}
+	TxPoolNoLocalsFlag = &cli.BoolFlag{
+		Name:  "txpool.nolocals",
+		Usage: "Disables price exemptions for locally submitted transactions",
+	}
+	TxPoolJournalFlag = &cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for local transaction to survive node restarts, relative to the datadir",
+		Value: DefaultConfig.TxPoolCfg.Journal,
+	}
+	TxPoolRejournalFlag = &cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the local transaction journal",
+		Value: DefaultConfig.TxPoolCfg.Rejournal,
+	}
+	TxPoolPriceLimitFlag = &cli.Uint64Flag{
+		Name:  "txpool.pricelimit",
+		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
+		Value: DefaultConfig.TxPoolCfg.PriceLimit,
+	}
+	TxPoolPriceBumpFlag = &cli.Uint64Flag{
+		Name:  "txpool.pricebump",
+		Usage: "Price bump percentage to replace an already existing transaction",
+		Value: DefaultConfig.TxPoolCfg.PriceBump,
+	}
+	TxPoolAccountSlotsFlag = &cli.Uint64Flag{
+		Name:  "txpool.accountslots",
+		Usage: "Minimum number of executable transaction slots guaranteed per account",
+		Value: DefaultConfig.TxPoolCfg.AccountSlots,
+	}
+	TxPoolGlobalSlotsFlag = &cli.Uint64Flag{
+		Name:  "txpool.globalslots",
+		Usage: "Maximum number of executable transaction slots for all accounts",
+		Value: DefaultConfig.TxPoolCfg.GlobalSlots,
+	}
+	TxPoolAccountQueueFlag = &cli.Uint64Flag{
+		Name:  "txpool.accountqueue",
+		Usage: "Maximum number of non-executable transaction slots permitted per account",
+		Value: DefaultConfig.TxPoolCfg.AccountQueue,
+	}
+	TxPoolGlobalQueueFlag = &cli.Uint64Flag{
+		Name:  "txpool.globalqueue",
+		Usage: "Maximum number of non-executable transaction slots for all accounts",
+		Value: DefaultConfig.TxPoolCfg.GlobalQueue,
+	}
+	TxPoolLifetimeFlag = &cli.DurationFlag{
+		Name:  "txpool.lifetime",
+		Usage: "Maximum amount of time non-executable transaction are queued",
+		Value: DefaultConfig.TxPoolCfg.Lifetime,
+	}
+)
+
 var (
 	settingFlag = []cli.Flag{
 		DataDirFlag,
+		SyncModeFlag,
+		PruneFlag,
+		PruneHistoryOlderFlag,
+		PruneReceiptsOlderFlag,
+	}
+	txPoolFlags = []cli.Flag{
+		TxPoolLocalsFlag,
+		TxPoolNoLocalsFlag,
+		TxPoolJournalFlag,
+		TxPoolRejournalFlag,
+		TxPoolPriceLimitFlag,
+		TxPoolPriceBumpFlag,
+		TxPoolAccountSlotsFlag,
+		TxPoolGlobalSlotsFlag,
+		TxPoolAccountQueueFlag,
+		TxPoolGlobalQueueFlag,
+		TxPoolLifetimeFlag,
 	}
 	accountFlag = []cli.Flag{
 		PasswordFileFlag,
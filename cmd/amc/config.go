@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/amazechain/amc/params"
 	"math/big"
+	"time"
 
 	"github.com/amazechain/amc/conf"
 )
@@ -31,15 +32,33 @@ var allocs embed.FS
 
 var DefaultConfig = conf.Config{
 	NodeCfg: conf.NodeConfig{
-		NodePrivate: "",
-		HTTP:        true,
-		HTTPHost:    "127.0.0.1",
-		HTTPPort:    "8545",
-		IPCPath:     "amc.ipc",
-		Miner:       false,
+		NodePrivate:      "",
+		HTTP:             true,
+		HTTPHost:         "127.0.0.1",
+		HTTPPort:         "8545",
+		HTTPApi:          "eth,web3,net,txpool",
+		WSApi:            "eth,web3,net,txpool",
+		HTTPVirtualHosts: "localhost",
+		IPCPath:          "amc.ipc",
+		Miner:            false,
+		SyncMode:         "full",
 	},
 	NetworkCfg: conf.NetWorkConfig{
-		Bootstrapped: true,
+		Bootstrapped:         true,
+		NAT:                  "any",
+		AutoNAT:              true,
+		HolePunching:         true,
+		MaxPeers:             50,
+		MaxPendingPeers:      50,
+		BanDuration:          time.Hour,
+		PeerScoring:          true,
+		GossipThreshold:      -1000,
+		PublishThreshold:     -2000,
+		GraylistThreshold:    -4000,
+		BlockTopicWeight:     1,
+		TxTopicWeight:        0.5,
+		InvalidMessageWeight: -100,
+		InvalidMessageDecay:  time.Hour,
 	},
 	LoggerCfg: conf.LoggerConfig{
 		LogFile:    "./logger.log",
@@ -72,6 +91,17 @@ var DefaultConfig = conf.Config{
 		InfluxDBBucket:       "",
 		InfluxDBOrganization: "",
 	},
+	TxPoolCfg: conf.TxPoolConfig{
+		Journal:      "transactions.journal",
+		Rejournal:    time.Hour,
+		PriceLimit:   1,
+		PriceBump:    10,
+		AccountSlots: 16,
+		GlobalSlots:  4096 + 1024,
+		AccountQueue: 64,
+		GlobalQueue:  1024,
+		Lifetime:     3 * time.Hour,
+	},
 
 	GenesisBlockCfg: ReadGenesis("allocs/genesis.json"),
 	GPO:             conf.FullNodeGPO,
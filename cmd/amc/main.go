@@ -36,10 +36,11 @@ func main() {
 	flags = append(flags, rpcFlags...)
 	flags = append(flags, configFlag...)
 	flags = append(flags, settingFlag...)
+	flags = append(flags, txPoolFlags...)
 	flags = append(flags, accountFlag...)
 	flags = append(flags, metricsFlags...)
 
-	rootCmd = append(rootCmd, walletCommand, accountCommand, exportCommand)
+	rootCmd = append(rootCmd, walletCommand, accountCommand, exportCommand, verifierCommand)
 	commands := rootCmd
 
 	app := &cli.App{
@@ -14,10 +14,13 @@ import (
 	"github.com/amazechain/amc/modules/ethdb/olddb"
 	"github.com/amazechain/amc/modules/state"
 	"github.com/amazechain/amc/params"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"unsafe"
 )
 
-func verify(ctx context.Context, msg *state.EntireCode) types.Hash {
+// verifyEntire re-executes a mined block statelessly on top of the state
+// witness carried by msg and returns the resulting state root.
+func verifyEntire(ctx context.Context, msg *state.EntireCode) (types.Hash, error) {
 	codeMap := make(map[types.Hash][]byte)
 	for _, pair := range msg.Codes {
 		codeMap[pair.Hash] = pair.Code
@@ -45,7 +48,7 @@ func verify(ctx context.Context, msg *state.EntireCode) types.Hash {
 	for _, tByte := range msg.Entire.Transactions {
 		tmp := &transaction.Transaction{}
 		if err := tmp.Unmarshal(tByte); nil != err {
-			panic(err)
+			return types.Hash{}, fmt.Errorf("unmarshal transaction failed: %w", err)
 		}
 		txs = append(txs, tmp)
 	}
@@ -68,14 +71,33 @@ func verify(ctx context.Context, msg *state.EntireCode) types.Hash {
 	ibs.SetHeight(block.Number64().Uint64())
 	ibs.SetGetOneFun(batch.GetOne)
 
+	// After the Merkle fork the state root is computed on the nodes of the
+	// parent state trie sent along with the block.
+	if number := block.Number64().Uint64(); params.AmazeChainConfig.IsMerkle(number) {
+		if msg.Trie == nil {
+			return types.Hash{}, fmt.Errorf("missing state trie witness of block %d", number)
+		}
+		db := memdb.New("")
+		defer db.Close()
+		tx, err := db.BeginRw(ctx)
+		if nil != err {
+			return types.Hash{}, err
+		}
+		defer tx.Rollback()
+		if err := state.WriteTrieWitness(tx, msg.Trie); nil != err {
+			return types.Hash{}, err
+		}
+		ibs.UseStateTrie(tx, number, msg.Trie.ParentRoot, params.AmazeChainConfig.Rules(number))
+	}
+
 	root, err := checkBlock(getNumberHash, block, ibs, msg.CoinBase, msg.Rewards)
 	if nil != err {
-		panic(err)
+		return types.Hash{}, err
 	}
-	return root
+	return root, ibs.Error()
 }
 
-func checkBlock(getHashF func(n uint64) types.Hash, block *block2.Block, ibs *state.IntraBlockState, coinbase types.Address, rewards []block2.Reward) (types.Hash, error) {
+func checkBlock(getHashF func(n uint64) types.Hash, block *block2.Block, ibs *state.IntraBlockState, coinbase types.Address, rewards []*block2.Reward) (types.Hash, error) {
 	header := block.Header().(*block2.Header)
 	chainConfig := params.AmazeChainConfig
 	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(block.Number64().ToBig()) == 0 {
@@ -104,7 +126,7 @@ func checkBlock(getHashF func(n uint64) types.Hash, block *block2.Block, ibs *st
 
 	if len(rewards) > 0 {
 		for _, reward := range rewards {
-			if reward.Amount != nil && !reward.Amount.IsZero() {
+			if reward != nil && reward.Amount != nil && !reward.Amount.IsZero() {
 				if !ibs.Exist(reward.Address) {
 					ibs.CreateAccount(reward.Address, false)
 				}
@@ -0,0 +1,339 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/amazechain/amc/accounts/keystore"
+	"github.com/amazechain/amc/cmd/utils"
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/conf"
+	"github.com/amazechain/amc/internal/api"
+	"github.com/amazechain/amc/internal/node"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rpc/jsonrpc"
+	"github.com/amazechain/amc/modules/state"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	verifierMinBackoff = time.Second
+	verifierMaxBackoff = time.Minute
+)
+
+var (
+	VerifierAccountFlag = &cli.StringFlag{
+		Name:  "verifier.account",
+		Usage: "Address or keystore index of the deposited account used to sign blocks",
+	}
+	VerifierEndpointFlag = &cli.StringFlag{
+		Name:  "verifier.endpoint",
+		Usage: "Websocket RPC endpoint of the node to verify blocks for",
+		Value: "ws://127.0.0.1:20013",
+	}
+
+	verifierCommand = &cli.Command{
+		Name:   "verifier",
+		Usage:  "Run a block verifier against a node",
+		Action: runVerifier,
+		Flags: []cli.Flag{
+			DataDirFlag,
+			KeyStoreDirFlag,
+			PasswordFileFlag,
+			AuthRPCJWTSecretFlag,
+			VerifierAccountFlag,
+			VerifierEndpointFlag,
+		},
+		Description: `
+    amc verifier --verifier.account <address> [options]
+
+Subscribes to the blocks mined by a node, re-executes each of them on top of
+the state witness sent along and submits a BLS signature of the resulting state
+root back to the node.
+
+The given account must hold a live deposit registered with the public key of
+its BLS key in the keystore, see 'amc verifier newkey'. You are prompted for the
+password of the BLS key, for non-interactive use it is read from the
+--account.password file. If the node requires authenticated websocket
+connections, the shared secret is read from the --authrpc.jwtsecret file.
+`,
+		Subcommands: []*cli.Command{
+			{
+				Name:   "newkey",
+				Usage:  "Create a new BLS key for a deposited account",
+				Action: verifierNewKey,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					KeyStoreDirFlag,
+					PasswordFileFlag,
+					LightKDFFlag,
+					VerifierAccountFlag,
+				},
+				Description: `
+    amc verifier newkey --verifier.account <address>
+
+Creates a new BLS key for the account and prints its public key, which is the
+key to register with the deposit of the account.
+
+The key is saved in encrypted format under <KEYSTORE>/bls, you are prompted for
+a password. An earlier BLS key of the account is replaced.
+`,
+			},
+			{
+				Name:      "importkey",
+				Usage:     "Import a BLS secret key for a deposited account",
+				Action:    verifierImportKey,
+				ArgsUsage: "<keyFile>",
+				Flags: []cli.Flag{
+					DataDirFlag,
+					KeyStoreDirFlag,
+					PasswordFileFlag,
+					LightKDFFlag,
+					VerifierAccountFlag,
+				},
+				Description: `
+    amc verifier importkey --verifier.account <address> <keyfile>
+
+Imports the unencrypted, hex encoded BLS secret key in <keyfile> as the BLS key
+of the account and prints its public key.
+
+The key is saved in encrypted format under <KEYSTORE>/bls, you are prompted for
+a password. Remove <keyfile> once the key is imported.
+`,
+			},
+		},
+	}
+)
+
+// blsVerifier signs the state roots of the blocks mined by a single node.
+type blsVerifier struct {
+	endpoint  string
+	jwtSecret []byte
+	address   types.Address
+	key       bls.SecretKey
+}
+
+func runVerifier(ctx *cli.Context) error {
+	cfg := DefaultConfig
+	// Load config file.
+	if len(cfgFile) > 0 {
+		if err := conf.LoadConfigFromFile(cfgFile, &cfg); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	log.Init(cfg.NodeCfg, cfg.LoggerCfg)
+
+	keydir, ks := verifierKeyStore(ctx, cfg)
+	acc, err := utils.MakeAddress(ks, ctx.String(VerifierAccountFlag.Name))
+	if err != nil {
+		utils.Fatalf("Could not find the verifier account: %v", err)
+	}
+	password := utils.GetPassPhraseWithList(fmt.Sprintf("Unlocking BLS key of %s", acc.Address.Hex()), false, 0, MakePasswordList(ctx))
+	key, err := keystore.LoadBLSKey(keydir, acc.Address, password)
+	if err != nil {
+		utils.Fatalf("Failed to unlock BLS key: %v", err)
+	}
+	v := &blsVerifier{
+		endpoint: ctx.String(VerifierEndpointFlag.Name),
+		address:  acc.Address,
+		key:      key,
+	}
+	if cfg.NodeCfg.JWTSecret != "" {
+		if v.jwtSecret, err = node.ReadJWTSecret(cfg.NodeCfg.JWTSecret); err != nil {
+			utils.Fatalf("Failed to load JWT secret: %v", err)
+		}
+	}
+
+	// State roots after the Merkle fork are computed in an in-memory database
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+
+	c, cancel := signal.NotifyContext(ctx.Context, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Info("Starting verifier", "address", v.address, "blsPubkey", hexutil.Encode(v.key.PublicKey().Marshal()), "endpoint", v.endpoint)
+	v.run(c)
+	return nil
+}
+
+// verifierKeyStore opens the keystore of the configured node and requires the
+// verifier account to be given.
+func verifierKeyStore(ctx *cli.Context, cfg conf.Config) (string, *keystore.KeyStore) {
+	if !ctx.IsSet(VerifierAccountFlag.Name) {
+		utils.Fatalf("No verifier account specified, use --%s", VerifierAccountFlag.Name)
+	}
+	keydir, err := cfg.NodeCfg.KeyDirConfig()
+	if err != nil {
+		utils.Fatalf("Failed to read configuration: %v", err)
+	}
+	return keydir, keystore.NewKeyStore(keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+}
+
+// verifierNewKey creates a new BLS key for the verifier account.
+func verifierNewKey(ctx *cli.Context) error {
+	key, err := bls.RandKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate BLS key: %v", err)
+	}
+	return storeVerifierKey(ctx, key)
+}
+
+// verifierImportKey imports an unencrypted BLS secret key for the verifier account.
+func verifierImportKey(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("keyfile must be given as the only argument")
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read the BLS key: %v", err)
+	}
+	raw, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		utils.Fatalf("Invalid BLS key: %v", err)
+	}
+	key, err := bls.SecretKeyFromBytes(raw)
+	if err != nil {
+		utils.Fatalf("Invalid BLS key: %v", err)
+	}
+	return storeVerifierKey(ctx, key)
+}
+
+// storeVerifierKey encrypts the BLS key of the verifier account into the keystore.
+func storeVerifierKey(ctx *cli.Context, key bls.SecretKey) error {
+	cfg := DefaultConfig
+	// Load config file.
+	if len(cfgFile) > 0 {
+		if err := conf.LoadConfigFromFile(cfgFile, &cfg); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	keydir, ks := verifierKeyStore(ctx, cfg)
+	acc, err := utils.MakeAddress(ks, ctx.String(VerifierAccountFlag.Name))
+	if err != nil {
+		utils.Fatalf("Could not find the verifier account: %v", err)
+	}
+	scryptN := keystore.StandardScryptN
+	scryptP := keystore.StandardScryptP
+	if cfg.NodeCfg.UseLightweightKDF || ctx.Bool(LightKDFFlag.Name) {
+		scryptN = keystore.LightScryptN
+		scryptP = keystore.LightScryptP
+	}
+
+	password := utils.GetPassPhraseWithList("Your BLS key is locked with a password. Please give a password. Do not forget this password.", true, 0, MakePasswordList(ctx))
+	file, err := keystore.StoreBLSKey(keydir, acc.Address, key, password, scryptN, scryptP)
+	if err != nil {
+		utils.Fatalf("Failed to store BLS key: %v", err)
+	}
+	fmt.Printf("\nBLS key of %s stored\n\n", acc.Address.Hex())
+	fmt.Printf("Public key to register with the deposit: %s\n", hexutil.Encode(key.PublicKey().Marshal()))
+	fmt.Printf("Path of the secret key file:             %s\n\n", file)
+	return nil
+}
+
+// run keeps a subscription to the node open until ctx is done, reconnecting
+// with exponential backoff whenever the connection is lost.
+func (v *blsVerifier) run(ctx context.Context) {
+	backoff := verifierMinBackoff
+	for {
+		connected, err := v.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = verifierMinBackoff
+		}
+		log.Warn("Verifier disconnected", "endpoint", v.endpoint, "err", err, "retry", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > verifierMaxBackoff {
+			backoff = verifierMaxBackoff
+		}
+	}
+}
+
+// serve subscribes to the mined blocks of the node and signs each of them until
+// the subscription fails. connected reports whether the subscription was set up.
+func (v *blsVerifier) serve(ctx context.Context) (connected bool, err error) {
+	header := make(http.Header)
+	if v.jwtSecret != nil {
+		token, err := node.NewJWTToken(v.jwtSecret)
+		if err != nil {
+			return false, err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	client, err := jsonrpc.DialWebsocketWithHeader(ctx, v.endpoint, "", header)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	entires := make(chan *state.EntireCode)
+	sub, err := client.Subscribe(ctx, "eth", entires, "minedBlock", v.address)
+	if err != nil {
+		return false, err
+	}
+	defer sub.Unsubscribe()
+	log.Info("Verifier subscribed to mined blocks", "endpoint", v.endpoint)
+
+	for {
+		select {
+		case entire := <-entires:
+			if entire == nil || entire.Entire.Header == nil {
+				continue
+			}
+			number := entire.Entire.Header.Number.Uint64()
+			root, err := verifyEntire(ctx, entire)
+			if err != nil {
+				log.Error("Failed to verify mined block", "number", number, "err", err)
+				continue
+			}
+
+			sign := api.AggSign{
+				Number:    number,
+				StateRoot: root,
+				Address:   v.address,
+			}
+			copy(sign.Sign[:], v.key.Sign(root[:]).Marshal())
+			if err := client.CallContext(ctx, nil, "eth_submitSign", sign); err != nil {
+				log.Warn("Failed to submit block signature", "number", number, "err", err)
+				continue
+			}
+			log.Debug("Submitted block signature", "number", number, "root", root)
+		case err := <-sub.Err():
+			return true, err
+		case <-ctx.Done():
+			return true, nil
+		}
+	}
+}
@@ -18,8 +18,11 @@ package block
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/amazechain/amc/api/protocol/types_pb"
+	"github.com/amazechain/amc/common/crypto"
+	"github.com/amazechain/amc/common/transaction"
 	"github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/internal/avm/rlp"
 	"github.com/amazechain/amc/utils"
@@ -74,6 +77,50 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	//w.Write(byte)
 }
 
+// DeriveFields fills the receipts with their computed fields based on consensus
+// data and contextual infos like containing block and transactions.
+func (rs Receipts) DeriveFields(hash types.Hash, number uint64, txs []*transaction.Transaction) error {
+	if len(txs) != len(rs) {
+		return errors.New("transaction and receipt count mismatch")
+	}
+	logIndex := uint(0)
+	for i, r := range rs {
+		// The transaction type and hash can be retrieved from the transaction itself
+		r.Type = txs[i].Type()
+		r.TxHash = txs[i].Hash()
+
+		// block location fields
+		r.BlockHash = hash
+		r.BlockNumber = uint256.NewInt(number)
+		r.TransactionIndex = uint(i)
+
+		// The contract address can be derived from the transaction itself
+		if txs[i].To() == nil && txs[i].From() != nil {
+			r.ContractAddress = crypto.CreateAddress(*txs[i].From(), txs[i].Nonce())
+		} else {
+			r.ContractAddress = types.Address{}
+		}
+
+		// The used gas can be calculated based on previous r
+		if i == 0 {
+			r.GasUsed = r.CumulativeGasUsed
+		} else {
+			r.GasUsed = r.CumulativeGasUsed - rs[i-1].CumulativeGasUsed
+		}
+
+		// The derived log fields can simply be set from the block and transaction
+		for _, log := range r.Logs {
+			log.BlockNumber = uint256.NewInt(number)
+			log.BlockHash = hash
+			log.TxHash = r.TxHash
+			log.TxIndex = uint(i)
+			log.Index = logIndex
+			logIndex++
+		}
+	}
+	return nil
+}
+
 func (rs *Receipts) FromProtoMessage(receipts *types_pb.Receipts) error {
 	for _, receipt := range receipts.Receipts {
 		var rec Receipt
@@ -0,0 +1,79 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package block
+
+import (
+	"testing"
+
+	"github.com/amazechain/amc/common/crypto"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	"github.com/holiman/uint256"
+)
+
+func TestDeriveFields(t *testing.T) {
+	from := types.HexToAddress("0x0000000000000000000000000000000000000001")
+	to := types.HexToAddress("0x0000000000000000000000000000000000000002")
+	txs := []*transaction.Transaction{
+		transaction.NewTx(&transaction.LegacyTx{Nonce: 1, From: &from, To: &to, Gas: 21000, GasPrice: uint256.NewInt(1)}),
+		transaction.NewTx(&transaction.LegacyTx{Nonce: 2, From: &from, Gas: 100000, GasPrice: uint256.NewInt(1)}),
+		transaction.NewTx(&transaction.LegacyTx{Nonce: 3, From: &from, To: &to, Gas: 50000, GasPrice: uint256.NewInt(1)}),
+	}
+	receipts := Receipts{
+		{CumulativeGasUsed: 21000, Logs: []*Log{{Address: to}}},
+		{CumulativeGasUsed: 100000},
+		{CumulativeGasUsed: 130000, Logs: []*Log{{Address: to}, {Address: to}}},
+	}
+
+	hash := types.HexToHash("0x1234")
+	if err := receipts.DeriveFields(hash, 10, txs); err != nil {
+		t.Fatal(err)
+	}
+
+	gasUsed := []uint64{21000, 79000, 30000}
+	logIndex := uint(0)
+	for i, r := range receipts {
+		if r.TxHash != txs[i].Hash() {
+			t.Errorf("receipt %d: tx hash mismatch: have %x, want %x", i, r.TxHash, txs[i].Hash())
+		}
+		if r.BlockHash != hash || r.BlockNumber.Uint64() != 10 || r.TransactionIndex != uint(i) {
+			t.Errorf("receipt %d: location mismatch: have %x %v %d", i, r.BlockHash, r.BlockNumber, r.TransactionIndex)
+		}
+		if r.GasUsed != gasUsed[i] {
+			t.Errorf("receipt %d: gas used mismatch: have %d, want %d", i, r.GasUsed, gasUsed[i])
+		}
+		for _, log := range r.Logs {
+			if log.TxHash != r.TxHash || log.TxIndex != uint(i) || log.BlockHash != hash || log.BlockNumber.Uint64() != 10 {
+				t.Errorf("receipt %d: log location mismatch", i)
+			}
+			if log.Index != logIndex {
+				t.Errorf("receipt %d: log index mismatch: have %d, want %d", i, log.Index, logIndex)
+			}
+			logIndex++
+		}
+	}
+	if want := crypto.CreateAddress(from, 2); receipts[1].ContractAddress != want {
+		t.Errorf("contract address mismatch: have %x, want %x", receipts[1].ContractAddress, want)
+	}
+	if !receipts[0].ContractAddress.IsNull() || !receipts[2].ContractAddress.IsNull() {
+		t.Error("contract address set for a call")
+	}
+
+	if err := receipts.DeriveFields(hash, 10, txs[:2]); err == nil {
+		t.Error("expected an error for a transaction count mismatch")
+	}
+}
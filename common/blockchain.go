@@ -53,6 +53,7 @@ type IBlockChain interface {
 	GetReceipts(blockHash types.Hash) (block.Receipts, error)
 	GetLogs(blockHash types.Hash) ([][]*block.Log, error)
 	SetHead(head uint64) error
+	ResetHead(update func(tx kv.RwTx) (block.IBlock, error)) error
 
 	GetHeader(types.Hash, *uint256.Int) block.IHeader
 	// alias for GetBlocksFromHash?
@@ -67,5 +68,12 @@ type IBlockChain interface {
 
 type IMiner interface {
 	Start()
+	Stop()
+	Mining() bool
 	PendingBlockAndReceipts() (block.IBlock, block.Receipts)
+
+	Coinbase() types.Address
+	SetEtherbase(addr types.Address) error
+	SetGasCeil(ceil uint64)
+	SetExtra(extra []byte) error
 }
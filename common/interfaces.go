@@ -18,10 +18,13 @@ package common
 
 import (
 	"context"
+	"time"
+
 	"github.com/amazechain/amc/common/block"
 	"github.com/amazechain/amc/common/message"
 	"github.com/amazechain/amc/common/transaction"
 	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/internal/forkid"
 	"github.com/golang/protobuf/proto"
 	"github.com/holiman/uint256"
 	"github.com/libp2p/go-libp2p-core/host"
@@ -36,13 +39,35 @@ type IDownloader interface {
 	Start() error
 	Close() error
 	IsDownloading() bool
+	Progress() SyncProgress
 	ConnHandler([]byte, peer.ID) error
 }
 
+// SyncProgress is the progress of the chain synchronisation.
+type SyncProgress struct {
+	StartingBlock uint64 // Block number where the sync began
+	CurrentBlock  uint64 // Block number the sync is at
+	HighestBlock  uint64 // Highest block number announced by the peers
+
+	PulledStates uint64 // Number of state entries downloaded by snap sync
+	SyncedTables uint64 // Number of state tables downloaded by snap sync
+	KnownTables  uint64 // Number of state tables to download by snap sync
+}
+
 type ConnHandler func([]byte, peer.ID) error
 
-type ProtocolHandshakeFn func(peer IPeer, genesisHash types.Hash, currentHeight *uint256.Int) (Peer, bool)
-type ProtocolHandshakeInfo func() (types.Hash, *uint256.Int, error)
+// ProtocolStatus is the chain status a node announces in the protocol handshake.
+type ProtocolStatus struct {
+	ChainID       uint64
+	GenesisHash   types.Hash
+	ForkID        forkid.ID
+	CurrentHeight *uint256.Int
+	TD            *uint256.Int
+	Capabilities  []string
+}
+
+type ProtocolHandshakeFn func(peer IPeer, status *ProtocolStatus) (Peer, error)
+type ProtocolHandshakeInfo func() (*ProtocolStatus, error)
 
 type INetwork interface {
 	WriterMessage(messageType message.MessageType, payload []byte, peer peer.ID) error
@@ -53,6 +78,40 @@ type INetwork interface {
 	Host() host.Host
 	PeerCount() int
 	Bootstrapped() bool
+
+	Peers() []PeerInfo
+	NodeInfo() (NodeInfo, error)
+	AddPeer(addr string) error
+	RemovePeer(addr string) error
+	AddTrustedPeer(addr string) error
+	RemoveTrustedPeer(addr string) error
+	BanPeer(id peer.ID, reason string)
+}
+
+// PeerInfo is the connection information of a connected peer.
+type PeerInfo struct {
+	ID           peer.ID
+	Addrs        []string
+	Inbound      bool
+	Static       bool
+	Trusted      bool
+	Version      string
+	Height       *uint256.Int
+	TD           *uint256.Int
+	Capabilities []string
+	ConnectedAt  time.Time
+}
+
+// NodeInfo is the connection information and chain status of the local node.
+// Addrs are the addresses announced to other peers, Reachability whether they
+// are reachable from the outside.
+type NodeInfo struct {
+	ID           peer.ID
+	Addrs        []string
+	ListenAddrs  []string
+	Reachability string
+	Version      string
+	Status       *ProtocolStatus
 }
 
 type IPeer interface {
@@ -67,6 +126,7 @@ type IPeer interface {
 type IPubSub interface {
 	JoinTopic(topic string) (*pubsub.Topic, error)
 	Publish(topic string, msg proto.Message) error
+	RegisterValidator(topic string, val pubsub.ValidatorEx) error
 	GetTopics() []string
 	Start() error
 }
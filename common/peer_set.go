@@ -22,12 +22,30 @@ import (
 	"time"
 )
 
+// Sync capabilities announced in the protocol handshake.
+const (
+	CapabilityFull = "full" // serves headers and bodies
+	CapabilitySnap = "snap" // serves the state of recent blocks
+)
+
 type Peer struct {
 	IPeer
 	CurrentHeight *uint256.Int
+	TD            *uint256.Int
+	Capabilities  []string
 	AddTimer      time.Time
 }
 
+// HasCapability reports whether the peer announced the sync capability.
+func (p Peer) HasCapability(name string) bool {
+	for _, c := range p.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 type PeerSet []Peer
 type PeerMap map[peer.ID]Peer
 
@@ -86,3 +86,64 @@ func TestNewDynamicTx(t *testing.T) {
 	//addr := types.PublicToAddress(pub)
 
 }
+
+func TestTransactionPriceNonceSort(t *testing.T) {
+	baseFee := uint256.NewInt(10)
+	groups := make(map[types.Address][]*Transaction)
+	for i := 0; i < 5; i++ {
+		from := types.Address{byte(i + 1)}
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			// Prices fall with the nonce, the first account pays least
+			price := uint256.NewInt(uint64(20 + i*10 - int(nonce)*5))
+			groups[from] = append(groups[from], NewTransaction(nonce, from, &from, uint256.NewInt(0), 21000, price, nil))
+		}
+	}
+	// An account whose head is under the base fee is dropped altogether
+	poor := types.Address{0xff}
+	groups[poor] = []*Transaction{NewTransaction(0, poor, &poor, uint256.NewInt(0), 21000, uint256.NewInt(5), nil)}
+
+	txset := NewTransactionsByPriceAndNonce(groups, baseFee)
+	var txs []*Transaction
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift()
+	}
+	if len(txs) != 15 {
+		t.Fatalf("expected 15 transactions, found %d", len(txs))
+	}
+	next := make(map[types.Address]uint64)
+	for i, tx := range txs {
+		from := *tx.From()
+		if tx.Nonce() != next[from] {
+			t.Fatalf("tx %d from %x: nonce %d, want %d", i, from, tx.Nonce(), next[from])
+		}
+		next[from]++
+		// A more expensive tx of another sender must not follow
+		if i+1 < len(txs) && *txs[i+1].From() != from && tx.EffectiveGasTipCmp(txs[i+1], baseFee) < 0 {
+			t.Errorf("tx %d priced %v sorted before tx priced %v", i, tx.GasPrice(), txs[i+1].GasPrice())
+		}
+	}
+}
+
+func TestEffectiveGasTip(t *testing.T) {
+	from := types.Address{1}
+	tx := NewTx(&DynamicFeeTx{
+		ChainID:   uint256.NewInt(1),
+		From:      &from,
+		GasTipCap: uint256.NewInt(5),
+		GasFeeCap: uint256.NewInt(100),
+		Value:     uint256.NewInt(0),
+	})
+	if tip, err := tx.EffectiveGasTip(uint256.NewInt(90)); err != nil || tip.Uint64() != 5 {
+		t.Errorf("tip capped by gasTipCap: have %v %v, want 5", tip, err)
+	}
+	if tip, err := tx.EffectiveGasTip(uint256.NewInt(98)); err != nil || tip.Uint64() != 2 {
+		t.Errorf("tip capped by gasFeeCap: have %v %v, want 2", tip, err)
+	}
+	if _, err := tx.EffectiveGasTip(uint256.NewInt(101)); err != ErrGasFeeCapTooLow {
+		t.Errorf("fee cap under base fee: have %v, want %v", err, ErrGasFeeCapTooLow)
+	}
+	if tx.GasFeeCap().Uint64() != 100 {
+		t.Errorf("gasFeeCap modified to %v", tx.GasFeeCap())
+	}
+}
@@ -45,5 +45,24 @@ type APosConfig struct {
 	RewardEpoch        uint64   `json:"rewardEpoch" yaml:"rewardEpoch"`
 	RewardLimit        *big.Int `json:"rewardLimit" yaml:"rewardLimit"`
 
-	DepositContract string `json:"depositContract" yaml:"depositContract"` // Deposit contract
+	VerifierQuorum uint64 `json:"verifierQuorum" yaml:"verifierQuorum"` // Percentage of deposited verifiers that must sign a block
+	MinVerifiers   uint64 `json:"minVerifiers" yaml:"minVerifiers"`     // Minimum number of verifier signatures per block
+
+	DepositContract  string             `json:"depositContract" yaml:"depositContract"`   // Deposit contract
+	DepositSchedules []*DepositSchedule `json:"depositSchedules" yaml:"depositSchedules"` // Deposit tiers and rewards, by activation block
+}
+
+// DepositSchedule is a set of deposit tiers that takes effect from Block on,
+// until the next schedule is activated.
+type DepositSchedule struct {
+	Block *big.Int      `json:"block" yaml:"block"`
+	Tiers []DepositTier `json:"tiers" yaml:"tiers"`
+}
+
+// DepositTier describes the reward paid to verifiers holding a deposit of
+// exactly Amount wei.
+type DepositTier struct {
+	Amount          *big.Int `json:"amount" yaml:"amount"`                   // Deposit amount in wei
+	RewardPerMonth  *big.Int `json:"rewardPerMonth" yaml:"rewardPerMonth"`   // Maximum reward per month in wei
+	MaxTaskPerEpoch uint64   `json:"maxTaskPerEpoch" yaml:"maxTaskPerEpoch"` // Maximum number of rewarded blocks per day
 }
@@ -25,4 +25,10 @@ type DatabaseConfig struct {
 	IsMem      bool     `json:"memory" yaml:"memory"`
 	MaxDB      uint64   `json:"max_db" yaml:"max_db"`
 	MaxReaders uint64   `json:"max_readers" yaml:"max_readers"`
+
+	// Prune holds a letter per kind of block data to prune: "h" for the state
+	// history, "r" for receipts and logs. Empty keeps everything (archive).
+	Prune              string `json:"prune" yaml:"prune"`
+	PruneHistoryOlder  uint64 `json:"prune_history_older" yaml:"prune_history_older"`
+	PruneReceiptsOlder uint64 `json:"prune_receipts_older" yaml:"prune_receipts_older"`
 }
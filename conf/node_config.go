@@ -36,6 +36,24 @@ type NodeConfig struct {
 	IPCPath     string `json:"ipc_path" yaml:"ipc_path"`
 	DataDir     string `json:"data_dir" yaml:"data_dir"`
 	Miner       bool   `json:"miner" yaml:"miner"`
+	SyncMode    string `json:"sync_mode" yaml:"sync_mode"`
+
+	// HTTPApi and WSApi are comma separated lists of the RPC modules served
+	// over HTTP and WebSocket. IPC serves all modules.
+	HTTPApi string `json:"http_api" yaml:"http_api"`
+	WSApi   string `json:"ws_api" yaml:"ws_api"`
+
+	// HTTPCors, HTTPVirtualHosts and WSOrigins are comma separated lists of the
+	// browser origins and host names accepted by the RPC servers, "*" accepts
+	// all of them.
+	HTTPCors         string `json:"http_cors" yaml:"http_cors"`
+	HTTPVirtualHosts string `json:"http_vhosts" yaml:"http_vhosts"`
+	WSOrigins        string `json:"ws_origins" yaml:"ws_origins"`
+
+	// JWTSecret is the path of the hex encoded 32 bytes secret WebSocket
+	// clients authenticate with. It is created if it does not exist. Empty
+	// disables authentication.
+	JWTSecret string `json:"jwt_secret" yaml:"jwt_secret"`
 
 	// KeyStoreDir is the file system folder that contains private keys. The directory can
 	// be specified as a relative path, in which case it is resolved relative to the
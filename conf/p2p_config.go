@@ -16,9 +16,56 @@
 
 package conf
 
+import "time"
+
 type NetWorkConfig struct {
 	ListenersAddress []string `json:"listeners" yaml:"listeners"`
 	BootstrapPeers   []string `json:"bootstraps" yaml:"bootstraps"`
 	LocalPeerKey     string   `json:"private" yaml:"network_private"`
 	Bootstrapped     bool     `json:"discover" yaml:"discover"`
+
+	// NAT traversal. NAT is "none", "any" to map the listen ports with UPnP
+	// or NAT-PMP, or "extip:<IP>" to announce the listen ports on a known
+	// external IP. AnnounceAddresses replaces the announced addresses with
+	// explicit external multiaddrs. AutoNAT serves reachability checks to
+	// other peers. Nodes that are not reachable reserve relayed addresses on
+	// Relays and upgrade relayed connections by HolePunching.
+	NAT               string   `json:"nat" yaml:"nat"`
+	AnnounceAddresses []string `json:"announce" yaml:"announce"`
+	AutoNAT           bool     `json:"autonat" yaml:"autonat"`
+	Relays            []string `json:"relays" yaml:"relays"`
+	HolePunching      bool     `json:"hole_punching" yaml:"hole_punching"`
+
+	// Connection limits. Inbound peers may take up to two thirds of
+	// MaxPeers, MaxPendingPeers limits the concurrent handshakes. Static and
+	// trusted peers are exempt, a limit of 0 disables it.
+	MaxPeers        int `json:"maxpeers" yaml:"maxpeers"`
+	MaxPendingPeers int `json:"maxpendingpeers" yaml:"maxpendingpeers"`
+
+	// Static peers are kept connected, trusted peers are always accepted.
+	// Both are given as p2p multiaddrs.
+	StaticPeers  []string `json:"static_peers" yaml:"static_peers"`
+	TrustedPeers []string `json:"trusted_peers" yaml:"trusted_peers"`
+
+	// BanDuration is how long peers violating the protocol are banned for,
+	// 0 disables banning.
+	BanDuration time.Duration `json:"ban_duration" yaml:"ban_duration"`
+
+	// Files the static, trusted and banned peers are kept in, set by the
+	// node to files in its data directory.
+	StaticPeersFile  string `json:"-" yaml:"-"`
+	TrustedPeersFile string `json:"-" yaml:"-"`
+	BannedPeersFile  string `json:"-" yaml:"-"`
+
+	// Gossipsub peer scoring. Peers publishing messages that fail validation
+	// lose score; peers below GraylistThreshold are ignored and pruned from
+	// the topic meshes.
+	PeerScoring          bool          `json:"peer_scoring" yaml:"peer_scoring"`
+	GossipThreshold      float64       `json:"gossip_threshold" yaml:"gossip_threshold"`
+	PublishThreshold     float64       `json:"publish_threshold" yaml:"publish_threshold"`
+	GraylistThreshold    float64       `json:"graylist_threshold" yaml:"graylist_threshold"`
+	BlockTopicWeight     float64       `json:"block_topic_weight" yaml:"block_topic_weight"`
+	TxTopicWeight        float64       `json:"tx_topic_weight" yaml:"tx_topic_weight"`
+	InvalidMessageWeight float64       `json:"invalid_message_weight" yaml:"invalid_message_weight"`
+	InvalidMessageDecay  time.Duration `json:"invalid_message_decay" yaml:"invalid_message_decay"`
 }
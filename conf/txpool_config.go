@@ -0,0 +1,39 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package conf
+
+import "time"
+
+type TxPoolConfig struct {
+	Locals   []string `json:"locals" yaml:"locals"`       // Addresses whose transactions are treated as local
+	NoLocals bool     `json:"no_locals" yaml:"no_locals"` // Whether local transaction handling should be disabled
+
+	// Journal is the file local transactions are kept in across restarts,
+	// relative to the data directory unless absolute. Empty disables it.
+	Journal   string        `json:"journal" yaml:"journal"`
+	Rejournal time.Duration `json:"rejournal" yaml:"rejournal"` // Time interval to regenerate the journal
+
+	PriceLimit uint64 `json:"price_limit" yaml:"price_limit"` // Minimum gas price to enforce for acceptance into the pool
+	PriceBump  uint64 `json:"price_bump" yaml:"price_bump"`   // Minimum price bump percentage to replace an already existing transaction (nonce)
+
+	AccountSlots uint64 `json:"account_slots" yaml:"account_slots"` // Number of executable transaction slots guaranteed per account
+	GlobalSlots  uint64 `json:"global_slots" yaml:"global_slots"`   // Maximum number of executable transaction slots for all accounts
+	AccountQueue uint64 `json:"account_queue" yaml:"account_queue"` // Maximum number of non-executable transaction slots permitted per account
+	GlobalQueue  uint64 `json:"global_queue" yaml:"global_queue"`   // Maximum number of non-executable transaction slots for all accounts
+
+	Lifetime time.Duration `json:"lifetime" yaml:"lifetime"` // Maximum amount of time non-executable transaction are queued
+}
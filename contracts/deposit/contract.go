@@ -1,17 +1,13 @@
 package deposit
 
 import (
-	"context"
 	"embed"
-	"github.com/amazechain/amc/common"
+	"math/big"
+
 	"github.com/amazechain/amc/common/crypto"
-	"github.com/amazechain/amc/common/crypto/bls"
-	"github.com/amazechain/amc/common/hexutil"
-	"github.com/amazechain/amc/common/transaction"
 	"github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/conf"
 	"github.com/amazechain/amc/log"
-	event "github.com/amazechain/amc/modules/event/v2"
 	"github.com/amazechain/amc/modules/rawdb"
 	"github.com/amazechain/amc/params"
 	"github.com/holiman/uint256"
@@ -50,36 +46,98 @@ func init() {
 	}
 }
 
-func GetDepositInfo(tx kv.Tx, addr types.Address) *Info {
+// DefaultSchedules returns the original deposit tiers. They apply, matched by
+// whole AMT, before the first configured schedule is activated and on chains
+// that configure none.
+func DefaultSchedules() []*conf.DepositSchedule {
+	return []*conf.DepositSchedule{{
+		Block: big.NewInt(0),
+		Tiers: []conf.DepositTier{
+			{Amount: amt(TenDeposit), RewardPerMonth: new(big.Int).SetUint64(TenDepositRewardPerMonth), MaxTaskPerEpoch: TenDepositMaxTaskPerEpoch},
+			{Amount: amt(OneHundredDeposit), RewardPerMonth: new(big.Int).SetUint64(OneHundredDepositRewardPerMonth), MaxTaskPerEpoch: OneHundredDepositMaxTaskPerEpoch},
+			{Amount: amt(FiveHundredDeposit), RewardPerMonth: new(big.Int).SetUint64(FiveHundredDepositRewardPerMonth), MaxTaskPerEpoch: FiveHundredDepositMaxTaskPerEpoch},
+		},
+	}}
+}
+
+func amt(n uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(n), new(big.Int).SetUint64(params.AMT))
+}
+
+// ActiveSchedule returns the schedule in effect at the given block number, i.e.
+// the one with the highest activation block not above it.
+func ActiveSchedule(schedules []*conf.DepositSchedule, number uint64) *conf.DepositSchedule {
+	var active *conf.DepositSchedule
+	for _, s := range schedules {
+		if s == nil || s.Block == nil || !s.Block.IsUint64() || s.Block.Uint64() > number {
+			continue
+		}
+		if active == nil || s.Block.Cmp(active.Block) > 0 {
+			active = s
+		}
+	}
+	return active
+}
+
+// tierRewards computes the reward paid per verified block and the cap per
+// reward epoch of a tier. A tier's monthly reward is spread over
+// DayPerMonth*MaxTaskPerEpoch blocks, rounding the per block reward up.
+func tierRewards(tier conf.DepositTier) (rewardPerBlock, maxRewardPerEpoch *uint256.Int) {
+	if tier.RewardPerMonth == nil || tier.MaxTaskPerEpoch == 0 {
+		return uint256.NewInt(0), uint256.NewInt(0)
+	}
+	rewardPerMonth, overflow := uint256.FromBig(tier.RewardPerMonth)
+	if overflow {
+		return uint256.NewInt(0), uint256.NewInt(0)
+	}
+	tasks := uint256.NewInt(DayPerMonth * tier.MaxTaskPerEpoch)
+	rewardPerBlock, rem := new(uint256.Int).DivMod(rewardPerMonth, tasks, new(uint256.Int))
+	if !rem.IsZero() {
+		rewardPerBlock.AddUint64(rewardPerBlock, 1)
+	}
+	maxRewardPerEpoch = new(uint256.Int).Mul(rewardPerBlock, uint256.NewInt(tier.MaxTaskPerEpoch))
+	return rewardPerBlock, maxRewardPerEpoch
+}
+
+// matchTier reports whether a deposit of amount wei belongs to tier. Configured
+// schedules match the exact amount, while the legacy tiers match the amount
+// truncated to whole AMT.
+func matchTier(tier conf.DepositTier, amount *uint256.Int, legacy bool) bool {
+	if tier.Amount == nil {
+		return false
+	}
+	if !legacy {
+		return tier.Amount.Cmp(amount.ToBig()) == 0
+	}
+	unit := new(big.Int).SetUint64(params.AMT)
+	return new(big.Int).Div(tier.Amount, unit).Cmp(new(big.Int).Div(amount.ToBig(), unit)) == 0
+}
+
+// GetDepositInfo returns the deposit of addr together with the rewards it earns
+// under the schedule active at the given block number. A deposit that matches
+// no tier earns nothing. It returns nil if addr has no deposit.
+func GetDepositInfo(tx kv.Tx, addr types.Address, schedules []*conf.DepositSchedule, number uint64) *Info {
 
 	pubkey, depositAmount, err := rawdb.GetDeposit(tx, addr)
 	if err != nil {
 		return nil
 	}
 
-	var (
-		maxRewardPerEpoch *uint256.Int
-		rewardPerBlock    *uint256.Int
-	)
-	depositEther := new(uint256.Int).Div(depositAmount, uint256.NewInt(params.AMT)).Uint64()
-	switch depositEther {
-	case TenDeposit:
-		rewardPerBlock = new(uint256.Int).Add(
-			new(uint256.Int).Div(uint256.NewInt(TenDepositRewardPerMonth), uint256.NewInt(DayPerMonth*TenDepositMaxTaskPerEpoch)),
-			uint256.NewInt(params.Wei),
-		)
-		maxRewardPerEpoch = new(uint256.Int).Mul(rewardPerBlock, uint256.NewInt(TenDepositMaxTaskPerEpoch))
-	case OneHundredDeposit:
-		rewardPerBlock = new(uint256.Int).Add(
-			new(uint256.Int).Div(uint256.NewInt(OneHundredDepositRewardPerMonth), uint256.NewInt(DayPerMonth*OneHundredDepositMaxTaskPerEpoch)),
-			uint256.NewInt(params.Wei),
-		)
-		maxRewardPerEpoch = new(uint256.Int).Mul(rewardPerBlock, uint256.NewInt(OneHundredDepositMaxTaskPerEpoch))
-	case FiveHundredDeposit:
-		maxRewardPerEpoch = new(uint256.Int).Div(uint256.NewInt(FiveHundredDepositRewardPerMonth), uint256.NewInt(DayPerMonth))
-		rewardPerBlock = new(uint256.Int).Div(maxRewardPerEpoch, uint256.NewInt(FiveHundredDepositMaxTaskPerEpoch))
-	default:
-		panic("wrong deposit amount")
+	rewardPerBlock, maxRewardPerEpoch := uint256.NewInt(0), uint256.NewInt(0)
+	schedule, legacy := ActiveSchedule(schedules, number), false
+	if schedule == nil {
+		schedule, legacy = DefaultSchedules()[0], true
+	}
+	matched := false
+	for _, tier := range schedule.Tiers {
+		if matchTier(tier, depositAmount, legacy) {
+			rewardPerBlock, maxRewardPerEpoch = tierRewards(tier)
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		log.Debug("deposit amount matches no deposit tier", "address", addr, "amount", depositAmount, "number", number)
 	}
 
 	return &Info{
@@ -103,156 +161,3 @@ type Info struct {
 //		DepositAmount: depositAmount,
 //	}
 //}
-
-type Deposit struct {
-	ctx             context.Context
-	cancel          context.CancelFunc
-	consensusConfig *conf.ConsensusConfig
-	blockChain      common.IBlockChain
-	db              kv.RwDB
-
-	logsSub   event.Subscription // Subscription for new log event
-	rmLogsSub event.Subscription // Subscription for removed log event
-
-	logsCh   chan common.NewLogsEvent     // Channel to receive new log event
-	rmLogsCh chan common.RemovedLogsEvent // Channel to receive removed log event
-}
-
-func NewDeposit(ctx context.Context, config *conf.ConsensusConfig, bc common.IBlockChain, db kv.RwDB) *Deposit {
-	c, cancel := context.WithCancel(ctx)
-	d := &Deposit{
-		ctx:             c,
-		cancel:          cancel,
-		consensusConfig: config,
-		blockChain:      bc,
-		db:              db,
-		logsCh:          make(chan common.NewLogsEvent),
-		rmLogsCh:        make(chan common.RemovedLogsEvent),
-	}
-
-	d.logsSub = event.GlobalEvent.Subscribe(d.logsCh)
-	d.rmLogsSub = event.GlobalEvent.Subscribe(d.rmLogsCh)
-
-	if d.logsSub == nil || d.rmLogsSub == nil {
-		log.Error("Subscribe for event system failed")
-	}
-	return d
-}
-
-func (d Deposit) Start() {
-	go d.eventLoop()
-}
-
-func (d Deposit) Stop() {
-	d.cancel()
-}
-
-func (d Deposit) eventLoop() {
-	// Ensure all subscriptions get cleaned up
-	defer func() {
-		d.logsSub.Unsubscribe()
-		d.rmLogsSub.Unsubscribe()
-	}()
-
-	for {
-		select {
-		case logEvent := <-d.logsCh:
-			for _, l := range logEvent.Logs {
-				if nil != d.consensusConfig.APos && l.Address.String() == d.consensusConfig.APos.DepositContract {
-					log.Trace("log event topic[0]= ", "hash", l.Topics[0], "depositEventSignature", depositEventSignature, "withdrawnSignature", withdrawnSignature)
-					if l.Topics[0] == depositEventSignature {
-						d.handleDepositEvent(l.TxHash, l.Data)
-					} else if l.Topics[0] == withdrawnSignature {
-						d.handleWithdrawnEvent(l.TxHash, l.Data)
-					}
-				}
-			}
-		case logRemovedEvent := <-d.rmLogsCh:
-			for _, l := range logRemovedEvent.Logs {
-				log.Info("logEvent", "address", l.Address, "data", l.Data, "")
-			}
-		case <-d.logsSub.Err():
-			return
-		case <-d.rmLogsSub.Err():
-			return
-		case <-d.ctx.Done():
-			return
-		}
-	}
-}
-
-func (d Deposit) handleDepositEvent(txHash types.Hash, data []byte) {
-	// 1
-	pb, amount, sig, err := UnpackDepositLogData(data)
-	if err != nil {
-		log.Warn("cannot unpack deposit log data")
-		return
-	}
-	// 2
-	signature, err := bls.SignatureFromBytes(sig)
-	if err != nil {
-		log.Warn("cannot unpack BLS signature", "signature", hexutil.Encode(sig), "err", err)
-		return
-	}
-	// 3
-	publicKey, err := bls.PublicKeyFromBytes(pb)
-	if err != nil {
-		log.Warn("cannot unpack BLS publicKey", "publicKey", hexutil.Encode(pb), "err", err)
-		return
-	}
-	// 4
-	log.Trace("DepositEvent verify:", "signature", hexutil.Encode(signature.Marshal()), "publicKey", hexutil.Encode(publicKey.Marshal()), "msg", hexutil.Encode(amount.Bytes()))
-	if signature.Verify(publicKey, amount.Bytes()) {
-		var tx *transaction.Transaction
-		rwTx, err := d.db.BeginRw(d.ctx)
-		defer rwTx.Rollback()
-		if err != nil {
-			log.Error("cannot open db", "err", err)
-			return
-		}
-
-		tx, _, _, _, err = rawdb.ReadTransactionByHash(rwTx, txHash)
-		if err != nil {
-			log.Error("rawdb.ReadTransactionByHash", "err", err, "hash", txHash)
-		}
-
-		if tx != nil {
-			log.Trace("add Deposit info", "address", tx.From(), "amount", amount.String())
-
-			var pub types.PublicKey
-			pub.SetBytes(publicKey.Marshal())
-			//
-			rawdb.PutDeposit(rwTx, *tx.From(), pub, *amount)
-			rwTx.Commit()
-		}
-	} else {
-		log.Error("DepositEvent cannot Verify signature", "signature", hexutil.Encode(sig), "publicKey", hexutil.Encode(pb), "message", hexutil.Encode(amount.Bytes()), "err", err)
-	}
-}
-
-func (d Deposit) handleWithdrawnEvent(txHash types.Hash, data []byte) {
-	var tx *transaction.Transaction
-
-	rwTx, err := d.db.BeginRw(d.ctx)
-	defer rwTx.Rollback()
-	if err != nil {
-		log.Error("cannot open db", "err", err)
-		return
-	}
-	tx, _, _, _, err = rawdb.ReadTransactionByHash(rwTx, txHash)
-	if err != nil {
-		log.Error("rawdb.ReadTransactionByHash", "err", err, "hash", txHash)
-		return
-	}
-	if tx == nil {
-		log.Error("cannot find Transaction", "err", err, "hash", txHash)
-		return
-	}
-
-	err = rawdb.DeleteDeposit(rwTx, *tx.From())
-	if err != nil {
-		log.Error("cannot delete deposit", "err", err)
-		return
-	}
-	rwTx.Commit()
-}
@@ -1,9 +1,13 @@
 package deposit
 
 import (
+	"math/big"
+	"testing"
+
 	"github.com/amazechain/amc/common/crypto/bls"
 	"github.com/amazechain/amc/common/hexutil"
-	"testing"
+	"github.com/amazechain/amc/conf"
+	"github.com/holiman/uint256"
 )
 
 func TestBLS(t *testing.T) {
@@ -41,3 +45,69 @@ func TestBLS(t *testing.T) {
 	t.Logf("pubkey %s", string(pub))
 
 }
+
+func TestDefaultScheduleRewards(t *testing.T) {
+	tests := []struct {
+		amount            uint64
+		rewardPerBlock    *uint256.Int
+		maxRewardPerEpoch *uint256.Int
+	}{
+		{TenDeposit, uint256.NewInt(3333333333333334), uint256.NewInt(33333333333333340)},
+		{OneHundredDeposit, uint256.NewInt(666666666666667), uint256.NewInt(66666666666666700)},
+		{FiveHundredDeposit, uint256.NewInt(1000000000000000), uint256.NewInt(500000000000000000)},
+	}
+	schedule := ActiveSchedule(DefaultSchedules(), 0)
+	for _, tt := range tests {
+		var found bool
+		for _, tier := range schedule.Tiers {
+			if tier.Amount.Cmp(amt(tt.amount)) != 0 {
+				continue
+			}
+			found = true
+			perBlock, perEpoch := tierRewards(tier)
+			if perBlock.Cmp(tt.rewardPerBlock) != 0 || perEpoch.Cmp(tt.maxRewardPerEpoch) != 0 {
+				t.Errorf("tier %d: have %v/%v, want %v/%v", tt.amount, perBlock, perEpoch, tt.rewardPerBlock, tt.maxRewardPerEpoch)
+			}
+		}
+		if !found {
+			t.Errorf("tier %d missing from default schedule", tt.amount)
+		}
+	}
+}
+
+func TestActiveSchedule(t *testing.T) {
+	schedules := []*conf.DepositSchedule{
+		{Block: big.NewInt(100)},
+		{Block: big.NewInt(0)},
+		{Block: big.NewInt(50)},
+	}
+	for number, want := range map[uint64]int64{0: 0, 49: 0, 50: 50, 99: 50, 100: 100, 1000: 100} {
+		if have := ActiveSchedule(schedules, number); have.Block.Int64() != want {
+			t.Errorf("block %d: have schedule %d, want %d", number, have.Block.Int64(), want)
+		}
+	}
+	if ActiveSchedule(schedules[:1], 99) != nil {
+		t.Error("schedule active before its block")
+	}
+}
+
+func TestMatchTier(t *testing.T) {
+	tier := conf.DepositTier{Amount: amt(TenDeposit)}
+	extra := new(uint256.Int).AddUint64(uint256.MustFromBig(amt(TenDeposit)), 1)
+	tests := []struct {
+		amount *uint256.Int
+		legacy bool
+		want   bool
+	}{
+		{uint256.MustFromBig(amt(TenDeposit)), false, true},
+		{uint256.MustFromBig(amt(TenDeposit)), true, true},
+		{extra, false, false},
+		{extra, true, true},
+		{uint256.MustFromBig(amt(OneHundredDeposit)), true, false},
+	}
+	for i, tt := range tests {
+		if have := matchTier(tier, tt.amount, tt.legacy); have != tt.want {
+			t.Errorf("test %d: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package deposit
+
+import (
+	"fmt"
+
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// ProcessDeposits applies the deposit and withdrawal events emitted by the
+// deposit contract in the given block to the Deposit table. Every change is
+// recorded in the DepositChangeSet so that it can be reverted by
+// rawdb.UnwindDeposits when the block leaves the canonical chain.
+func ProcessDeposits(tx kv.RwTx, contract types.Address, number uint64, txs []*transaction.Transaction, receipts block.Receipts) error {
+	for i, receipt := range receipts {
+		if receipt == nil || i >= len(txs) || txs[i].From() == nil {
+			continue
+		}
+		from := *txs[i].From()
+		for _, l := range receipt.Logs {
+			if l.Address != contract || len(l.Topics) == 0 {
+				continue
+			}
+			switch l.Topics[0] {
+			case depositEventSignature:
+				pub, amount, ok := verifyDepositLog(l.Data)
+				if !ok {
+					continue
+				}
+				log.Trace("add Deposit info", "address", from, "amount", amount.String(), "number", number)
+				if err := rawdb.WriteDepositChange(tx, number, from); nil != err {
+					return err
+				}
+				if err := rawdb.PutDeposit(tx, from, pub, *amount); nil != err {
+					return err
+				}
+			case withdrawnSignature:
+				log.Trace("remove Deposit info", "address", from, "number", number)
+				if err := rawdb.WriteDepositChange(tx, number, from); nil != err {
+					return err
+				}
+				if err := rawdb.DeleteDeposit(tx, from); nil != err {
+					return err
+				}
+			}
+		}
+	}
+	return rawdb.WriteDepositProgress(tx, number)
+}
+
+// RebuildDeposits applies the deposit contract events of the canonical blocks
+// above the last processed block, which are only missing if the node stopped
+// between storing a block and its deposits.
+//
+// A registry without any recorded progress was kept by an earlier version and
+// may have diverged from the chain. It is reset and replayed from the receipts
+// of every canonical block, setRewards is run for each replayed block as it is
+// for ReplayDeposits.
+func RebuildDeposits(tx kv.RwTx, contract types.Address, setRewards func(tx kv.RwTx, b *block.Block) error) error {
+	current := rawdb.ReadCurrentBlock(tx)
+	if current == nil {
+		return nil
+	}
+	head := current.Number64().Uint64()
+
+	progress, ok, err := rawdb.ReadDepositProgress(tx)
+	if nil != err {
+		return err
+	}
+	if !ok {
+		log.Info("Rebuilding deposit registry", "head", head)
+		if err := ResetDeposits(tx); nil != err {
+			return err
+		}
+		return ReplayDeposits(tx, contract, 1, head, setRewards)
+	}
+	if progress >= head {
+		return nil
+	}
+	if err := ReplayDeposits(tx, contract, progress+1, head, nil); nil != err {
+		return err
+	}
+	log.Info("Caught up deposit registry", "from", progress+1, "to", head)
+	return nil
+}
+
+// ResetDeposits clears the deposit registry, its history and the rewards
+// derived from it, leaving the registry of the genesis block.
+func ResetDeposits(tx kv.RwTx) error {
+	for _, table := range []string{modules.Deposit, modules.DepositChangeSet, modules.DepositHistory, modules.Reward} {
+		if err := tx.ClearBucket(table); nil != err {
+			return err
+		}
+	}
+	return rawdb.WriteDepositProgress(tx, 0)
+}
+
+// ReplayDeposits applies the deposit contract events of the canonical blocks
+// in [from, to], read from their receipts. If before is not nil it is run for
+// each block before its events are applied, when the registry is the one of
+// the parent block the block was executed on.
+func ReplayDeposits(tx kv.RwTx, contract types.Address, from, to uint64, before func(tx kv.RwTx, b *block.Block) error) error {
+	// The events are read from the receipts, which must not be pruned
+	if from <= to {
+		if err := rawdb.CheckPruned(tx, rawdb.PruneKindReceipts, from); nil != err {
+			return fmt.Errorf("cannot replay deposit registry: %w", err)
+		}
+	}
+	for number := from; number <= to; number++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, number)
+		if nil != err {
+			return err
+		}
+		b := rawdb.ReadBlock(tx, hash, number)
+		if b == nil {
+			return fmt.Errorf("canonical block %d not found", number)
+		}
+		receipts, err := rawdb.ReadReceiptsByHash(tx, hash)
+		if nil != err {
+			return err
+		}
+		if len(receipts) != len(b.Transactions()) {
+			return fmt.Errorf("receipts of canonical block %d not found", number)
+		}
+		if before != nil {
+			if err := before(tx, b); nil != err {
+				return err
+			}
+		}
+		if err := ProcessDeposits(tx, contract, number, b.Transactions(), receipts); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyDepositLog unpacks a DepositEvent and checks that the BLS signature
+// over the deposited amount was made by the deposited public key.
+func verifyDepositLog(data []byte) (types.PublicKey, *uint256.Int, bool) {
+	pb, amount, sig, err := UnpackDepositLogData(data)
+	if err != nil {
+		log.Warn("cannot unpack deposit log data")
+		return types.PublicKey{}, nil, false
+	}
+	signature, err := bls.SignatureFromBytes(sig)
+	if err != nil {
+		log.Warn("cannot unpack BLS signature", "signature", hexutil.Encode(sig), "err", err)
+		return types.PublicKey{}, nil, false
+	}
+	publicKey, err := bls.PublicKeyFromBytes(pb)
+	if err != nil {
+		log.Warn("cannot unpack BLS publicKey", "publicKey", hexutil.Encode(pb), "err", err)
+		return types.PublicKey{}, nil, false
+	}
+	if !signature.Verify(publicKey, amount.Bytes()) {
+		log.Error("DepositEvent cannot Verify signature", "signature", hexutil.Encode(sig), "publicKey", hexutil.Encode(pb), "message", hexutil.Encode(amount.Bytes()))
+		return types.PublicKey{}, nil, false
+	}
+
+	var pub types.PublicKey
+	pub.SetBytes(publicKey.Marshal())
+	return pub, amount, true
+}
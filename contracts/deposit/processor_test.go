@@ -0,0 +1,119 @@
+package deposit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/amazechain/amc/accounts/abi"
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+var testContract = types.Address{0xde}
+
+// depositTx returns a transaction from addr and its receipt carrying the
+// deposit contract event of a deposit of amount signed by the key of seed.
+func depositTx(t *testing.T, addr types.Address, seed byte, amount uint64) (*transaction.Transaction, *block.Receipt) {
+	sk, err := bls.SecretKeyFromRandom32Byte([32]byte{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractAbi, err := abi.JSON(bytes.NewReader(depositAbiCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := uint256.NewInt(amount)
+	data, err := contractAbi.Events["DepositEvent"].Inputs.Pack(sk.PublicKey().Marshal(), value.ToBig(), sk.Sign(value.Bytes()).Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contractTx(addr), &block.Receipt{Logs: []*block.Log{{
+		Address: testContract,
+		Topics:  []types.Hash{depositEventSignature},
+		Data:    data,
+	}}}
+}
+
+// withdrawTx returns a transaction from addr and its receipt carrying the
+// withdrawal event of the deposit contract.
+func withdrawTx(addr types.Address) (*transaction.Transaction, *block.Receipt) {
+	return contractTx(addr), &block.Receipt{Logs: []*block.Log{{
+		Address: testContract,
+		Topics:  []types.Hash{withdrawnSignature},
+	}}}
+}
+
+func contractTx(from types.Address) *transaction.Transaction {
+	to := testContract
+	return transaction.NewTx(&transaction.LegacyTx{
+		GasPrice: uint256.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		From:     &from,
+		Value:    uint256.NewInt(0),
+	})
+}
+
+func processBlock(t *testing.T, tx kv.RwTx, number uint64, txs []*transaction.Transaction, receipts block.Receipts) {
+	t.Helper()
+	if err := ProcessDeposits(tx, testContract, number, txs, receipts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessDepositsReorg(t *testing.T) {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	_, tx := memdb.NewTestTx(t)
+
+	a, b := types.Address{0x0a}, types.Address{0x0b}
+	if err := rawdb.WriteDepositProgress(tx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Block 1 deposits for a, the old block 2 withdraws it
+	txA, receiptA := depositTx(t, a, 1, TenDeposit)
+	processBlock(t, tx, 1, []*transaction.Transaction{txA}, block.Receipts{receiptA})
+	txW, receiptW := withdrawTx(a)
+	processBlock(t, tx, 2, []*transaction.Transaction{txW}, block.Receipts{receiptW})
+	if rawdb.IsDeposit(tx, a) {
+		t.Fatal("withdrawn deposit still present")
+	}
+
+	// The reorg unwinds the old block 2 and applies the new one depositing
+	// for b instead
+	if err := rawdb.UnwindDeposits(tx, 2); err != nil {
+		t.Fatal(err)
+	}
+	txB, receiptB := depositTx(t, b, 2, TenDeposit)
+	processBlock(t, tx, 2, []*transaction.Transaction{txB}, block.Receipts{receiptB})
+
+	for _, addr := range []types.Address{a, b} {
+		if _, amount, err := rawdb.GetDeposit(tx, addr); err != nil || amount.Uint64() != TenDeposit {
+			t.Errorf("deposit of %s: have %v (err %v), want %d", addr, amount, err, TenDeposit)
+		}
+	}
+	if n, err := rawdb.DepositNumAt(tx, 1); err != nil || n != 1 {
+		t.Errorf("deposits at block 1: have %d (err %v), want 1", n, err)
+	}
+
+	// Unwinding both blocks leaves no deposits behind
+	for _, number := range []uint64{2, 1} {
+		if err := rawdb.UnwindDeposits(tx, number); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rawdb.IsDeposit(tx, a) || rawdb.IsDeposit(tx, b) {
+		t.Error("unwound deposits still present")
+	}
+	if progress, ok, err := rawdb.ReadDepositProgress(tx); err != nil || !ok || progress != 0 {
+		t.Errorf("progress %d (%v, %v), want 0", progress, ok, err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/types"
+	"github.com/holiman/uint256"
+)
+
+// AdminAPI is the collection of administrative API methods exposed over
+// the admin namespace.
+type AdminAPI struct {
+	api *API
+}
+
+// NewAdminAPI creates a new instance of AdminAPI.
+func NewAdminAPI(api *API) *AdminAPI {
+	return &AdminAPI{api: api}
+}
+
+// TxPoolPriceLimit returns the minimum gas price a transaction needs to be
+// accepted into the transaction pool.
+func (api *AdminAPI) TxPoolPriceLimit() *hexutil.Big {
+	return (*hexutil.Big)(api.api.TxsPool().GasPrice().ToBig())
+}
+
+// SetTxPoolPriceLimit sets the minimum gas price a transaction needs to be
+// accepted into the transaction pool. Remote transactions below a raised
+// limit are dropped from the pool.
+func (api *AdminAPI) SetTxPoolPriceLimit(price hexutil.Big) (bool, error) {
+	if err := api.api.setGasPrice(price); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setGasPrice sets the minimum gas price a transaction needs to be accepted
+// into the transaction pool.
+func (n *API) setGasPrice(price hexutil.Big) error {
+	if price.ToInt().Sign() <= 0 {
+		return errors.New("price limit must be positive")
+	}
+	limit, overflow := uint256.FromBig(price.ToInt())
+	if overflow {
+		return errors.New("price limit higher than 2^256-1")
+	}
+	n.TxsPool().SetGasPrice(limit)
+	return nil
+}
+
+// PeerInfo is the connection information of a connected peer as returned by
+// admin_peers.
+type PeerInfo struct {
+	ID           string       `json:"id"`
+	Addrs        []string     `json:"addrs"`
+	Version      string       `json:"version"`
+	Inbound      bool         `json:"inbound"`
+	Static       bool         `json:"static"`
+	Trusted      bool         `json:"trusted"`
+	Height       uint64       `json:"height"`
+	TD           *hexutil.Big `json:"td"`
+	Capabilities []string     `json:"capabilities"`
+	ConnectedAt  time.Time    `json:"connectedAt"`
+}
+
+// NodeInfo is the connection information and chain status of the local node
+// as returned by admin_nodeInfo. Addrs are the external addresses announced
+// to other peers.
+type NodeInfo struct {
+	ID           string         `json:"id"`
+	Addrs        []string       `json:"addrs"`
+	ListenAddrs  []string       `json:"listenAddrs"`
+	Reachability string         `json:"reachability"`
+	Version      string         `json:"version"`
+	ChainID      hexutil.Uint64 `json:"chainId"`
+	Genesis      types.Hash     `json:"genesis"`
+	Height       uint64         `json:"height"`
+	TD           *hexutil.Big   `json:"td"`
+	ForkID       ForkID         `json:"forkId"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// ForkID is the EIP-2124 fork identifier of the local chain.
+type ForkID struct {
+	Hash hexutil.Bytes `json:"hash"`
+	Next uint64        `json:"next"`
+}
+
+// Peers returns the connection information of the connected peers.
+func (api *AdminAPI) Peers() []PeerInfo {
+	peers := api.api.P2pServer().Peers()
+	infos := make([]PeerInfo, 0, len(peers))
+	for _, p := range peers {
+		info := PeerInfo{
+			ID:           p.ID.String(),
+			Addrs:        p.Addrs,
+			Version:      p.Version,
+			Inbound:      p.Inbound,
+			Static:       p.Static,
+			Trusted:      p.Trusted,
+			Capabilities: p.Capabilities,
+			ConnectedAt:  p.ConnectedAt,
+		}
+		if p.Height != nil {
+			info.Height = p.Height.Uint64()
+		}
+		if p.TD != nil {
+			info.TD = (*hexutil.Big)(p.TD.ToBig())
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// NodeInfo returns the connection information and chain status of the local
+// node.
+func (api *AdminAPI) NodeInfo() (*NodeInfo, error) {
+	node, err := api.api.P2pServer().NodeInfo()
+	if err != nil {
+		return nil, err
+	}
+	return newNodeInfo(node), nil
+}
+
+func newNodeInfo(node common.NodeInfo) *NodeInfo {
+	status := node.Status
+	return &NodeInfo{
+		ID:           node.ID.String(),
+		Addrs:        node.Addrs,
+		ListenAddrs:  node.ListenAddrs,
+		Reachability: node.Reachability,
+		Version:      node.Version,
+		ChainID:      hexutil.Uint64(status.ChainID),
+		Genesis:      status.GenesisHash,
+		Height:       status.CurrentHeight.Uint64(),
+		TD:           (*hexutil.Big)(status.TD.ToBig()),
+		ForkID: ForkID{
+			Hash: status.ForkID.Hash[:],
+			Next: status.ForkID.Next,
+		},
+		Capabilities: status.Capabilities,
+	}
+}
+
+// AddPeer connects to the peer at the given p2p multiaddr and keeps it
+// connected. The peer is persisted in the static peers of the datadir.
+func (api *AdminAPI) AddPeer(addr string) (bool, error) {
+	if err := api.api.P2pServer().AddPeer(addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeer disconnects the peer at the given p2p multiaddr and removes it
+// from the static peers.
+func (api *AdminAPI) RemovePeer(addr string) (bool, error) {
+	if err := api.api.P2pServer().RemovePeer(addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddTrustedPeer marks the peer at the given p2p multiaddr as trusted. The
+// peer is persisted in the trusted peers of the datadir.
+func (api *AdminAPI) AddTrustedPeer(addr string) (bool, error) {
+	if err := api.api.P2pServer().AddTrustedPeer(addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer removes the peer at the given p2p multiaddr from the
+// trusted peers.
+func (api *AdminAPI) RemoveTrustedPeer(addr string) (bool, error) {
+	if err := api.api.P2pServer().RemoveTrustedPeer(addr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
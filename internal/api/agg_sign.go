@@ -2,31 +2,18 @@ package api
 
 import (
 	"context"
-	"encoding/hex"
-	"fmt"
-	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/block"
 	"github.com/amazechain/amc/common/crypto/bls"
 	"github.com/amazechain/amc/common/crypto/bls/blst"
 	"github.com/amazechain/amc/common/types"
-	"github.com/amazechain/amc/contracts/deposit"
 	"github.com/amazechain/amc/internal/consensus"
 	"github.com/amazechain/amc/log"
-	event "github.com/amazechain/amc/modules/event/v2"
 	"github.com/amazechain/amc/modules/rawdb"
 	"github.com/ledgerwatch/erigon-lib/kv"
 )
 
 var sigChannel = make(chan AggSign, 10)
 
-var validVerifers = map[string]string{
-	"AMC4541Fc1CCB4e042a3BaDFE46904F9D22d127B682": "157aee59b889a8a9e3ecec11e4f79f6c065e3d21c6da2222b916c54f8c820d9c",
-	"AMC9BA336835422BAeFc537d75642959d2a866500a3": "00121edadf6e723f2fe8c23d2359f57a7058986a1b8458d23eb29db7204afea7",
-	"AMCf13d680bA12717fE27d33caB983c5C755Ff74358": "5de474bbf3fee5dfda9047287f596c6e6c0271876305357e399fffba6a5f9a9f",
-	"AMCd1ff88affe38dfb65c621706dff6468ecd418bff": "4c1ad066cc2971c94a8aca6a7d3e4bdd86891922c5b1d39e6d0da8cad9262be4",
-	"AMCb9e94477f5f88b5e8da2e97e8506d6e4fcf04e5b": "2c02dd3cf600af9a8567e5cc5ff158c1b89e1f3ea21bff61f505d141a96a60ee",
-}
-
 //type WithCodeAndHash struct {
 //	CodeIndex []byte `json:"codeIndex"`
 //	Code      []byte `json:"code"`
@@ -168,15 +155,6 @@ func (s *AggSign) Check(root types.Hash) bool {
 	return sig.Verify(pub, s.StateRoot[:])
 }
 
-func DepositInfo(db kv.RwDB, key types.Address) *deposit.Info {
-	var info *deposit.Info
-	_ = db.View(context.Background(), func(tx kv.Tx) error {
-		info = deposit.GetDepositInfo(tx, key)
-		return nil
-	})
-	return info
-}
-
 func IsDeposit(db kv.RwDB, addr types.Address) (bool, error) {
 	tx, err := db.BeginRo(context.Background())
 	if nil != err {
@@ -187,7 +165,23 @@ func IsDeposit(db kv.RwDB, addr types.Address) (bool, error) {
 	return rawdb.IsDeposit(tx, addr), nil
 }
 
-func SignMerge(ctx context.Context, header *block.Header, depositNum uint64) (types.Signature, []*block.Verify, error) {
+// depositPublicKey returns the public key deposited by addr.
+func depositPublicKey(db kv.RwDB, addr types.Address) (types.PublicKey, error) {
+	tx, err := db.BeginRo(context.Background())
+	if nil != err {
+		return types.PublicKey{}, err
+	}
+	defer tx.Rollback()
+
+	pub, _, err := rawdb.GetDeposit(tx, addr)
+	return pub, err
+}
+
+// SignMerge collects verifier signatures for the given header until ctx is done
+// and aggregates them. Signatures that do not match the header's state root or
+// are not made with the key deposited by their account are discarded. It returns
+// consensus.ErrNotEnoughSign if fewer than quorum signatures were collected.
+func SignMerge(ctx context.Context, db kv.RwDB, header *block.Header, quorum uint64) (types.Signature, []*block.Verify, error) {
 	aggrSigns := make([]bls.Signature, 0)
 	verifiers := make([]*block.Verify, 0)
 	uniq := make(map[types.Address]struct{})
@@ -206,7 +200,13 @@ LOOP:
 				continue
 			}
 
-			if false && !s.Check(header.Root) {
+			// A signature under any other key than the deposited one would
+			// fail the aggregate.
+			if pub, err := depositPublicKey(db, s.Address); nil != err || pub != s.PublicKey {
+				log.Tracef("discard sign: %s has no deposit of key %x", s.Address, s.PublicKey)
+				continue
+			}
+			if !s.Check(header.Root) {
 				log.Tracef("discard sign: sign check failed! %v", s)
 				continue
 			}
@@ -225,11 +225,8 @@ LOOP:
 			break LOOP
 		}
 	}
-	// todo enough sigs check
-	// 1
-	// uint64(len(aggrSigns)) < depositNum/2
-	// uint64(len(aggrSigns)) < 7
-	if uint64(len(aggrSigns)) < 1 {
+
+	if len(aggrSigns) == 0 || uint64(len(aggrSigns)) < quorum {
 		return types.Signature{}, nil, consensus.ErrNotEnoughSign
 	}
 
@@ -238,59 +235,3 @@ LOOP:
 	copy(aggSign[:], aggS.Marshal())
 	return aggSign, verifiers, nil
 }
-
-func MachineVerify(ctx context.Context) error {
-	entire := make(chan common.MinedEntireEvent)
-	blocksSub := event.GlobalFeed.Subscribe(entire)
-	defer blocksSub.Unsubscribe()
-
-	errs := make(chan error)
-	defer close(errs)
-
-	for {
-		select {
-		case b := <-entire:
-			log.Tracef("machine verify accept entire, number: %d", b.Entire.Entire.Header.Number.Uint64())
-			for k, s := range validVerifers {
-				go func(seckey string, address string) {
-					// recover private key
-					sByte, err := hex.DecodeString(seckey)
-					if nil != err {
-						errs <- err
-						return
-					}
-					var addr types.Address
-					if !addr.DecodeString(address) {
-						errs <- fmt.Errorf("unvalid address")
-						return
-					}
-
-					// publicKey
-					var bs [32]byte
-					copy(bs[:], sByte)
-					pri, err := bls.SecretKeyFromRandom32Byte(bs)
-					if nil != err {
-						errs <- err
-						return
-					}
-
-					// Signature
-					sign := pri.Sign(b.Entire.Entire.Header.Root[:])
-					tmp := AggSign{Number: b.Entire.Entire.Header.Number.Uint64()}
-					copy(tmp.StateRoot[:], b.Entire.Entire.Header.Root[:])
-					copy(tmp.Sign[:], sign.Marshal())
-					copy(tmp.PublicKey[:], pri.PublicKey().Marshal())
-					tmp.Address = addr
-					// send res
-					sigChannel <- tmp
-					//log.Tracef("send verify sign, %+v", tmp)
-				}(s, k)
-			}
-		case <-ctx.Done():
-			return nil
-		case err := <-errs:
-			return err
-		}
-	}
-	return nil
-}
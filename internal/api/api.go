@@ -18,6 +18,7 @@ package api
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -33,6 +34,7 @@ import (
 
 	"math"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -49,6 +51,7 @@ import (
 	mvm_common "github.com/amazechain/amc/internal/avm/common"
 	mvm_types "github.com/amazechain/amc/internal/avm/types"
 	"github.com/amazechain/amc/internal/consensus"
+	"github.com/amazechain/amc/internal/tracers/logger"
 	"github.com/amazechain/amc/log"
 	"github.com/amazechain/amc/modules/rawdb"
 	"github.com/amazechain/amc/modules/rpc/jsonrpc"
@@ -73,15 +76,17 @@ type API struct {
 	engine     consensus.Engine
 	txspool    txs_pool.ITxsPool
 	downloader common.IDownloader
+	miner      common.IMiner
 
 	accountManager *accounts.Manager
 	chainConfig    *params.ChainConfig
+	extRPCEnabled  bool
 
 	gpo *Oracle
 }
 
 // NewAPI creates a new protocol API.
-func NewAPI(pubsub common.IPubSub, p2pserver common.INetwork, peers map[peer.ID]common.Peer, bc common.IBlockChain, db kv.RwDB, engine consensus.Engine, txspool txs_pool.ITxsPool, downloader common.IDownloader, accountManager *accounts.Manager, config *params.ChainConfig) *API {
+func NewAPI(pubsub common.IPubSub, p2pserver common.INetwork, peers map[peer.ID]common.Peer, bc common.IBlockChain, db kv.RwDB, engine consensus.Engine, txspool txs_pool.ITxsPool, downloader common.IDownloader, miner common.IMiner, accountManager *accounts.Manager, config *params.ChainConfig) *API {
 	return &API{
 		db:             db,
 		pubsub:         pubsub,
@@ -91,6 +96,7 @@ func NewAPI(pubsub common.IPubSub, p2pserver common.INetwork, peers map[peer.ID]
 		engine:         engine,
 		txspool:        txspool,
 		downloader:     downloader,
+		miner:          miner,
 		accountManager: accountManager,
 		chainConfig:    config,
 	}
@@ -100,6 +106,12 @@ func (api *API) SetGpo(gpo *Oracle) {
 	api.gpo = gpo
 }
 
+// SetExtRPCEnabled sets whether the RPC endpoints are exposed to external
+// hosts, which forbids unlocking accounts unless insecure unlocking is allowed.
+func (api *API) SetExtRPCEnabled(enabled bool) {
+	api.extRPCEnabled = enabled
+}
+
 func (api *API) Apis() []jsonrpc.API {
 	nonceLock := new(AddrLocker)
 	return []jsonrpc.API{
@@ -112,6 +124,15 @@ func (api *API) Apis() []jsonrpc.API {
 		}, {
 			Namespace: "eth",
 			Service:   NewTransactionAPI(api, nonceLock),
+		}, {
+			Namespace: "eth",
+			Service:   NewDownloaderAPI(api),
+		}, {
+			Namespace: "eth",
+			Service:   NewAccountAPI(api.AccountManager()),
+		}, {
+			Namespace: "personal",
+			Service:   NewPersonalAccountAPI(api, nonceLock),
 		}, {
 			Namespace: "web3",
 			Service:   &Web3API{api},
@@ -126,6 +147,12 @@ func (api *API) Apis() []jsonrpc.API {
 		{
 			Namespace: "txpool",
 			Service:   NewTxsPoolAPI(api),
+		}, {
+			Namespace: "admin",
+			Service:   NewAdminAPI(api),
+		}, {
+			Namespace: "miner",
+			Service:   NewMinerAPI(api),
 		}, {
 			Namespace: "eth",
 			Service:   filters.NewFilterAPI(api, 5*time.Minute),
@@ -135,6 +162,7 @@ func (api *API) Apis() []jsonrpc.API {
 
 func (n *API) TxsPool() txs_pool.ITxsPool     { return n.txspool }
 func (n *API) Downloader() common.IDownloader { return n.downloader }
+func (n *API) Miner() common.IMiner           { return n.miner }
 func (n *API) P2pServer() common.INetwork     { return n.p2pserver }
 func (n *API) Peers() map[peer.ID]common.Peer { return n.peers }
 func (n *API) Database() kv.RwDB              { return n.db }
@@ -156,7 +184,9 @@ func (n *API) GetEvm(ctx context.Context, msg internal.Message, ibs evmtypes.Int
 	return vm2.NewEVM(context, txContext, ibs, n.GetChainConfig(), *vmConfig), vmError, nil
 }
 
-func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) evmtypes.IntraBlockState {
+// State returns the state after the given block. It is nil if the block is
+// unknown, and an error wrapping rawdb.ErrPruned if its history was pruned.
+func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) (evmtypes.IntraBlockState, error) {
 	// todo if header not found
 	var blockHash types.Hash
 
@@ -168,7 +198,7 @@ func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) evmtypes.
 		} else {
 			header = n.BlockChain().GetHeaderByNumber(uint256.NewInt(uint64(blockNr.Int64())))
 			if header == nil {
-				return nil
+				return nil, nil
 			}
 		}
 
@@ -181,11 +211,16 @@ func (n *API) State(tx kv.Tx, blockNrOrHash jsonrpc.BlockNumberOrHash) evmtypes.
 
 	blockNr := rawdb.ReadHeaderNumber(tx, blockHash)
 	if nil == blockNr {
-		return nil
+		return nil, nil
+	}
+	// The state after the block is read back from the change sets of the
+	// blocks after it.
+	if err := rawdb.CheckPruned(tx, rawdb.PruneKindHistory, *blockNr+1); nil != err {
+		return nil, err
 	}
 
 	stateReader := state.NewPlainState(tx, *blockNr+1)
-	return state.New(stateReader)
+	return state.New(stateReader), nil
 }
 
 func (n *API) GetChainConfig() *params.ChainConfig {
@@ -232,6 +267,30 @@ func (s *AmcAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(tipcap), err
 }
 
+// Syncing returns false if the node is synced, otherwise the progress of the
+// chain synchronisation.
+func (s *AmcAPI) Syncing() (interface{}, error) {
+	progress := s.api.Downloader().Progress()
+	if !s.api.Downloader().IsDownloading() && progress.CurrentBlock >= progress.HighestBlock {
+		return false, nil
+	}
+	return newSyncStatus(progress), nil
+}
+
+// Coinbase returns the address the node seals blocks with.
+func (s *AmcAPI) Coinbase() (mvm_common.Address, error) {
+	coinbase := s.api.Miner().Coinbase()
+	if coinbase == (types.Address{}) {
+		return mvm_common.Address{}, errors.New("etherbase must be explicitly specified")
+	}
+	return *mvm_types.FromAmcAddress(&coinbase), nil
+}
+
+// Mining returns whether the node is sealing blocks.
+func (s *AmcAPI) Mining() bool {
+	return s.api.Miner().Mining()
+}
+
 type feeHistoryResult struct {
 	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
@@ -331,17 +390,17 @@ func (s *TxPoolAPI) Inspect() map[string]map[string]map[string]string {
 // AccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type AccountAPI struct {
+	am *accounts.Manager
 }
 
 // NewAccountAPI creates a new AccountAPI.
-func NewAccountAPI() *AccountAPI {
-	return &AccountAPI{}
+func NewAccountAPI(am *accounts.Manager) *AccountAPI {
+	return &AccountAPI{am: am}
 }
 
 // Accounts returns the collection of accounts this node manages.
 func (s *AccountAPI) Accounts() []types.Address {
-	//return s.am.Accounts()
-	return nil
+	return s.am.Accounts()
 }
 
 // BlockChainAPI provides an API to access Ethereum blockchain data.
@@ -367,9 +426,9 @@ func (s *BlockChainAPI) GetBalance(ctx context.Context, address mvm_common.Addre
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
-	if state == nil {
-		return nil, nil
+	state, err := s.api.State(tx, blockNrOrHash)
+	if nil != err || state == nil {
+		return nil, err
 	}
 	balance := state.GetBalance(*mvm_types.ToAmcAddress(&address))
 	return (*hexutil.Big)(balance.ToBig()), nil
@@ -389,9 +448,9 @@ func (s *BlockChainAPI) GetCode(ctx context.Context, address mvm_common.Address,
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
-	if state == nil {
-		return nil, nil
+	state, err := s.api.State(tx, blockNrOrHash)
+	if nil != err || state == nil {
+		return nil, err
 	}
 	code := state.GetCode(*mvm_types.ToAmcAddress(&address))
 	return code, nil
@@ -407,9 +466,9 @@ func (s *BlockChainAPI) GetStorageAt(ctx context.Context, address types.Address,
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
-	if state == nil {
-		return nil, nil
+	state, err := s.api.State(tx, blockNrOrHash)
+	if nil != err || state == nil {
+		return nil, err
 	}
 	var va uint256.Int
 	k := types.HexToHash(key)
@@ -417,6 +476,52 @@ func (s *BlockChainAPI) GetStorageAt(ctx context.Context, address types.Address,
 	return va.Bytes(), nil
 }
 
+// GetProof returns the Merkle proof of the account and of the given storage
+// slots at the given block. Proofs are available from the merkle fork on.
+func (s *BlockChainAPI) GetProof(ctx context.Context, address types.Address, storageKeys []string, blockNrOrHash jsonrpc.BlockNumberOrHash) (*AccountResult, error) {
+	header, err := s.api.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if nil != err {
+		return nil, err
+	}
+	if !s.api.GetChainConfig().IsMerkle(header.Number.Uint64()) {
+		return nil, fmt.Errorf("state proofs are not available before the merkle fork block")
+	}
+	keys := make([]types.Hash, len(storageKeys))
+	for i, key := range storageKeys {
+		if keys[i], err = decodeHash(key); nil != err {
+			return nil, err
+		}
+	}
+
+	tx, err := s.api.db.BeginRo(ctx)
+	if nil != err {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	proof, err := state.ProveAccount(tx, header.Root, address, keys)
+	if nil != err {
+		return nil, err
+	}
+	storageProof := make([]StorageResult, len(proof.Storage))
+	for i, sp := range proof.Storage {
+		storageProof[i] = StorageResult{
+			Key:   storageKeys[i],
+			Value: (*hexutil.Big)(sp.Value.ToBig()),
+			Proof: toHexSlice(sp.Proof),
+		}
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(proof.Proof),
+		Balance:      (*hexutil.Big)(proof.Balance.ToBig()),
+		CodeHash:     proof.CodeHash,
+		Nonce:        hexutil.Uint64(proof.Nonce),
+		StorageHash:  proof.StorageHash,
+		StorageProof: storageProof,
+	}, nil
+}
+
 // GetUncleCountByBlockHash returns number of uncles in the block for the given block hash
 func (s *BlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash mvm_common.Hash) *hexutil.Uint {
 	if block, _ := s.api.BlockChain().GetBlockByHash(mvm_types.ToAmcHash(blockHash)); block != nil {
@@ -427,6 +532,26 @@ func (s *BlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash
 	return nil
 }
 
+// GetUncleCountByBlockNumber returns number of uncles in the block for the given block number
+func (s *BlockChainAPI) GetUncleCountByBlockNumber(ctx context.Context, blockNr jsonrpc.BlockNumber) *hexutil.Uint {
+	if block, _ := blockByNumber(s.api, blockNr); block != nil {
+		//POA donot have Uncles
+		n := hexutil.Uint(0)
+		return &n
+	}
+	return nil
+}
+
+// GetUncleByBlockNumberAndIndex returns the uncle block for the given block number and index.
+func (s *BlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr jsonrpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
+	b, err := blockByNumber(s.api, blockNr)
+	if b != nil {
+		//POA donot have Uncles
+		return nil, nil
+	}
+	return nil, err
+}
+
 // GetUncleByBlockHashAndIndex returns the uncle block for the given block hash and index.
 func (s *BlockChainAPI) GetUncleByBlockHashAndIndex(ctx context.Context, blockHash mvm_common.Hash, index hexutil.Uint) (map[string]interface{}, error) {
 	b, err := s.api.BlockChain().GetBlockByHash(mvm_types.ToAmcHash(blockHash))
@@ -560,15 +685,20 @@ func (diff *BlockOverrides) Apply(blockCtx *evmtypes.BlockContext) {
 func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash jsonrpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) (*internal.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
+	return applyCall(ctx, api, args, blockNrOrHash, overrides, &vm2.Config{NoBaseFee: true}, timeout, globalGasCap)
+}
+
+// headerByNumberOrHash returns the header of the given block, or the head of
+// the chain for the pending block.
+func headerByNumberOrHash(api *API, blockNrOrHash jsonrpc.BlockNumberOrHash) (block.IHeader, error) {
 	// header := api.BlockChain().CurrentBlock().Header()
 	//state := api.BlockChain().StateAt(header.Hash()).(*statedb.StateDB)
 	var header block.IHeader
 	var err error
 	if blockNr, ok := blockNrOrHash.Number(); ok {
-		if blockNr < jsonrpc.EarliestBlockNumber {
-			header = api.BlockChain().CurrentBlock().Header()
-		} else {
-			header = api.BlockChain().GetHeaderByNumber(uint256.NewInt(uint64(blockNr.Int64())))
+		var b block.IBlock
+		if b, err = blockByNumber(api, blockNr); b != nil {
+			header = b.Header()
 		}
 	}
 	if hash, ok := blockNrOrHash.Hash(); ok {
@@ -577,6 +707,19 @@ func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash j
 	if err != nil {
 		return nil, err
 	}
+	if header == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	return header, nil
+}
+
+// applyCall executes the call on the state of the given block with the state
+// overrides applied.
+func applyCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash jsonrpc.BlockNumberOrHash, overrides *StateOverride, vmConfig *vm2.Config, timeout time.Duration, globalGasCap uint64) (*internal.ExecutionResult, error) {
+	header, err := headerByNumberOrHash(api, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
 	//state := api.State(blockNrOrHash).(*statedb.StateDB)
 	tx, err := api.db.BeginRo(ctx)
 	if nil != err {
@@ -586,7 +729,13 @@ func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash j
 
 	//reader := state.NewPlainStateReader(tx)
 	//ibs := state.New(reader)
-	ibs := api.State(tx, blockNrOrHash)
+	ibs, err := api.State(tx, blockNrOrHash)
+	if nil != err {
+		return nil, err
+	}
+	if ibs == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
 	if err := overrides.Apply(ibs.(*state.IntraBlockState)); err != nil {
 		return nil, err
 	}
@@ -609,7 +758,7 @@ func DoCall(ctx context.Context, api *API, args TransactionArgs, blockNrOrHash j
 	}
 
 	//todo debug: , Debug: true, Tracer: vm.NewMarkdownLogger(os.Stdout)
-	evm, vmError, err := api.GetEvm(ctx, msg, ibs, header, &vm2.Config{NoBaseFee: true})
+	evm, vmError, err := api.GetEvm(ctx, msg, ibs, header, vmConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -689,22 +838,7 @@ func (s *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrO
 }
 
 func BlockByNumber(ctx context.Context, number jsonrpc.BlockNumber, n *API) (block.IBlock, error) {
-	// todo
-	// Pending block is only known by the miner
-	if number == jsonrpc.PendingBlockNumber {
-		iblock := n.BlockChain().CurrentBlock()
-		return iblock, nil
-	}
-	// Otherwise resolve and return the block
-	if number == jsonrpc.LatestBlockNumber {
-		iblock := n.BlockChain().CurrentBlock()
-		return iblock, nil
-	}
-	iblock, err := n.BlockChain().GetBlockByNumber(uint256.NewInt(uint64(number)))
-	if err != nil {
-		return nil, err
-	}
-	return iblock, nil
+	return blockByNumber(n, number)
 }
 
 func BlockByNumberOrHash(ctx context.Context, blockNrOrHash jsonrpc.BlockNumberOrHash, api *API) (block.IBlock, error) {
@@ -827,7 +961,10 @@ func DoEstimateGas(ctx context.Context, n *API, args TransactionArgs, blockNrOrH
 			return 0, err
 		}
 		defer tx.Rollback()
-		statedb := n.State(tx, blockNrOrHash)
+		statedb, err := n.State(tx, blockNrOrHash)
+		if nil != err {
+			return 0, err
+		}
 		if statedb == nil {
 			return 0, errors.New("cannot load stateDB")
 		}
@@ -921,25 +1058,109 @@ func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, b
 	return DoEstimateGas(ctx, s.api, args, bNrOrHash, rpcGasCap)
 }
 
+// accessListResult returns an optional accesslist
+// Its the result of the `eth_createAccessList` RPC call.
+// It contains an error if the transaction itself failed.
+type accessListResult struct {
+	Accesslist *mvm_types.AccessList `json:"accessList"`
+	Error      string                `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64        `json:"gasUsed"`
+}
+
+// CreateAccessList creates an AccessList for the given transaction.
+// BlockNrOrHash and state overrides can be specified to create the accessList
+// on top of a certain state.
+func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *jsonrpc.BlockNumberOrHash, overrides *StateOverride) (*accessListResult, error) {
+	bNrOrHash := jsonrpc.BlockNumberOrHashWithNumber(jsonrpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	acl, gasUsed, vmerr, err := AccessList(ctx, s.api, bNrOrHash, args, overrides)
+	if err != nil {
+		return nil, err
+	}
+	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
+	if vmerr != nil {
+		result.Error = vmerr.Error()
+	}
+	return result, nil
+}
+
+// AccessList creates an access list for the given transaction.
+// If the accesslist creation fails an error is returned.
+// If the transaction itself fails, an vmErr is returned.
+func AccessList(ctx context.Context, api *API, blockNrOrHash jsonrpc.BlockNumberOrHash, args TransactionArgs, overrides *StateOverride) (acl mvm_types.AccessList, gasUsed uint64, vmErr error, err error) {
+	header, err := headerByNumberOrHash(api, blockNrOrHash)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	// If the gas amount is not set, default to RPC gas cap.
+	if args.Gas == nil {
+		tmp := hexutil.Uint64(api.RPCGasCap())
+		args.Gas = &tmp
+	}
+
+	// Ensure any missing fields are filled, extract the recipient and input data
+	if err := args.setDefaults(ctx, api); err != nil {
+		return nil, 0, nil, err
+	}
+	var to types.Address
+	if args.To != nil {
+		to = *mvm_types.ToAmcAddress(args.To)
+	} else {
+		to = crypto.CreateAddress(args.from(), uint64(*args.Nonce))
+	}
+	// Retrieve the precompiles since they don't need to be added to the access list
+	precompiles := vm2.ActivePrecompiles(api.GetChainConfig().Rules(header.Number64().Uint64()))
+
+	// Create an initial tracer
+	prevTracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
+	if args.AccessList != nil {
+		prevTracer = logger.NewAccessListTracer(mvm_types.ToAmcAccessList(*args.AccessList), args.from(), to, precompiles)
+	}
+	for {
+		// Retrieve the current access list to expand
+		accessList := prevTracer.AccessList()
+		log.Trace("Creating access list", "input", accessList)
+
+		// Set the accesslist to the last al
+		rpcAccessList := fromAccessList(accessList)
+		args.AccessList = &rpcAccessList
+
+		// Apply the transaction with the access list tracer
+		tracer := logger.NewAccessListTracer(accessList, args.from(), to, precompiles)
+		config := &vm2.Config{Tracer: tracer, Debug: true, NoBaseFee: true}
+		res, err := applyCall(ctx, api, args, blockNrOrHash, overrides, config, rpcEVMTimeout, api.RPCGasCap())
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.toTransaction().Hash(), err)
+		}
+		if tracer.Equal(prevTracer) {
+			return rpcAccessList, res.UsedGas, res.Err, nil
+		}
+		prevTracer = tracer
+	}
+}
+
+// fromAccessList converts the access list to its RPC form, which lists the
+// addresses without storage keys with an empty list of keys.
+func fromAccessList(accessList transaction.AccessList) mvm_types.AccessList {
+	acl := make(mvm_types.AccessList, 0, len(accessList))
+	for _, tuple := range mvm_types.FromAmcAccessList(accessList) {
+		if tuple.StorageKeys == nil {
+			tuple.StorageKeys = []mvm_common.Hash{}
+		}
+		acl = append(acl, tuple)
+	}
+	return acl
+}
+
 // GetBlockByNumber returns the requested canonical block.
 //   - When blockNr is -1 the chain head is returned.
 //   - When blockNr is -2 the pending chain head is returned.
 //   - When fullTx is true all transactions in the block are returned, otherwise
 //     only the transaction hash is returned.
 func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number jsonrpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
-
-	var (
-		block block.IBlock
-		err   error
-	)
-	// header
-	if number == jsonrpc.LatestBlockNumber {
-		block = s.api.BlockChain().CurrentBlock()
-		err = nil
-	} else {
-		block, err = s.api.BlockChain().GetBlockByNumber(uint256.NewInt(uint64(number.Int64())))
-	}
-
+	block, err := blockByNumber(s.api, number)
 	if block != nil && err == nil {
 		response, err := RPCMarshalBlock(block, s.api.BlockChain(), true, fullTx)
 		if err == nil && number == jsonrpc.PendingBlockNumber {
@@ -954,6 +1175,16 @@ func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number jsonrpc.Blo
 	return nil, err
 }
 
+// blockByNumber returns the block with the given number. The latest, pending,
+// safe and finalized tags all resolve to the current block, as blocks are
+// final once sealed and the pending block is only known by the miner.
+func blockByNumber(api *API, number jsonrpc.BlockNumber) (block.IBlock, error) {
+	if number < jsonrpc.EarliestBlockNumber {
+		return api.BlockChain().CurrentBlock(), nil
+	}
+	return api.BlockChain().GetBlockByNumber(uint256.NewInt(uint64(number.Int64())))
+}
+
 // GetBlockByHash get block by hash
 func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash mvm_common.Hash, fullTx bool) (map[string]interface{}, error) {
 	block, err := s.api.BlockChain().GetBlockByHash(mvm_types.ToAmcHash(hash))
@@ -1005,11 +1236,15 @@ func (s *BlockChainAPI) MinedBlock(ctx context.Context, address types.Address) (
 }
 
 func (s *BlockChainAPI) SubmitSign(sign AggSign) error {
-	info := DepositInfo(s.api.db, sign.Address)
-	if nil == info {
+	var pub types.PublicKey
+	if err := s.api.db.View(context.Background(), func(tx kv.Tx) error {
+		var err error
+		pub, _, err = rawdb.GetDeposit(tx, sign.Address)
+		return err
+	}); nil != err {
 		return fmt.Errorf("unauthed address: %s", sign.Address)
 	}
-	sign.PublicKey.SetBytes(info.PublicKey.Bytes())
+	sign.PublicKey.SetBytes(pub.Bytes())
 	go func() {
 		sigChannel <- sign
 	}()
@@ -1043,9 +1278,9 @@ func (s *TransactionAPI) GetTransactionCount(ctx context.Context, address mvm_co
 	}
 	defer tx.Rollback()
 
-	state := s.api.State(tx, blockNrOrHash)
-	if state == nil {
-		return nil, nil
+	state, err := s.api.State(tx, blockNrOrHash)
+	if nil != err || state == nil {
+		return nil, err
 	}
 	nonce := state.GetNonce(*mvm_types.ToAmcAddress(&address))
 	return (*hexutil.Uint64)(&nonce), nil
@@ -1074,10 +1309,9 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash mvm_com
 	var tx *transaction.Transaction
 	var blockHash types.Hash
 	var index uint64
-	var blockNumber uint64
 	var err error
 	s.api.Database().View(ctx, func(t kv.Tx) error {
-		tx, blockHash, blockNumber, index, err = rawdb.ReadTransactionByHash(t, mvm_types.ToAmcHash(hash))
+		tx, blockHash, _, index, err = rawdb.ReadTransactionByHash(t, mvm_types.ToAmcHash(hash))
 		if err != nil || tx == nil {
 			log.Tracef("rawdb.ReadTransactionByHash, err = %v, txhash = %v \n", err, hash)
 			// When the transaction doesn't exist, the RPC method should return JSON null
@@ -1088,50 +1322,61 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash mvm_com
 	if tx == nil {
 		return nil, nil
 	}
-	//tx, blockHash, blockNumber, index, err := rawdb.ReadTransactionByHash(s.api.Database().(kv.Tx), mvm_types.ToAmcHash(hash))
-	//if err != nil || tx == nil {
-	//	// When the transaction doesn't exist, the RPC method should return JSON null
-	//	// as per specification.
-	//	return nil, nil
-	//}
-	//log.Infof("GetTransactionReceipt, hash %+v , %+v, %+v, %+v", tx, blockHash, blockNumber, index)
 	receipts, err := s.api.BlockChain().GetReceipts(blockHash)
-
-	//log.Infof("GetTransactionReceipt, receipts %+v", receipts)
 	if err != nil {
 		return nil, err
 	}
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	header, err := s.api.BlockChain().GetHeaderByHash(blockHash)
+	if err != nil || header == nil {
+		return nil, err
+	}
+	return marshalReceipt(receipts[index], tx, header), nil
+}
 
-	from := tx.From()
+// GetBlockReceipts returns the receipts of all the transactions in a block.
+func (s *TransactionAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash jsonrpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	iblock, err := BlockByNumberOrHash(ctx, blockNrOrHash, s.api)
+	if err != nil || iblock == nil {
+		return nil, err
+	}
+	receipts, err := s.api.BlockChain().GetReceipts(iblock.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := iblock.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(receipts), len(txs))
+	}
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, txs[i], iblock.Header())
+	}
+	return result, nil
+}
+
+// marshalReceipt returns the RPC form of a receipt with its derived fields
+// filled in.
+func marshalReceipt(receipt *block.Receipt, tx *transaction.Transaction, header block.IHeader) map[string]interface{} {
 	fields := map[string]interface{}{
-		"blockHash":         mvm_types.FromAmcHash(blockHash),
-		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
-		"from":              mvm_types.FromAmcAddress(from),
+		"blockHash":         mvm_types.FromAmcHash(header.Hash()),
+		"blockNumber":       hexutil.Uint64(header.Number64().Uint64()),
+		"transactionHash":   mvm_types.FromAmcHash(tx.Hash()),
+		"transactionIndex":  hexutil.Uint64(receipt.TransactionIndex),
+		"from":              mvm_types.FromAmcAddress(tx.From()),
 		"to":                mvm_types.FromAmcAddress(tx.To()),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
 		"contractAddress":   nil,
-		"logsBloom":         receipt.Bloom, //receipt.Bloom
+		"logsBloom":         receipt.Bloom,
 		"type":              hexutil.Uint(tx.Type()),
 	}
 	// Assign the effective gas price paid
-	//todo !IsLondon
-	if false {
-		fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
-	} else {
-		header, err := s.api.BlockChain().GetHeaderByHash(blockHash)
-		if err != nil {
-			return nil, err
-		}
-		gasPrice := new(big.Int).Add(header.BaseFee64().ToBig(), tx.EffectiveGasTipValue(header.BaseFee64()).ToBig())
-		fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
-	}
+	gasPrice := new(big.Int).Add(header.BaseFee64().ToBig(), tx.EffectiveGasTipValue(header.BaseFee64()).ToBig())
+	fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
 	// Assign receipt status or post state.
 	if len(receipt.PostState) > 0 {
 		fields["root"] = hexutil.Bytes(receipt.PostState)
@@ -1147,10 +1392,16 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash mvm_com
 	if !receipt.ContractAddress.IsNull() {
 		fields["contractAddress"] = mvm_types.FromAmcAddress(&receipt.ContractAddress)
 	}
+	return fields
+}
 
-	json, _ := json.Marshal(fields)
-	log.Infof("GetTransactionReceipt, result %s", string(json))
-	return fields, nil
+// GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
+func (s *TransactionAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr jsonrpc.BlockNumber) *hexutil.Uint {
+	if block, _ := blockByNumber(s.api, blockNr); block != nil {
+		n := hexutil.Uint(len(block.Transactions()))
+		return &n
+	}
+	return nil
 }
 
 // GetBlockTransactionCountByHash returns the number of transactions in the block with the given hash.
@@ -1207,15 +1458,72 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash mvm_comm
 // GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
 func (s *TransactionAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash mvm_common.Hash, index hexutil.Uint) *RPCTransaction {
 	if block, _ := s.api.BlockChain().GetBlockByHash(mvm_types.ToAmcHash(blockHash)); block != nil {
-		for i, tx := range block.Transactions() {
-			if i == int(index) {
-				return newRPCTransaction(tx, mvm_types.ToAmcHash(blockHash), block.Number64().Uint64(), uint64(index), block.Header().BaseFee64().ToBig())
-			}
-		}
+		return newRPCTransactionFromBlockIndex(block, uint64(index))
+	}
+	return nil
+}
+
+// GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
+func (s *TransactionAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr jsonrpc.BlockNumber, index hexutil.Uint) *RPCTransaction {
+	if block, _ := blockByNumber(s.api, blockNr); block != nil {
+		return newRPCTransactionFromBlockIndex(block, uint64(index))
 	}
 	return nil
 }
 
+// GetRawTransactionByBlockNumberAndIndex returns the bytes of the transaction for the given block number and index.
+func (s *TransactionAPI) GetRawTransactionByBlockNumberAndIndex(ctx context.Context, blockNr jsonrpc.BlockNumber, index hexutil.Uint) (hexutil.Bytes, error) {
+	if block, _ := blockByNumber(s.api, blockNr); block != nil {
+		return newRPCRawTransactionFromBlockIndex(block, uint64(index))
+	}
+	return nil, nil
+}
+
+// GetRawTransactionByBlockHashAndIndex returns the bytes of the transaction for the given block hash and index.
+func (s *TransactionAPI) GetRawTransactionByBlockHashAndIndex(ctx context.Context, blockHash mvm_common.Hash, index hexutil.Uint) (hexutil.Bytes, error) {
+	if block, _ := s.api.BlockChain().GetBlockByHash(mvm_types.ToAmcHash(blockHash)); block != nil {
+		return newRPCRawTransactionFromBlockIndex(block, uint64(index))
+	}
+	return nil, nil
+}
+
+// GetRawTransactionByHash returns the bytes of the transaction for the given hash.
+func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash mvm_common.Hash) (hexutil.Bytes, error) {
+	var tx *transaction.Transaction
+	if err := s.api.Database().View(ctx, func(t kv.Tx) error {
+		var err error
+		tx, _, _, _, err = rawdb.ReadTransactionByHash(t, mvm_types.ToAmcHash(hash))
+		return err
+	}); nil != err {
+		return nil, err
+	}
+	if tx == nil {
+		if tx = s.api.TxsPool().GetTx(mvm_types.ToAmcHash(hash)); tx == nil {
+			// Transaction not found anywhere, abort
+			return nil, nil
+		}
+	}
+	return marshalTransaction(tx)
+}
+
+// newRPCRawTransactionFromBlockIndex returns the bytes of the transaction at
+// the given index of the block.
+func newRPCRawTransactionFromBlockIndex(b block.IBlock, index uint64) (hexutil.Bytes, error) {
+	txs := b.Transactions()
+	if index >= uint64(len(txs)) {
+		return nil, nil
+	}
+	return marshalTransaction(txs[index])
+}
+
+// marshalTransaction returns the canonical binary encoding of the transaction,
+// as accepted by eth_sendRawTransaction.
+func marshalTransaction(tx *transaction.Transaction) (hexutil.Bytes, error) {
+	mvmTx := new(mvm_types.Transaction)
+	mvmTx.FromAmcTransaction(tx)
+	return mvmTx.MarshalBinary()
+}
+
 // SubmitTransaction ?
 func SubmitTransaction(ctx context.Context, api *API, tx *transaction.Transaction) (mvm_common.Hash, error) {
 
@@ -1267,11 +1575,118 @@ func (s *TransactionAPI) SendTransaction(ctx context.Context, args TransactionAr
 	return SubmitTransaction(ctx, s.api, signed)
 }
 
+// Sign calculates an ECDSA signature for:
+// keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
+//
+// The account associated with addr must be unlocked.
+func (s *TransactionAPI) Sign(addr types.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.api.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the requested hash with the wallet
+	signature, err := wallet.SignText(account, data)
+	if err == nil {
+		signature[crypto.RecoveryIDOffset] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
+// SignTransaction will sign the given transaction with the from account.
+// The node needs to have the private key of the account corresponding with
+// the given from address and it needs to be unlocked.
+func (s *TransactionAPI) SignTransaction(ctx context.Context, args TransactionArgs) (*SignTransactionResult, error) {
+	if args.Gas == nil {
+		return nil, errors.New("gas not specified")
+	}
+	if args.GasPrice == nil && (args.MaxPriorityFeePerGas == nil || args.MaxFeePerGas == nil) {
+		return nil, errors.New("missing gasPrice or maxFeePerGas/maxPriorityFeePerGas")
+	}
+	if args.Nonce == nil {
+		return nil, errors.New("nonce not specified")
+	}
+	if err := args.setDefaults(ctx, s.api); err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: args.from()}
+
+	wallet, err := s.api.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := wallet.SignTx(account, args.toTransaction(), s.api.GetChainConfig().ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return newSignTransactionResult(s.api, signed)
+}
+
+// TypedDataArgs is the EIP-712 typed data to sign, which clients send either
+// as a JSON object or as a string holding the JSON encoded object.
+type TypedDataArgs accounts.TypedData
+
+// UnmarshalJSON decodes the typed data from either encoding.
+func (args *TypedDataArgs) UnmarshalJSON(input []byte) error {
+	var encoded string
+	if err := json.Unmarshal(input, &encoded); err == nil {
+		input = []byte(encoded)
+	}
+	return json.Unmarshal(input, (*accounts.TypedData)(args))
+}
+
+// SignTypedData_v4 calculates an ECDSA signature of the EIP-712 typed data:
+// keccak256("\x19\x01" + domainSeparator + hashStruct(message)).
+//
+// The account associated with addr must be unlocked.
+func (s *TransactionAPI) SignTypedData_v4(addr types.Address, typedData TypedDataArgs) (hexutil.Bytes, error) {
+	_, rawData, err := accounts.TypedDataAndHash(accounts.TypedData(typedData))
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.api.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// The wallet signs the keccak256 hash of the data, which is the typed data hash
+	signature, err := wallet.SignData(account, accounts.MimetypeTypedData, []byte(rawData))
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // checkTxFee  todo
 func checkTxFee(gasPrice uint256.Int, gas uint64, cap float64) error {
 	return nil
 }
 
+// decodeHash parses a hex-encoded 32 byte hash. The 0x prefix and leading
+// zeros are optional.
+func decodeHash(s string) (types.Hash, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if (len(s) & 1) > 0 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("invalid hex string %q", s)
+	}
+	if len(b) > types.HashLength {
+		return types.Hash{}, fmt.Errorf("hex string too long, want at most %d bytes", types.HashLength)
+	}
+	return types.BytesToHash(b), nil
+}
+
 // toHexSlice creates a slice of hex-strings based on []byte.
 func toHexSlice(b [][]byte) []string {
 	r := make([]string, len(b))
@@ -1302,10 +1717,10 @@ func NewDebugAPI(api *API) *DebugAPI {
 	return &DebugAPI{api: api}
 }
 
-// SetHead rewinds the head of the blockchain to a previous block.
-func (api *DebugAPI) SetHead(number hexutil.Uint64) {
-	api.api.Downloader().Close()
-	api.api.BlockChain().SetHead(uint64(number))
+// SetHead rewinds the head of the blockchain and its state to a previous
+// block. The downloader keeps running and syncs the chain again from there.
+func (api *DebugAPI) SetHead(number hexutil.Uint64) error {
+	return api.api.BlockChain().SetHead(uint64(number))
 }
 
 func (debug *DebugAPI) GetAccount(ctx context.Context, address types.Address) {
@@ -1325,7 +1740,7 @@ func NewNetAPI(api *API, networkVersion uint64) *NetAPI {
 
 // Listening returns an indication if the node is listening for network connections.
 func (s *NetAPI) Listening() bool {
-	return true // always listening
+	return len(s.api.P2pServer().Host().Addrs()) > 0
 }
 
 // PeerCount returns the number of connected peers
@@ -65,7 +65,7 @@ func (b *API) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*type
 	//	return block.Header(), nil
 	//}
 	// Otherwise resolve and return the block
-	if number == rpc.LatestBlockNumber {
+	if number < rpc.EarliestBlockNumber {
 		return b.bc.CurrentBlock().Header().(*types.Header), nil
 	}
 	//if number == rpc.FinalizedBlockNumber {
@@ -88,7 +88,11 @@ func (b *API) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*type
 	//	}
 	//	return nil, errors.New("safe block not found")
 	//}
-	return b.bc.GetHeaderByNumber(uint256.NewInt(uint64(number.Int64()))).(*types.Header), nil
+	header, ok := b.bc.GetHeaderByNumber(uint256.NewInt(uint64(number.Int64()))).(*types.Header)
+	if !ok || header == nil {
+		return nil, errors.New("header not found")
+	}
+	return header, nil
 }
 
 func (b *API) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
@@ -110,7 +114,8 @@ func (b *API) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockN
 
 func (b *API) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	iHeader, _ := b.bc.GetHeaderByHash(hash)
-	return iHeader.(*types.Header), nil
+	header, _ := iHeader.(*types.Header)
+	return header, nil
 }
 
 func (b *API) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
@@ -357,9 +362,11 @@ func (b *API) AccountManager() *accounts.Manager {
 	return b.accountManager
 }
 
-//func (b *API) ExtRPCEnabled() bool {
-//	return b.extRPCEnabled
-//}
+// ExtRPCEnabled returns whether the RPC endpoints are exposed to external
+// hosts.
+func (b *API) ExtRPCEnabled() bool {
+	return b.extRPCEnabled
+}
 
 //func (b *API) UnprotectedAllowed() bool {
 //	return b.allowUnprotectedTxs
@@ -0,0 +1,119 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/common/hexutil"
+	event "github.com/amazechain/amc/modules/event/v2"
+	"github.com/amazechain/amc/modules/rpc/jsonrpc"
+)
+
+// syncStatusInterval is the interval at which the progress of a running sync
+// is sent to the syncing subscriptions.
+const syncStatusInterval = 5 * time.Second
+
+// SyncStatus is the progress of the chain synchronisation.
+type SyncStatus struct {
+	StartingBlock hexutil.Uint64 `json:"startingBlock"`
+	CurrentBlock  hexutil.Uint64 `json:"currentBlock"`
+	HighestBlock  hexutil.Uint64 `json:"highestBlock"`
+	PulledStates  hexutil.Uint64 `json:"pulledStates"`
+	SyncedTables  hexutil.Uint64 `json:"syncedTables"`
+	KnownTables   hexutil.Uint64 `json:"knownTables"`
+}
+
+func newSyncStatus(progress common.SyncProgress) *SyncStatus {
+	return &SyncStatus{
+		StartingBlock: hexutil.Uint64(progress.StartingBlock),
+		CurrentBlock:  hexutil.Uint64(progress.CurrentBlock),
+		HighestBlock:  hexutil.Uint64(progress.HighestBlock),
+		PulledStates:  hexutil.Uint64(progress.PulledStates),
+		SyncedTables:  hexutil.Uint64(progress.SyncedTables),
+		KnownTables:   hexutil.Uint64(progress.KnownTables),
+	}
+}
+
+// SyncingResult is sent to the syncing subscriptions when a sync starts or
+// finishes, and periodically while it runs.
+type SyncingResult struct {
+	Syncing bool        `json:"syncing"`
+	Status  *SyncStatus `json:"status,omitempty"`
+}
+
+// DownloaderAPI provides an API to follow the chain synchronisation.
+type DownloaderAPI struct {
+	api *API
+}
+
+// NewDownloaderAPI creates a new instance of DownloaderAPI.
+func NewDownloaderAPI(api *API) *DownloaderAPI {
+	return &DownloaderAPI{api: api}
+}
+
+// Syncing sends a notification when the node starts or stops syncing, and the
+// sync progress while it is syncing.
+func (api *DownloaderAPI) Syncing(ctx context.Context) (*jsonrpc.Subscription, error) {
+	notifier, supported := jsonrpc.NotifierFromContext(ctx)
+	if !supported {
+		return &jsonrpc.Subscription{}, jsonrpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		startCh := make(chan common.DownloaderStartEvent, 1)
+		finishCh := make(chan common.DownloaderFinishEvent, 1)
+		startSub := event.GlobalEvent.Subscribe(startCh)
+		finishSub := event.GlobalEvent.Subscribe(finishCh)
+		defer func() {
+			startSub.Unsubscribe()
+			finishSub.Unsubscribe()
+		}()
+
+		ticker := time.NewTicker(syncStatusInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-startCh:
+				notifier.Notify(rpcSub.ID, &SyncingResult{
+					Syncing: true,
+					Status:  newSyncStatus(api.api.Downloader().Progress()),
+				})
+			case <-finishCh:
+				notifier.Notify(rpcSub.ID, &SyncingResult{Syncing: false})
+			case <-ticker.C:
+				if api.api.Downloader().IsDownloading() {
+					notifier.Notify(rpcSub.ID, &SyncingResult{
+						Syncing: true,
+						Status:  newSyncStatus(api.api.Downloader().Progress()),
+					})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -2,7 +2,9 @@ package filters
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/block"
 	"github.com/amazechain/amc/common/txs_pool"
@@ -11,11 +13,19 @@ import (
 	"github.com/amazechain/amc/internal/consensus"
 	vm2 "github.com/amazechain/amc/internal/vm"
 	"github.com/amazechain/amc/internal/vm/evmtypes"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
 	"github.com/amazechain/amc/modules/rpc/jsonrpc"
-	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"math/big"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+const (
+	maxFilterBlockRange = 1000000 // Maximum number of blocks a range query may span
+	maxFilterLogs       = 10000   // Maximum number of logs a query may return
 )
 
 type Api interface {
@@ -133,25 +143,24 @@ func (f *Filter) Logs(ctx context.Context) ([]*block.Log, error) {
 	if f.end == jsonrpc.LatestBlockNumber.Int64() || f.end == jsonrpc.PendingBlockNumber.Int64() {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	if f.begin < 0 {
+		return nil, errors.New("invalid block range")
+	}
+	if end > head {
+		end = head
+	}
 	var (
-		logs           []*block.Log
-		err            error
-		size, sections = uint64(4096), uint64(10)
-		//todo
+		logs []*block.Log
+		err  error
 	)
-	if indexed := sections * size; indexed > uint64(f.begin) {
-		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
-		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+	if uint64(f.begin) <= end {
+		if end-uint64(f.begin) >= maxFilterBlockRange {
+			return nil, fmt.Errorf("block range too large, at most %d blocks can be queried", maxFilterBlockRange)
 		}
-		if err != nil {
-			return logs, err
+		if logs, err = f.indexedLogs(ctx, end); err != nil {
+			return nil, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
 	if pending {
 		pendingLogs, err := f.pendingLogs()
 		if err != nil {
@@ -159,73 +168,138 @@ func (f *Filter) Logs(ctx context.Context) ([]*block.Log, error) {
 		}
 		logs = append(logs, pendingLogs...)
 	}
-	return logs, err
+	return logs, nil
 }
 
-// indexedLogs returns the logs matching the filter criteria based on the bloom
-// bits indexed available locally or via the network.
+// indexedLogs returns the logs matching the filter criteria in the blocks up to
+// end that the log indexes report as candidates.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*block.Log, error) {
-	// Create a matcher session and request servicing from the backend
-	matches := make(chan uint64, 64)
-
-	//session, err := f.matcher.Start(ctx, uint64(f.begin), end, matches)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer session.Close()
+	tx, err := f.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	//f.api.ServiceFilter(ctx, session)
+	if err := rawdb.CheckPruned(tx, rawdb.PruneKindReceipts, uint64(f.begin)); err != nil {
+		return nil, err
+	}
+	blocks, err := f.candidateBlocks(tx, uint64(f.begin), end)
+	if err != nil {
+		return nil, err
+	}
 
-	// Iterate over the matches until exhausted or context closed
 	var logs []*block.Log
+	for it := blocks.Iterator(); it.HasNext(); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		number := uint64(it.Next())
+		header := rawdb.ReadHeaderByNumber(tx, number)
+		if header == nil {
+			break
+		}
+		found, err := f.checkMatches(ctx, header)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+		if len(logs) > maxFilterLogs {
+			return nil, fmt.Errorf("query returned more than %d results", maxFilterLogs)
+		}
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
+}
 
-	for {
-		select {
-		case number, ok := <-matches:
-			// Abort if all matches have been fulfilled
-			if !ok {
-				//err := session.Error()
-				//if err == nil {
-				//	f.begin = int64(end) + 1
-				//}
-				return logs, nil
-			}
-			f.begin = int64(number) + 1
+// candidateBlocks returns the blocks in [begin, end] that may contain matching
+// logs. Address and topic criteria are resolved by intersecting the bitmaps of
+// the LogAddressIndex and LogTopicIndex tables; without criteria, and for the
+// blocks not yet added to the indexes while they are being built, every block
+// with logs is a candidate.
+func (f *Filter) candidateBlocks(tx kv.Tx, begin, end uint64) (*roaring.Bitmap, error) {
+	if !f.hasCriteria() {
+		return logBlocks(tx, begin, end)
+	}
+	indexed, ok, err := rawdb.ReadLogIndexProgress(tx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || indexed < begin {
+		return logBlocks(tx, begin, end)
+	}
+	unindexed := roaring.New()
+	if indexed < end {
+		if unindexed, err = logBlocks(tx, indexed+1, end); err != nil {
+			return nil, err
+		}
+		end = indexed
+	}
 
-			// Retrieve the suggested block and pull any truly matching logs
-			header := f.api.BlockChain().GetHeaderByNumber(uint256.NewInt(number))
-			if header == nil {
-				return logs, nil
+	blocks := roaring.New()
+	blocks.AddRange(begin, end+1)
+	if len(f.addresses) > 0 {
+		matches := roaring.New()
+		for _, addr := range f.addresses {
+			m, err := rawdb.ReadLogIndex(tx, modules.LogAddressIndex, addr.Bytes(), begin, end)
+			if err != nil {
+				return nil, err
 			}
-			found, err := f.checkMatches(ctx, header)
+			matches.Or(m)
+		}
+		blocks.And(matches)
+	}
+	// The indexes are not positional, a topic matches at any position.
+	for _, sub := range f.topics {
+		if len(sub) == 0 {
+			continue
+		}
+		matches := roaring.New()
+		for _, topic := range sub {
+			m, err := rawdb.ReadLogIndex(tx, modules.LogTopicIndex, topic.Bytes(), begin, end)
 			if err != nil {
-				return logs, err
+				return nil, err
 			}
-			logs = append(logs, found...)
-
-		case <-ctx.Done():
-			return logs, ctx.Err()
+			matches.Or(m)
 		}
+		blocks.And(matches)
 	}
+	blocks.Or(unindexed)
+	return blocks, nil
 }
 
-// unindexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*block.Log, error) {
-	var logs []*block.Log
+// logBlocks returns the blocks in [begin, end] with logs stored.
+func logBlocks(tx kv.Tx, begin, end uint64) (*roaring.Bitmap, error) {
+	c, err := tx.Cursor(modules.Log)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
 
-	for ; f.begin <= int64(end); f.begin++ {
-		header := f.api.BlockChain().GetHeaderByNumber(uint256.NewInt(uint64(f.begin)))
-		if header == nil {
-			return logs, nil
-		}
-		found, err := f.blockLogs(ctx, header)
+	blocks := roaring.New()
+	for k, _, err := c.Seek(modules.EncodeBlockNumber(begin)); k != nil; k, _, err = c.Next() {
 		if err != nil {
-			return logs, err
+			return nil, err
 		}
-		logs = append(logs, found...)
+		number := binary.BigEndian.Uint64(k[:modules.NumberLength])
+		if number > end {
+			break
+		}
+		blocks.Add(uint32(number))
 	}
-	return logs, nil
+	return blocks, nil
+}
+
+// hasCriteria reports whether the filter restricts the log addresses or topics.
+func (f *Filter) hasCriteria() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // blockLogs returns the logs matching the filter criteria within a single block.
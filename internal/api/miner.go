@@ -0,0 +1,88 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/types"
+)
+
+// MinerAPI provides an API to control the miner.
+type MinerAPI struct {
+	api *API
+}
+
+// NewMinerAPI creates a new instance of MinerAPI.
+func NewMinerAPI(api *API) *MinerAPI {
+	return &MinerAPI{api: api}
+}
+
+// Start starts sealing blocks with the current etherbase. Sealing begins once
+// the node is synced.
+func (api *MinerAPI) Start() error {
+	if api.api.Miner().Coinbase() == (types.Address{}) {
+		return errors.New("etherbase must be explicitly specified")
+	}
+	api.api.Miner().Start()
+	return nil
+}
+
+// Stop stops sealing blocks.
+func (api *MinerAPI) Stop() {
+	api.api.Miner().Stop()
+}
+
+// Mining returns whether blocks are being sealed.
+func (api *MinerAPI) Mining() bool {
+	return api.api.Miner().Mining()
+}
+
+// SetEtherbase sets the etherbase of the miner, which seals the blocks with
+// its key. The account must be unlocked in a local wallet and be an
+// authorized signer.
+func (api *MinerAPI) SetEtherbase(etherbase types.Address) (bool, error) {
+	if err := api.api.Miner().SetEtherbase(etherbase); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetGasLimit sets the gas ceiling the gas limit of mined blocks moves
+// towards.
+func (api *MinerAPI) SetGasLimit(gasLimit hexutil.Uint64) bool {
+	api.api.Miner().SetGasCeil(uint64(gasLimit))
+	return true
+}
+
+// SetGasPrice sets the minimum gas price of the transactions accepted into
+// the transaction pool and thereby mined.
+func (api *MinerAPI) SetGasPrice(gasPrice hexutil.Big) (bool, error) {
+	if err := api.api.setGasPrice(gasPrice); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetExtra sets the extra data of mined blocks.
+func (api *MinerAPI) SetExtra(extra string) (bool, error) {
+	if err := api.api.Miner().SetExtra([]byte(extra)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/amazechain/amc/accounts"
+	"github.com/amazechain/amc/accounts/keystore"
+	"github.com/amazechain/amc/common/crypto"
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	mvm_common "github.com/amazechain/amc/internal/avm/common"
+	"github.com/amazechain/amc/log"
+)
+
+// defaultUnlockDuration is how long an account stays unlocked when no
+// duration is given.
+const defaultUnlockDuration = 300 * time.Second
+
+// errUnlockForbidden is returned when an account would be unlocked through
+// RPC endpoints exposed to external hosts without insecure unlocking allowed.
+var errUnlockForbidden = errors.New("account unlock with HTTP access is forbidden")
+
+// SignTransactionResult represents a RLP encoded signed transaction.
+type SignTransactionResult struct {
+	Raw hexutil.Bytes   `json:"raw"`
+	Tx  *RPCTransaction `json:"tx"`
+}
+
+func newSignTransactionResult(api *API, tx *transaction.Transaction) (*SignTransactionResult, error) {
+	data, err := marshalTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, newRPCPendingTransaction(tx, api.BlockChain().CurrentBlock().Header())}, nil
+}
+
+// PersonalAccountAPI provides an API to access accounts managed by this node.
+// It offers methods to create, (un)lock and list accounts. Some methods accept
+// passwords and are therefore considered private by default.
+type PersonalAccountAPI struct {
+	api       *API
+	nonceLock *AddrLocker
+}
+
+// NewPersonalAccountAPI creates a new PersonalAccountAPI.
+func NewPersonalAccountAPI(api *API, nonceLock *AddrLocker) *PersonalAccountAPI {
+	return &PersonalAccountAPI{api: api, nonceLock: nonceLock}
+}
+
+// unlockAllowed returns an error if passwords may not be used to decrypt keys,
+// which is the case when the RPC endpoints are exposed to external hosts.
+func (s *PersonalAccountAPI) unlockAllowed() error {
+	if s.api.ExtRPCEnabled() && !s.api.AccountManager().Config().InsecureUnlockAllowed {
+		return errUnlockForbidden
+	}
+	return nil
+}
+
+// ListAccounts will return a list of addresses for accounts this node manages.
+func (s *PersonalAccountAPI) ListAccounts() []types.Address {
+	return s.api.AccountManager().Accounts()
+}
+
+// rawWallet is a JSON representation of an accounts.Wallet interface, with its
+// data contents extracted into plain fields.
+type rawWallet struct {
+	URL      string             `json:"url"`
+	Status   string             `json:"status"`
+	Failure  string             `json:"failure,omitempty"`
+	Accounts []accounts.Account `json:"accounts,omitempty"`
+}
+
+// ListWallets will return a list of wallets this node manages.
+func (s *PersonalAccountAPI) ListWallets() []rawWallet {
+	wallets := make([]rawWallet, 0)
+	for _, wallet := range s.api.AccountManager().Wallets() {
+		status, failure := wallet.Status()
+
+		raw := rawWallet{
+			URL:      wallet.URL().String(),
+			Status:   status,
+			Accounts: wallet.Accounts(),
+		}
+		if failure != nil {
+			raw.Failure = failure.Error()
+		}
+		wallets = append(wallets, raw)
+	}
+	return wallets
+}
+
+// OpenWallet initiates a hardware wallet opening procedure, establishing a USB
+// connection and attempting to authenticate via the provided passphrase.
+func (s *PersonalAccountAPI) OpenWallet(url string, passphrase *string) error {
+	wallet, err := s.api.AccountManager().Wallet(url)
+	if err != nil {
+		return err
+	}
+	pass := ""
+	if passphrase != nil {
+		pass = *passphrase
+	}
+	return wallet.Open(pass)
+}
+
+// fetchKeystore retrieves the encrypted keystore from the account manager.
+func fetchKeystore(am *accounts.Manager) (*keystore.KeyStore, error) {
+	if ks := am.Backends(keystore.KeyStoreType); len(ks) > 0 {
+		return ks[0].(*keystore.KeyStore), nil
+	}
+	return nil, errors.New("local keystore not used")
+}
+
+// NewAccount will create a new account and returns the address for the new account.
+func (s *PersonalAccountAPI) NewAccount(password string) (types.Address, error) {
+	ks, err := fetchKeystore(s.api.AccountManager())
+	if err != nil {
+		return types.Address{}, err
+	}
+	acc, err := ks.NewAccount(password)
+	if err != nil {
+		return types.Address{}, err
+	}
+	log.Info("Your new key was generated", "address", acc.Address)
+	return acc.Address, nil
+}
+
+// ImportRawKey stores the given hex encoded ECDSA key into the key directory,
+// encrypting it with the passphrase.
+func (s *PersonalAccountAPI) ImportRawKey(privkey string, password string) (types.Address, error) {
+	key, err := crypto.HexToECDSA(privkey)
+	if err != nil {
+		return types.Address{}, err
+	}
+	ks, err := fetchKeystore(s.api.AccountManager())
+	if err != nil {
+		return types.Address{}, err
+	}
+	acc, err := ks.ImportECDSA(key, password)
+	return acc.Address, err
+}
+
+// UnlockAccount will unlock the account associated with the given address with
+// the given password for duration seconds. If duration is nil it will use a
+// default of 300 seconds. It returns an indication if the account was unlocked.
+func (s *PersonalAccountAPI) UnlockAccount(ctx context.Context, addr types.Address, password string, duration *uint64) (bool, error) {
+	if err := s.unlockAllowed(); err != nil {
+		return false, err
+	}
+
+	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
+	d := defaultUnlockDuration
+	if duration != nil {
+		if *duration > max {
+			return false, errors.New("unlock duration too large")
+		}
+		d = time.Duration(*duration) * time.Second
+	}
+	ks, err := fetchKeystore(s.api.AccountManager())
+	if err != nil {
+		return false, err
+	}
+	err = ks.TimedUnlock(accounts.Account{Address: addr}, password, d)
+	if err != nil {
+		log.Warn("Failed account unlock attempt", "address", addr, "err", err)
+	}
+	return err == nil, err
+}
+
+// LockAccount will lock the account associated with the given address when it's unlocked.
+func (s *PersonalAccountAPI) LockAccount(addr types.Address) bool {
+	if ks, err := fetchKeystore(s.api.AccountManager()); err == nil {
+		return ks.Lock(addr) == nil
+	}
+	return false
+}
+
+// signTransaction sets defaults and signs the given transaction with the
+// password of the from account.
+// NOTE: the caller needs to ensure that the nonceLock is held, if applicable,
+// and release it after the transaction has been submitted to the tx pool.
+func (s *PersonalAccountAPI) signTransaction(ctx context.Context, args *TransactionArgs, passwd string) (*transaction.Transaction, error) {
+	if err := s.unlockAllowed(); err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: args.from()}
+	wallet, err := s.api.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	if err := args.setDefaults(ctx, s.api); err != nil {
+		return nil, err
+	}
+	tx := args.toTransaction()
+	return wallet.SignTxWithPassphrase(account, passwd, tx, s.api.GetChainConfig().ChainID)
+}
+
+// SendTransaction will create a transaction from the given arguments and try
+// to sign it with the key associated with args.From. If the given password
+// isn't able to decrypt the key it fails.
+func (s *PersonalAccountAPI) SendTransaction(ctx context.Context, args TransactionArgs, passwd string) (mvm_common.Hash, error) {
+	if args.Nonce == nil {
+		// Hold the mutex around signing to prevent concurrent assignment of
+		// the same nonce to multiple accounts.
+		s.nonceLock.LockAddr(args.from())
+		defer s.nonceLock.UnlockAddr(args.from())
+	}
+	signed, err := s.signTransaction(ctx, &args, passwd)
+	if err != nil {
+		log.Warn("Failed transaction send attempt", "from", args.from(), "to", args.To, "value", args.Value, "err", err)
+		return mvm_common.Hash{}, err
+	}
+	return SubmitTransaction(ctx, s.api, signed)
+}
+
+// SignTransaction will create a transaction from the given arguments and try
+// to sign it with the key associated with args.From. If the given password
+// isn't able to decrypt the key it fails. The transaction is returned in RLP
+// form, not broadcast to other nodes.
+func (s *PersonalAccountAPI) SignTransaction(ctx context.Context, args TransactionArgs, passwd string) (*SignTransactionResult, error) {
+	// No need to obtain the noncelock mutex, since we won't be sending this
+	// tx into the transaction pool, but right back to the user
+	if args.From == nil {
+		return nil, errors.New("sender not specified")
+	}
+	if args.Gas == nil {
+		return nil, errors.New("gas not specified")
+	}
+	if args.GasPrice == nil && (args.MaxFeePerGas == nil || args.MaxPriorityFeePerGas == nil) {
+		return nil, errors.New("missing gasPrice or maxFeePerGas/maxPriorityFeePerGas")
+	}
+	if args.Nonce == nil {
+		return nil, errors.New("nonce not specified")
+	}
+	signed, err := s.signTransaction(ctx, &args, passwd)
+	if err != nil {
+		log.Warn("Failed transaction sign attempt", "from", args.from(), "to", args.To, "value", args.Value, "err", err)
+		return nil, err
+	}
+	return newSignTransactionResult(s.api, signed)
+}
+
+// Sign calculates an Ethereum ECDSA signature for:
+// keccak256("\x19Ethereum Signed Message:\n" + len(message) + message))
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PersonalAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr types.Address, passwd string) (hexutil.Bytes, error) {
+	if err := s.unlockAllowed(); err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+	wallet, err := s.api.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble sign the data with the wallet
+	signature, err := wallet.SignTextWithPassphrase(account, passwd, data)
+	if err != nil {
+		log.Warn("Failed data sign attempt", "address", addr, "err", err)
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
+// EcRecover returns the address for the account that was used to create the
+// signature. Note, this function is compatible with eth_sign and personal_sign.
+func (s *PersonalAccountAPI) EcRecover(ctx context.Context, data, sig hexutil.Bytes) (types.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return types.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	if sig[crypto.RecoveryIDOffset] != 27 && sig[crypto.RecoveryIDOffset] != 28 {
+		return types.Address{}, errors.New("invalid signature (V is not 27 or 28)")
+	}
+	// Transform yellow paper V from 27/28 to 0/1, without touching the caller's signature
+	sig = append(hexutil.Bytes{}, sig...)
+	sig[crypto.RecoveryIDOffset] -= 27
+
+	rpk, err := crypto.SigToPub(accounts.TextHash(data), sig)
+	if err != nil {
+		return types.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*rpk), nil
+}
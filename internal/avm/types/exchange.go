@@ -275,6 +275,21 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return &Transaction{inner: cpy, time: tx.time}, nil
 }
 
+// MarshalBinary returns the canonical encoding of the transaction, the RLP
+// encoding for legacy transactions and the type followed by the RLP encoded
+// payload for EIP-2718 typed transactions.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.Type() == LegacyTxType {
+		return rlp.EncodeToBytes(tx.inner)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(tx.Type())
+	if err := rlp.Encode(&buf, tx.inner); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // UnmarshalBinary
 func (tx *Transaction) UnmarshalBinary(b []byte) error {
 	if len(b) > 0 && b[0] > 0x7f {
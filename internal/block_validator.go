@@ -19,11 +19,11 @@ package internal
 import (
 	"fmt"
 	"github.com/amazechain/amc/common/block"
-	"github.com/amazechain/amc/common/crypto/bls"
 	"github.com/amazechain/amc/common/transaction"
 	"github.com/amazechain/amc/internal/consensus"
 	"github.com/amazechain/amc/modules/state"
 	"github.com/amazechain/amc/params"
+	"github.com/ledgerwatch/erigon-lib/kv"
 )
 
 // BlockValidator is responsible for validating block headers, uncles and
@@ -50,28 +50,6 @@ func NewBlockValidator(config *params.ChainConfig, blockchain *BlockChain, engin
 // header's transaction and uncle roots. The headers are assumed to be already
 // validated at this point.
 func (v *BlockValidator) ValidateBody(b block.IBlock) error {
-	// Check Signature valid
-	vfs := b.Body().Verifier()
-	ss := make([]bls.PublicKey, len(vfs))
-	for i, p := range vfs {
-		blsP, err := bls.PublicKeyFromBytes(p.PublicKey[:])
-		if nil != err {
-			return err
-		}
-		ss[i] = blsP
-	}
-
-	if v.config.IsBeijing(b.Number64().Uint64()) {
-		header := b.Header().(*block.Header)
-		sig, err := bls.SignatureFromBytes(header.Signature[:])
-		if nil != err {
-			return err
-		}
-		if false && !sig.FastAggregateVerify(ss, header.Root) {
-			return fmt.Errorf("AggSignature verify falied")
-		}
-	}
-
 	// Check whether the block's known, and if not, that it's linkable
 	if v.bc.HasBlockAndState(b.Hash(), b.Number64().Uint64()) {
 		return ErrKnownBlock
@@ -90,6 +68,18 @@ func (v *BlockValidator) ValidateBody(b block.IBlock) error {
 	return nil
 }
 
+// ValidateAggSign checks the verifiers' aggregated signature of a block
+// against the deposits at its parent, which must be the head block in tx.
+func (v *BlockValidator) ValidateAggSign(tx kv.Tx, b block.IBlock) error {
+	if !v.config.IsBeijing(b.Number64().Uint64()) {
+		return nil
+	}
+	if verifier, ok := v.engine.(consensus.AggSignVerifier); ok {
+		return verifier.VerifyAggSign(tx, b.Header(), b.Body().Verifier())
+	}
+	return nil
+}
+
 // ValidateState validates the various changes that happen after a state
 // transition, such as amount of used gas, the receipt roots and the state root
 // itself. ValidateState returns a database batch if the validation was a success
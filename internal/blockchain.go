@@ -27,6 +27,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/amazechain/amc/modules/prune"
 	"github.com/amazechain/amc/modules/state"
 	"github.com/amazechain/amc/params"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -71,6 +72,7 @@ const (
 	maxFutureBlocks     = 256
 	tdCacheLimit        = 1024
 	maxTimeFutureBlocks = 5 * 60 // 5 min
+	pruneBatchBlocks    = 1000   // blocks of each kind of data pruned per transaction
 )
 
 type BlockChain struct {
@@ -110,6 +112,9 @@ type BlockChain struct {
 
 	forker    *ForkChoice
 	validator Validator
+
+	pruneMode prune.Mode
+	pruneCh   chan struct{}
 }
 
 type insertStats struct {
@@ -127,7 +132,7 @@ func (bc *BlockChain) Engine() consensus.Engine {
 	return bc.engine
 }
 
-func NewBlockChain(ctx context.Context, genesisBlock block2.IBlock, engine consensus.Engine, downloader common.IDownloader, db kv.RwDB, pubsub common.IPubSub, config *params.ChainConfig) (common.IBlockChain, error) {
+func NewBlockChain(ctx context.Context, genesisBlock block2.IBlock, engine consensus.Engine, downloader common.IDownloader, db kv.RwDB, pubsub common.IPubSub, config *params.ChainConfig, pruneMode prune.Mode) (common.IBlockChain, error) {
 	c, cancel := context.WithCancel(ctx)
 	var current *block2.Block
 	_ = db.View(c, func(tx kv.Tx) error {
@@ -160,6 +165,8 @@ func NewBlockChain(ctx context.Context, genesisBlock block2.IBlock, engine conse
 		tdCache:       tdCache,
 		futureBlocks:  futureBlocks,
 		receiptCache:  receiptsCache,
+		pruneMode:     pruneMode,
+		pruneCh:       make(chan struct{}, 1),
 	}
 
 	bc.forker = NewForkChoice(bc, nil)
@@ -216,6 +223,11 @@ func (bc *BlockChain) Start() error {
 	go bc.runLoop()
 	go bc.newBlockLoop()
 	go bc.updateFutureBlocksLoop()
+	if bc.pruneMode.Enabled() {
+		log.Info("Pruning block data", "mode", bc.pruneMode)
+		bc.wg.Add(1)
+		go bc.pruneLoop()
+	}
 
 	return nil
 }
@@ -256,6 +268,11 @@ func (bc *BlockChain) GetReceipts(blockHash types.Hash) (block2.Receipts, error)
 	if nil != err {
 		return nil, err
 	}
+	if number := rawdb.ReadHeaderNumber(rtx, blockHash); number != nil {
+		if err := rawdb.CheckPruned(rtx, rawdb.PruneKindReceipts, *number); nil != err {
+			return nil, err
+		}
+	}
 	return rawdb.ReadReceiptsByHash(rtx, blockHash)
 }
 
@@ -467,6 +484,41 @@ func (bc *BlockChain) runLoop() {
 	}
 }
 
+// pruneLoop removes the block data that fell out of the prune distances once
+// blocks are inserted. Large backlogs, as after enabling pruning on an
+// existing database, are pruned in batches of pruneBatchBlocks blocks, one
+// transaction each, so that block insertion is not held up.
+func (bc *BlockChain) pruneLoop() {
+	defer bc.wg.Done()
+	for {
+		select {
+		case <-bc.pruneCh:
+			for done := false; !done; {
+				if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
+					head := rawdb.ReadCurrentBlockNumber(tx)
+					if head == nil {
+						done = true
+						return nil
+					}
+					var err error
+					done, err = prune.Prune(tx, bc.pruneMode, *head, pruneBatchBlocks)
+					return err
+				}); nil != err {
+					log.Error("Failed to prune block data", "err", err)
+					break
+				}
+				select {
+				case <-bc.ctx.Done():
+					return
+				default:
+				}
+			}
+		case <-bc.ctx.Done():
+			return
+		}
+	}
+}
+
 // updateFutureBlocksLoop
 func (bc *BlockChain) updateFutureBlocksLoop() {
 	futureTimer := time.NewTicker(2 * time.Second)
@@ -607,7 +659,12 @@ func (bc *BlockChain) GetHeaderByHash(h types.Hash) (block2.IHeader, error) {
 		return nil, err
 	}
 	defer tx.Rollback()
-	return rawdb.ReadHeaderByHash(tx, h)
+	header, err := rawdb.ReadHeaderByHash(tx, h)
+	// Don't return a nil *block.Header as a non-nil block2.IHeader.
+	if nil != err || header == nil {
+		return nil, err
+	}
+	return header, nil
 }
 
 // GetCanonicalHash returns the canonical hash for a given block number
@@ -798,7 +855,10 @@ func (bc *BlockChain) GetTd(hash types.Hash, number *uint256.Int) *uint256.Int {
 		return nil
 	})
 
-	bc.tdCache.Add(hash, td)
+	// Blocks without a total difficulty yet are not cached
+	if td != nil {
+		bc.tdCache.Add(hash, td)
+	}
 	return td
 }
 
@@ -831,7 +891,14 @@ func (bc *BlockChain) InsertChain(chain []block2.IBlock) (int, error) {
 	}
 	bc.lock.Lock()
 	defer bc.lock.Unlock()
-	return bc.insertChain(chain)
+	n, err := bc.insertChain(chain)
+	if n > 0 && bc.pruneMode.Enabled() {
+		select {
+		case bc.pruneCh <- struct{}{}:
+		default:
+		}
+	}
+	return n, err
 }
 
 func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
@@ -865,46 +932,12 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 	// Peek the error for the first block to decide the directing import logic
 	it := newInsertIterator(chain, results, bc.validator)
 	block, err := it.next()
-	if bc.skipBlock(err) {
-		var (
-			reorg   bool
-			current = bc.CurrentBlock()
-		)
-		for block != nil && bc.skipBlock(err) {
-			reorg, err = bc.forker.ReorgNeeded(current.Header(), block.Header())
-			if err != nil {
-				return it.index, err
-			}
-			if reorg {
-				// Switch to import mode if the forker says the reorg is necessary
-				// and also the block is not on the canonical chain.
-				// In eth2 the forker always returns true for reorg decision (blindly trusting
-				// the external consensus engine), but in order to prevent the unnecessary
-				// reorgs when importing known blocks, the special case is handled here.
-				if block.Number64().Uint64() > current.Number64().Uint64() || bc.GetCanonicalHash(block.Number64()) != block.Hash() {
-					break
-				}
-			}
-			log.Debug("Ignoring already known block", "number", block.Number64(), "hash", block.Hash())
-			stats.ignored++
-			block, err = it.next()
-		}
-		// The remaining blocks are still known blocks, the only scenario here is:
-		// During the fast sync, the pivot point is already submitted but rollback
-		// happens. Then node resets the head full block to a lower height via `rollback`
-		// and leaves a few known blocks in the database.
-		//
-		// When node runs a fast sync again, it can re-import a batch of known blocks via
-		// `insertChain` while a part of them have higher total difficulty than current
-		// head full block(new pivot point).
-		for block != nil && bc.skipBlock(err) {
-			log.Debug("Writing previously known block", "number", block.Number64(), "hash", block.Hash())
-			if err := bc.writeKnownBlock(nil, block); err != nil {
-				return it.index, err
-			}
-			lastCanon = block
-			block, err = it.next()
-		}
+	// Known blocks are canonical blocks at or below the head, there is nothing
+	// left to write for them.
+	for block != nil && bc.skipBlock(err) {
+		log.Debug("Ignoring already known block", "number", block.Number64(), "hash", block.Hash())
+		stats.ignored++
+		block, err = it.next()
 	}
 
 	switch {
@@ -939,50 +972,7 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 		return it.index, err
 	}
 
-	//wtx, err := bc.ChainDB.BeginRw(bc.ctx)
-	//if nil != err {
-	//	return it.index, err
-	//}
-	//defer wtx.Rollback()
-
-	//var batch ethdb.DbWithPendingMutations
-
-	// state is stored through ethdb batches
-	//batch = olddb.NewHashBatch(wtx, bc.Quit(), paths.DefaultDataDir())
-	//// avoids stacking defers within the loop
-	//defer func() {
-	//	batch.Rollback()
-	//}()
-
-	evmRecord := func(ctx context.Context, db kv.RwDB, blockNr uint64, f func(tx kv.RwTx, ibs *state.IntraBlockState, reader state.StateReader, writer state.WriterWithChangeSets) error) error {
-		tx, err := db.BeginRw(ctx)
-		if nil != err {
-			return err
-		}
-		defer tx.Rollback()
-		//batch := olddb.NewHashBatch(tx, bc.Quit(), paths.DefaultDataDir())
-		//defer batch.Rollback()
-		//stateReader, stateWriter, err := NewStateReaderWriter(batch, tx, blockNr, true)
-		//if nil != err {
-		//	return err
-		//}
-
-		stateReader := state.NewPlainStateReader(tx)
-		ibs := state.New(stateReader)
-		stateWriter := state.NewPlainStateWriter(tx, tx, block.Number64().Uint64())
-
-		if err = f(tx, ibs, stateReader, stateWriter); nil != err {
-			return err
-		}
-
-		//if err := batch.Commit(); nil != err {
-		//	return err
-		//}
-
-		return tx.Commit()
-	}
-
-	for ; block != nil && err == nil || errors.Is(err, ErrKnownBlock); block, err = it.next() {
+	for ; block != nil && err == nil; block, err = it.next() {
 		// If the chain is terminating, stop processing blocks
 		if bc.insertStopped() {
 			log.Debug("Abort during block processing")
@@ -991,105 +981,40 @@ func (bc *BlockChain) insertChain(chain []block2.IBlock) (int, error) {
 
 		log.Tracef("Current block: number=%v, hash=%v, difficult=%v | Insert block block: number=%v, hash=%v, difficult= %v",
 			bc.CurrentBlock().Number64(), bc.CurrentBlock().Hash(), bc.CurrentBlock().Difficulty(), block.Number64(), block.Hash(), block.Difficulty())
-		// Retrieve the parent block and it's state to execute on top
+		// A block on top of an older canonical block starts a side chain,
+		// which only becomes canonical if the fork choice prefers it.
+		if block.ParentHash() != bc.CurrentBlock().Hash() {
+			return bc.insertSideChain(block, it)
+		}
 		start := time.Now()
-		// TODO
-		//stateDB := statedb.NewStateDB(block.ParentHash(), bc.chainDB, bc.changeDB)
-		//stateReader, stateWriter, err := NewStateReaderWriter(batch, wtx, block.Number64().Uint64(), true)
-		//if nil != err {
-		//	return it.index, err
-		//}
-		//ibs := state.New(stateReader)
-
-		var receipts block2.Receipts
-		var logs []*block2.Log
-		var usedGas uint64
-		if err := evmRecord(bc.ctx, bc.ChainDB, block.Number64().Uint64(), func(tx kv.RwTx, ibs *state.IntraBlockState, reader state.StateReader, writer state.WriterWithChangeSets) error {
-			getHeader := func(hash types.Hash, number uint64) *block2.Header {
-				return rawdb.ReadHeader(tx, hash, number)
-			}
-			blockHashFunc := GetHashFn(block.Header().(*block2.Header), getHeader)
 
+		var (
+			logs    []*block2.Log
+			usedGas uint64
+		)
+		if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
 			var err error
-			receipts, logs, usedGas, err = bc.process.Process(tx, block.(*block2.Block), ibs, reader, writer, blockHashFunc)
-			if err != nil {
-				bc.reportBlock(block, receipts, err)
-				//atomic.StoreUint32(&followupInterrupt, 1)
-				return err
-			}
-			if err := bc.validator.ValidateState(block, ibs, receipts, usedGas); err != nil {
-				bc.reportBlock(block, receipts, err)
-				//atomic.StoreUint32(&followupInterrupt, 1)
-				return err
-			}
-
-			return nil
+			_, logs, usedGas, err = bc.executeBlock(tx, block)
+			return err
 		}); nil != err {
 			return it.index, err
 		}
-		//var followupInterrupt uint32
-		//receipts, logs, usedGas, err := bc.process.Process(block.(*block2.Block), ibs, stateReader, stateWriter, blockHashFunc)
-		//if err != nil {
-		//	bc.reportBlock(block, receipts, err)
-		//	//atomic.StoreUint32(&followupInterrupt, 1)
-		//	return it.index, err
-		//}
-
-		//if err := bc.validator.ValidateState(block, ibs, receipts, usedGas); err != nil {
-		//	bc.reportBlock(block, receipts, err)
-		//	//atomic.StoreUint32(&followupInterrupt, 1)
-		//	return it.index, err
-		//}
-
-		// write state
-		//if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-		//	stateWrite := state.NewPlainStateWriter(batch, tx, block.Number64().Uint64())
-		//	if err := ibs.CommitBlock(params.AmazeChainConfig.Rules(block.Number64().Uint64()), stateWrite); nil != err {
-		//		return err
-		//	}
-		//	return nil
-		//}); nil != err {
-		//	return it.index, err
-		//}
-
-		var status WriteStatus
-		status, err = bc.writeBlockWithState(block, receipts)
-		//atomic.StoreUint32(&followupInterrupt, 1)
-		if err != nil {
-			return it.index, err
-		}
+		bc.currentBlock = block
+		bc.futureBlocks.Remove(block.Hash())
 
 		// Report the import stats before returning the various results
 		stats.processed++
 		stats.usedGas += usedGas
 
-		switch status {
-		case CanonStatTy:
-			log.Debug("Inserted new block ", "number ", block.Number64(), "hash", block.Hash(),
-				"txs", len(block.Transactions()), "gas", block.GasUsed(),
-				"elapsed", time.Since(start).Seconds(),
-				"root", block.StateRoot())
+		log.Debug("Inserted new block ", "number ", block.Number64(), "hash", block.Hash(),
+			"txs", len(block.Transactions()), "gas", block.GasUsed(),
+			"elapsed", time.Since(start).Seconds(),
+			"root", block.StateRoot())
 
-			if len(logs) > 0 {
-				event.GlobalEvent.Send(&common.NewLogsEvent{Logs: logs})
-			}
-
-			lastCanon = block
-
-		case SideStatTy:
-			log.Debug("Inserted forked block", "number", block.Number64(), "hash", block.Hash(),
-				"diff", block.Difficulty(), "elapsed", time.Since(start).Seconds(),
-				"txs", len(block.Transactions()), "gas", block.GasUsed(),
-				"root", block.StateRoot())
-
-		default:
-			// This in theory is impossible, but lets be nice to our future selves and leave
-			// a log, instead of trying to track down blocks imports that don't emit logs.
-			log.Warn("Inserted block with unknown status", "number", block.Number64(), "hash", block.Hash(),
-				"diff", block.Difficulty(), "elapsed", time.Since(start).Seconds(),
-				"txs", len(block.Transactions()), "gas", block.GasUsed(),
-				"root", block.StateRoot())
+		if len(logs) > 0 {
+			event.GlobalEvent.Send(&common.NewLogsEvent{Logs: logs})
 		}
+		lastCanon = block
 	}
 
 	// Any blocks remaining here? The only ones we care about are the future ones
@@ -1154,11 +1079,16 @@ func (bc *BlockChain) insertSideChain(block block2.IBlock, it *insertIterator) (
 			}
 		}
 		if externTd.Cmp(uint256.NewInt(0)) == 0 {
-			externTd = *bc.GetTd(block.ParentHash(), uint256.NewInt(0).Sub(block.Number64(), uint256.NewInt(1)))
+			ptd := bc.GetTd(block.ParentHash(), uint256.NewInt(0).Sub(block.Number64(), uint256.NewInt(1)))
+			if ptd == nil {
+				return it.index, consensus.ErrUnknownAncestor
+			}
+			externTd = *ptd
 		}
 		externTd = *externTd.Add(&externTd, block.Difficulty())
 
-		if !bc.HasBlock(block.Hash(), block.Number64().Uint64()) {
+		// Blocks stored without their total difficulty are written again
+		if bc.GetTd(block.Hash(), block.Number64()) == nil {
 			start := time.Now()
 			if err := bc.WriteBlockWithoutState(block); err != nil {
 				return it.index, err
@@ -1181,51 +1111,7 @@ func (bc *BlockChain) insertSideChain(block block2.IBlock, it *insertIterator) (
 		log.Info("Sidechain written to disk", "start", it.first().Number64(), "end", it.previous().Number64(), "sidetd", externTd, "localtd", localTd)
 		return it.index, err
 	}
-	var (
-		hashes  []types.Hash
-		numbers []uint64
-	)
-	parent := it.previous()
-	for parent != nil && !bc.HasState(parent.StateRoot()) {
-		hashes = append(hashes, parent.Hash())
-		numbers = append(numbers, parent.Number64().Uint64())
-
-		parent = bc.GetHeader(parent.(*block2.Header).ParentHash, uint256.NewInt(0).Sub(parent.Number64(), uint256.NewInt(1)))
-	}
-	if parent == nil {
-		return it.index, errors.New("missing parent")
-	}
-	// Import all the pruned blocks to make the state available
-	var (
-		blocks []block2.IBlock
-	)
-	for i := len(hashes) - 1; i >= 0; i-- {
-		// Append the next block to our batch
-		block := bc.GetBlock(hashes[i], numbers[i])
-
-		blocks = append(blocks, block)
-
-		// If memory use grew too large, import and continue. Sadly we need to discard
-		// all raised events and logs from notifications since we're too heavy on the
-		// memory here.
-		if len(blocks) >= 2048 {
-			log.Info("Importing heavy sidechain segment", "blocks", len(blocks), "start", blocks[0].Number64(), "end", block.Number64())
-			if _, err := bc.insertChain(blocks); err != nil {
-				return 0, err
-			}
-			blocks = blocks[:0]
-			// If the chain is terminating, stop processing blocks
-			if bc.insertStopped() {
-				log.Debug("Abort during blocks processing")
-				return 0, nil
-			}
-		}
-	}
-	if len(blocks) > 0 {
-		log.Info("Importing sidechain segment", "start", blocks[0].Number64(), "end", blocks[len(blocks)-1].Number64())
-		return bc.insertChain(blocks)
-	}
-	return 0, nil
+	return it.index, bc.reorg(lastBlock)
 }
 
 // recoverAncestors
@@ -1258,112 +1144,119 @@ func (bc *BlockChain) recoverAncestors(block block2.IBlock) (types.Hash, error)
 	return block.Hash(), nil
 }
 
-// WriteBlockWithoutState without state
+// WriteBlockWithoutState writes a block and its total difficulty without
+// executing it, e.g. a side chain block.
 func (bc *BlockChain) WriteBlockWithoutState(block block2.IBlock) (err error) {
 	if bc.insertStopped() {
 		return errInsertionInterrupted
 	}
-	//if err := bc.state.WriteTD(block.Hash(), td); err != nil {
-	//	return err
-	//}
 	return bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-		if err := rawdb.WriteBlock(tx, block.(*block2.Block)); err != nil {
-			return err
-		}
-		return nil
-	})
-}
-
-// WriteBlockWithState
-func (bc *BlockChain) writeBlockWithState(block block2.IBlock, receipts []*block2.Receipt) (status WriteStatus, err error) {
-	if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-		//ptd := bc.GetTd(block.ParentHash(), block.Number64().Sub(uint256.NewInt(1)))
-		ptd, err := rawdb.ReadTd(tx, block.ParentHash(), uint256.NewInt(0).Sub(block.Number64(), uint256.NewInt(1)).Uint64())
+		number := block.Number64().Uint64()
+		ptd, err := rawdb.ReadTd(tx, block.ParentHash(), number-1)
 		if nil != err {
-			log.Errorf("ReadTd failed err: %v", err)
+			return err
 		}
 		if ptd == nil {
 			return consensus.ErrUnknownAncestor
 		}
-
-		//if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-		externTd := uint256.NewInt(0).Add(ptd, block.Difficulty())
-		if err := rawdb.WriteTd(tx, block.Hash(), block.Number64().Uint64(), externTd); nil != err {
-			return err
-		}
-		log.Trace("writeTd:", "number", block.Number64().Uint64(), "hash", block.Hash(), "td", externTd.Uint64())
-		if len(receipts) > 0 {
-			//if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-			if err := rawdb.AppendReceipts(tx, block.Number64().Uint64(), receipts); nil != err {
-				log.Errorf("rawdb.AppendReceipts failed err= %v", err)
-				return err
-			}
-		}
-		if err := rawdb.WriteBlock(tx, block.(*block2.Block)); err != nil {
+		if err := rawdb.WriteTd(tx, block.Hash(), number, uint256.NewInt(0).Add(ptd, block.Difficulty())); nil != err {
 			return err
 		}
-		//todo
-		rawdb.WriteTxLookupEntries(tx, block.(*block2.Block))
-		return nil
-	}); nil != err {
-		return NonStatTy, err
-	}
+		return rawdb.WriteBlock(tx, block.(*block2.Block))
+	})
+}
 
-	reorg, err := bc.forker.ReorgNeeded(bc.currentBlock.Header(), block.Header())
+// executeBlock executes a block on top of the head block in tx, validates the
+// result and makes the block the head. The caller makes it the current block
+// once tx is committed, so an invalid block leaves nothing behind.
+func (bc *BlockChain) executeBlock(tx kv.RwTx, block block2.IBlock) (block2.Receipts, []*block2.Log, uint64, error) {
+	number := block.Number64().Uint64()
+	if head := rawdb.ReadHeadBlockHash(tx); head != block.ParentHash() {
+		return nil, nil, 0, fmt.Errorf("block %d is not on top of the head block %x", number, head)
+	}
+	parent := rawdb.ReadHeader(tx, block.ParentHash(), number-1)
+	if parent == nil {
+		return nil, nil, 0, consensus.ErrUnknownAncestor
+	}
+	ptd, err := rawdb.ReadTd(tx, block.ParentHash(), number-1)
 	if nil != err {
-		return NonStatTy, err
+		return nil, nil, 0, err
 	}
-	if reorg {
-		// Reorganise the chain if the parent is not the head block
-		if block.ParentHash() != bc.currentBlock.Hash() {
-			if err := bc.reorg(nil, bc.currentBlock, block); err != nil {
-				return NonStatTy, err
-			}
-		}
-		status = CanonStatTy
-	} else {
-		status = SideStatTy
+	if ptd == nil {
+		return nil, nil, 0, consensus.ErrUnknownAncestor
+	}
+	if err := bc.validator.ValidateAggSign(tx, block); nil != err {
+		bc.reportBlock(block, nil, err)
+		return nil, nil, 0, err
 	}
-	// Set new head.
-	if status == CanonStatTy {
-		if err := bc.writeHeadBlock(nil, block); nil != err {
-			log.Errorf("failed to save lates blocks, err: %v", err)
-			return NonStatTy, err
+
+	stateReader := state.NewPlainStateReader(tx)
+	ibs := state.New(stateReader)
+	stateWriter := state.NewPlainStateWriter(tx, tx, number)
+	if bc.chainConfig.IsMerkle(number) {
+		parentRoot, err := state.ParentTrieRoot(tx, bc.chainConfig, number, parent.Root)
+		if nil != err {
+			return nil, nil, 0, err
 		}
+		ibs.UseStateTrie(tx, number, parentRoot, bc.chainConfig.Rules(number))
 	}
-	//
-	if _, ok := bc.futureBlocks.Get(block.Hash()); ok {
-		bc.futureBlocks.Remove(block.Hash())
+	getHeader := func(hash types.Hash, number uint64) *block2.Header {
+		return rawdb.ReadHeader(tx, hash, number)
+	}
+	receipts, logs, usedGas, err := bc.process.Process(tx, block.(*block2.Block), ibs, stateReader, stateWriter, GetHashFn(block.Header().(*block2.Header), getHeader))
+	if err != nil {
+		bc.reportBlock(block, receipts, err)
+		return nil, nil, 0, err
+	}
+	if err := bc.validator.ValidateState(block, ibs, receipts, usedGas); err != nil {
+		bc.reportBlock(block, receipts, err)
+		return nil, nil, 0, err
 	}
-	return status, nil
-}
 
-// writeHeadBlock head
-func (bc *BlockChain) writeHeadBlock(tx kv.RwTx, block block2.IBlock) error {
-	var err error
-	var notExternalTx bool
-	if nil == tx {
-		tx, err = bc.ChainDB.BeginRw(bc.ctx)
-		if nil != err {
-			return err
+	if err := rawdb.WriteTd(tx, block.Hash(), number, uint256.NewInt(0).Add(ptd, block.Difficulty())); nil != err {
+		return nil, nil, 0, err
+	}
+	if len(receipts) > 0 {
+		if err := rawdb.AppendReceipts(tx, number, receipts); nil != err {
+			return nil, nil, 0, err
 		}
-		defer tx.Rollback()
-		notExternalTx = true
 	}
+	if err := bc.processDeposits(tx, block, receipts); nil != err {
+		return nil, nil, 0, err
+	}
+	if err := bc.writeHeadBlock(tx, block); nil != err {
+		return nil, nil, 0, err
+	}
+	return receipts, logs, usedGas, nil
+}
 
+// writeHeadBlock writes an executed block and makes it the head block in tx.
+func (bc *BlockChain) writeHeadBlock(tx kv.RwTx, block block2.IBlock) error {
 	if err := rawdb.WriteBlock(tx, block.(*block2.Block)); nil != err {
 		log.Errorf("failed to save last block, err: %v", err)
 		return err
 	}
+	rawdb.WriteTxLookupEntries(tx, block.(*block2.Block))
+	if err := rawdb.WriteCanonicalHash(tx, block.Hash(), block.Number64().Uint64()); nil != err {
+		return err
+	}
 	rawdb.WriteHeadBlockHash(tx, block.Hash())
-	if err = rawdb.WriteCanonicalHash(tx, block.Hash(), block.Number64().Uint64()); nil != err {
+	if err := rawdb.WriteHeadHeaderHash(tx, block.Hash()); nil != err {
 		return err
 	}
-	bc.currentBlock = block
-	if notExternalTx {
-		if err = tx.Commit(); nil != err {
-			return err
-		}
+	return rawdb.WriteHeadLogIndex(tx, block.Number64().Uint64())
+}
+
+// processDeposits applies the deposit contract events of a block being
+// executed, if the consensus engine keeps a deposit registry. The changes are
+// recorded in the DepositChangeSet and reverted when the block is unwound.
+func (bc *BlockChain) processDeposits(tx kv.RwTx, block block2.IBlock, receipts block2.Receipts) error {
+	dp, ok := bc.engine.(consensus.DepositProcessor)
+	if !ok {
+		return nil
+	}
+	if err := dp.ProcessDeposits(tx, block.Header(), block.Transactions(), receipts); nil != err {
+		return fmt.Errorf("failed to process deposits of block %d: %w", block.Number64().Uint64(), err)
 	}
 	return nil
 }
@@ -1400,15 +1293,120 @@ func (bc *BlockChain) ReorgNeeded(current block2.IBlock, header block2.IBlock) b
 	return true
 }
 
-// SetHead set new head
+// SetHead rewinds the canonical chain and its state to the given block. The
+// blocks above it are kept and can be imported again.
 func (bc *BlockChain) SetHead(head uint64) error {
-	newHeadBlock, err := bc.GetBlockByNumber(uint256.NewInt(head))
-	if err != nil {
-		return nil
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	var newHead block2.IBlock
+	if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
+		var err error
+		newHead, err = bc.unwindTo(tx, head)
+		return err
+	}); nil != err {
+		return err
 	}
-	return bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
-		return rawdb.WriteHeadHeaderHash(tx, newHeadBlock.Hash())
-	})
+	bc.currentBlock = newHead
+	log.Info("Rewound chain", "number", newHead.Number64().Uint64(), "hash", newHead.Hash())
+	return nil
+}
+
+// unwindTo reverts the canonical chain to the block with the given number and
+// returns that block, the caller makes it the current block once tx is
+// committed. The plain state is restored from the change sets, and the
+// receipts, logs, log indexes, deposit registry, transaction lookups and
+// canonical hashes of the blocks above it are removed.
+func (bc *BlockChain) unwindTo(tx kv.RwTx, number uint64) (block2.IBlock, error) {
+	head := rawdb.ReadCurrentBlock(tx)
+	if head == nil {
+		return nil, errBlockDoesNotExist
+	}
+	target, err := rawdb.ReadBlockByNumber(tx, number)
+	if nil != err {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("canonical block %d not found", number)
+	}
+	current := head.Number64().Uint64()
+	if number >= current {
+		return head, nil
+	}
+	// The change sets of the blocks above the target are needed to revert the
+	// state.
+	if err := rawdb.CheckPruned(tx, rawdb.PruneKindHistory, number+1); nil != err {
+		return nil, fmt.Errorf("cannot unwind to block %d: %w", number, err)
+	}
+
+	// Newest block first, as the deposit registry is unwound one block at a
+	// time.
+	for n := current; n > number; n-- {
+		b, err := rawdb.ReadBlockByNumber(tx, n)
+		if nil != err {
+			return nil, err
+		}
+		if b != nil {
+			for _, t := range b.Transactions() {
+				if err := rawdb.DeleteTxLookupEntry(tx, t.Hash()); nil != err {
+					return nil, err
+				}
+			}
+		}
+		if err := rawdb.UnwindDeposits(tx, n); nil != err {
+			return nil, err
+		}
+	}
+	// The log indexes are unwound from the logs, before these are removed.
+	if err := rawdb.UnwindLogIndex(tx, number+1); nil != err {
+		return nil, err
+	}
+	if err := state.UnwindState(tx, current, number); nil != err {
+		return nil, err
+	}
+	if err := rawdb.TruncateReceipts(tx, number+1); nil != err {
+		return nil, err
+	}
+	// Receipts of the blocks inserted again on top of the target are stored.
+	if boundary, err := rawdb.ReadPruneBoundary(tx, rawdb.PruneKindReceipts); nil != err {
+		return nil, err
+	} else if boundary > number+1 {
+		if err := rawdb.WritePruneBoundary(tx, rawdb.PruneKindReceipts, number+1); nil != err {
+			return nil, err
+		}
+	}
+	if err := rawdb.TruncateCanonicalHash(tx, number+1, false); nil != err {
+		return nil, err
+	}
+	rawdb.WriteHeadBlockHash(tx, target.Hash())
+	if err := rawdb.WriteHeadHeaderHash(tx, target.Hash()); nil != err {
+		return nil, err
+	}
+	log.Info("Unwound chain", "from", current, "to", number)
+	return target, nil
+}
+
+// ResetHead makes a block stored by update the head of the chain without
+// executing it, e.g. after importing a snap synced state. update runs in the
+// same transaction and returns the new head, which becomes the current block
+// once the transaction is committed.
+func (bc *BlockChain) ResetHead(update func(tx kv.RwTx) (block2.IBlock, error)) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	var head block2.IBlock
+	if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
+		var err error
+		if head, err = update(tx); nil != err {
+			return err
+		}
+		rawdb.WriteHeadBlockHash(tx, head.Hash())
+		return rawdb.WriteHeadHeaderHash(tx, head.Hash())
+	}); nil != err {
+		return err
+	}
+	bc.currentBlock = head
+	return nil
 }
 
 // addFutureBlock checks if the block is within the max allowed window to get
@@ -1432,176 +1430,89 @@ func (bc *BlockChain) addFutureBlock(block block2.IBlock) error {
 	return nil
 }
 
-// writeKnownBlock updates the head block flag with a known block
-// and introduces chain reorg if necessary.
-func (bc *BlockChain) writeKnownBlock(tx kv.RwTx, block block2.IBlock) error {
-	var notExternalTx bool
-	var err error
-	if nil == tx {
-		tx, err = bc.ChainDB.BeginRw(bc.ctx)
-		if nil != err {
-			return err
-		}
-		defer tx.Rollback()
-		notExternalTx = true
-	}
-
-	current := bc.CurrentBlock()
-	if block.ParentHash() != current.Hash() {
-		if err := bc.reorg(tx, current, block); err != nil {
-			return err
-		}
-	}
-	if err = bc.writeHeadBlock(tx, block); nil != err {
-		return err
-	}
-	if notExternalTx {
-		if err = tx.Commit(); nil != err {
-			return err
-		}
-	}
-	return nil
-}
-
-// reorg takes two blocks, an old chain and a new chain and will reconstruct the
-// blocks and inserts them to be part of the new canonical chain and accumulates
-// potential missing transactions and post an event about them.
-// Note the new head block won't be processed here, callers need to handle it
-// externally.
-func (bc *BlockChain) reorg(tx kv.RwTx, oldBlock, newBlock block2.IBlock) error {
+// reorg makes the side chain ending in newHead canonical. The canonical chain
+// is unwound to the common ancestor and the blocks of the side chain are
+// executed on top of it in a single transaction, so the chain is left as it
+// was if any of them is invalid. The current block only moves once they are
+// all written.
+func (bc *BlockChain) reorg(newHead block2.IBlock) error {
 	var (
-		newChain    block2.Blocks
-		oldChain    block2.Blocks
-		commonBlock block2.IBlock
-
-		deletedTxs []types.Hash
-		addedTxs   []types.Hash
+		newChain    []block2.IBlock
+		removedLogs []*block2.Log
+		addedLogs   []*block2.Log
+		oldHead     = bc.CurrentBlock()
 	)
-	// Reduce the longer chain to the same number as the shorter one
-	if oldBlock.Number64().Uint64() > newBlock.Number64().Uint64() {
-		// Old chain is longer, gather all transactions and logs as deleted ones
-		for ; oldBlock != nil && oldBlock.Number64().Uint64() != newBlock.Number64().Uint64(); oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.Number64().Uint64()-1) {
-			oldChain = append(oldChain, oldBlock)
-			for _, tx := range oldBlock.Transactions() {
-				hash := tx.Hash()
-				deletedTxs = append(deletedTxs, hash)
+	if err := bc.ChainDB.Update(bc.ctx, func(tx kv.RwTx) error {
+		// Walk back to the common ancestor
+		ancestor := newHead
+		for {
+			hash, err := rawdb.ReadCanonicalHash(tx, ancestor.Number64().Uint64())
+			if nil != err {
+				return err
+			}
+			if hash == ancestor.Hash() {
+				break
+			}
+			newChain = append(newChain, ancestor)
+			if ancestor = rawdb.ReadBlock(tx, ancestor.ParentHash(), ancestor.Number64().Uint64()-1); ancestor == nil {
+				return fmt.Errorf("invalid new chain")
 			}
 		}
-	} else {
-		// New chain is longer, stash all blocks away for subsequent insertion
-		for ; newBlock != nil && newBlock.Number64() != oldBlock.Number64(); newBlock = bc.GetBlock(newBlock.ParentHash(), newBlock.Number64().Uint64()-1) {
-			newChain = append(newChain, newBlock)
-		}
-	}
-	if oldBlock == nil {
-		return fmt.Errorf("invalid old chain")
-	}
-	if newBlock == nil {
-		return fmt.Errorf("invalid new chain")
-	}
-
-	var useExternalTx bool
-	var err error
-	if tx == nil {
-		tx, err = bc.ChainDB.BeginRw(bc.ctx)
-		if nil != err {
-			return err
-		}
-		defer tx.Rollback()
-		useExternalTx = false
-	}
-
-	// Both sides of the reorg are at the same number, reduce both until the common
-	// ancestor is found
-	for {
-		// If the common ancestor was found, bail out
-		if oldBlock.Hash() == newBlock.Hash() {
-			commonBlock = oldBlock
-			break
+		if len(newChain) == 0 {
+			return nil
 		}
-		// Remove an old block as well as stash away a new block
-		oldChain = append(oldChain, oldBlock)
-		for _, tx := range oldBlock.Transactions() {
-			h := tx.Hash()
-			deletedTxs = append(deletedTxs, h)
+		number := ancestor.Number64().Uint64()
+		for n := number + 1; n <= oldHead.Number64().Uint64(); n++ {
+			for _, r := range rawdb.ReadRawReceipts(tx, n) {
+				for _, l := range r.Logs {
+					l := *l
+					l.Removed = true
+					removedLogs = append(removedLogs, &l)
+				}
+			}
 		}
-		newChain = append(newChain, newBlock)
-
-		// Step back with both chains
-		//oldBlock = bc.GetBlock(oldBlock.ParentHash(), oldBlock.Number64().Uint64()-1)
-		oldBlock = rawdb.ReadBlock(tx, oldBlock.ParentHash(), oldBlock.Number64().Uint64()-1)
-		if oldBlock == nil {
-			return fmt.Errorf("invalid old chain")
+		if _, err := bc.unwindTo(tx, number); nil != err {
+			return err
 		}
-		//newBlock = bc.GetBlock(newBlock.ParentHash(), newBlock.Number64().Uint64()-1)
-		newBlock = rawdb.ReadBlock(tx, newBlock.ParentHash(), newBlock.Number64().Uint64()-1)
-		if newBlock == nil {
-			return fmt.Errorf("invalid new chain")
+		for i := len(newChain) - 1; i >= 0; i-- {
+			_, logs, _, err := bc.executeBlock(tx, newChain[i])
+			if nil != err {
+				return err
+			}
+			addedLogs = append(addedLogs, logs...)
 		}
-	}
 
-	// Ensure the user sees large reorgs
-	if len(oldChain) > 0 && len(newChain) > 0 {
+		// Ensure the user sees large reorgs
+		drop := oldHead.Number64().Uint64() - number
 		logFn := log.Info
 		msg := "Chain reorg detected"
-		if len(oldChain) > 63 {
+		if drop > 63 {
 			msg = "Large chain reorg detected"
 			logFn = log.Warn
 		}
-		logFn(msg, "number", commonBlock.Number64(), "hash", commonBlock.Hash(),
-			"drop", len(oldChain), "dropfrom", oldChain[0].Hash(), "add", len(newChain), "addfrom", newChain[0].Hash())
-	} else if len(newChain) > 0 {
-		// Special case happens in the post merge stage that current head is
-		// the ancestor of new head while these two blocks are not consecutive
-		log.Info("Extend chain", "add", len(newChain), "number", newChain[0].Number64(), "hash", newChain[0].Hash())
-	} else {
-		// len(newChain) == 0 && len(oldChain) > 0
-		// rewind the canonical chain to a lower point.
-		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number64(), "oldhash", oldBlock.Hash(), "oldblocks", len(oldChain), "newnum", newBlock.Number64(), "newhash", newBlock.Hash(), "newblocks", len(newChain))
-	}
-	// Insert the new chain(except the head block(reverse order)),
-	// taking care of the proper incremental order.
-	for i := len(newChain) - 1; i >= 1; i-- {
-		// Insert the block in the canonical way, re-writing history
-		bc.writeHeadBlock(tx, newChain[i])
-
-		// Collect the new added transactions.
-		for _, tx := range newChain[i].Transactions() {
-			h := tx.Hash()
-			addedTxs = append(addedTxs, h)
-		}
-	}
-
-	//return bc.ChainDB.Update(bc.ctx, func(txw kv.RwTx) error {
-	// Delete useless indexes right now which includes the non-canonical
-	// transaction indexes, canonical chain indexes which above the head.
-	for _, t := range types.HashDifference(deletedTxs, addedTxs) {
-		rawdb.DeleteTxLookupEntry(tx, t)
+		logFn(msg, "number", number, "hash", ancestor.Hash(), "drop", drop, "add", len(newChain), "addfrom", newChain[len(newChain)-1].Hash())
+		return nil
+	}); nil != err {
+		return err
 	}
-
-	// Delete all hash markers that are not part of the new canonical chain.
-	// Because the reorg function does not handle new chain head, all hash
-	// markers greater than or equal to new chain head should be deleted.
-	number := commonBlock.Number64().Uint64()
-	if len(newChain) > 1 {
-		number = newChain[1].Number64().Uint64()
+	// A new head already on the canonical chain leaves the head as it is
+	if len(newChain) == 0 {
+		return nil
 	}
-	for i := number + 1; ; i++ {
-		hash, _ := rawdb.ReadCanonicalHash(tx, i)
-		if hash == (types.Hash{}) {
-			break
-		}
-		rawdb.TruncateCanonicalHash(tx, i, false)
+	bc.currentBlock = newHead
+	for _, b := range newChain {
+		bc.futureBlocks.Remove(b.Hash())
 	}
 
-	if !useExternalTx {
-		if err = tx.Commit(); nil != err {
-			return err
-		}
+	if len(removedLogs) > 0 {
+		event.GlobalEvent.Send(&common.RemovedLogsEvent{Logs: removedLogs})
+	}
+	if len(addedLogs) > 0 {
+		event.GlobalEvent.Send(&common.NewLogsEvent{Logs: addedLogs})
 	}
-
 	return nil
 }
+
 func (bc *BlockChain) Quit() <-chan struct{} {
 	return bc.ctx.Done()
 }
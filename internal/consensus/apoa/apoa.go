@@ -631,6 +631,18 @@ func (c *Apoa) Authorize(signer types.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// IsSigner reports whether the address is an authorized signer at the head of
+// the chain.
+func (c *Apoa) IsSigner(chain consensus.ChainHeaderReader, signer types.Address) (bool, error) {
+	header := chain.CurrentBlock().Header()
+	snap, err := c.snapshot(chain, header.Number64().Uint64(), header.Hash(), nil)
+	if err != nil {
+		return false, err
+	}
+	_, ok := snap.Signers[signer]
+	return ok, nil
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Apoa) Seal(chain consensus.ChainHeaderReader, b block.IBlock, results chan<- block.IBlock, stop <-chan struct{}) error {
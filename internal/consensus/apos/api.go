@@ -289,7 +289,7 @@ func (api *API) GetDepositInfo(address common.Address) (*deposit.Info, error) {
 	var err error
 
 	api.apos.db.View(context.Background(), func(tx kv.Tx) error {
-		info = deposit.GetDepositInfo(tx, addr)
+		info = deposit.GetDepositInfo(tx, addr, api.apos.config.APos.DepositSchedules, api.chain.CurrentBlock().Number64().Uint64())
 		return nil
 	})
 
@@ -344,7 +344,7 @@ func (api *API) GetMinedBlock(address common.Address, from jsonrpc.BlockNumberOr
 	)
 
 	api.apos.db.View(context.Background(), func(tx kv.Tx) error {
-		depositInfo = deposit.GetDepositInfo(tx, addr)
+		depositInfo = deposit.GetDepositInfo(tx, addr, api.apos.config.APos.DepositSchedules, api.chain.CurrentBlock().Number64().Uint64())
 		return nil
 	})
 	if depositInfo == nil {
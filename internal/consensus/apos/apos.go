@@ -27,6 +27,7 @@ import (
 	"github.com/holiman/uint256"
 
 	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/contracts/deposit"
 	"github.com/amazechain/amc/internal/api"
 	"github.com/amazechain/amc/modules/rawdb"
 
@@ -64,6 +65,8 @@ const (
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
+
+	defaultMinVerifiers = 1 // Minimum number of verifier signatures required if not configured
 )
 
 // APos proof-of-authority protocol constants.
@@ -212,6 +215,12 @@ func New(config *conf.ConsensusConfig, db kv.RwDB, chainConfig *params.ChainConf
 	if conf.APos.Epoch == 0 {
 		conf.APos.Epoch = epochLength
 	}
+	if conf.APos.MinVerifiers == 0 {
+		conf.APos.MinVerifiers = defaultMinVerifiers
+	}
+	if conf.APos.VerifierQuorum > 100 {
+		conf.APos.VerifierQuorum = 100
+	}
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
@@ -616,9 +625,7 @@ func (c *APos) Rewards(tx kv.RwTx, header block.IHeader, state *state.IntraBlock
 	//calc rewards
 	var rewards []*block.Reward
 
-	beijing, _ := uint256.FromBig(c.chainConfig.BeijingBlock)
-	if new(uint256.Int).Mod(new(uint256.Int).Sub(header.Number64(), beijing), uint256.NewInt(c.config.APos.RewardEpoch)).
-		Cmp(uint256.NewInt(0)) == 0 {
+	if c.isRewardBlock(header.Number64()) {
 		log.Debug("begin setreward", "headnumber", header.Number64().ToBig().String())
 
 		rewardService := newReward(c.config, c.chainConfig)
@@ -649,6 +656,13 @@ func (c *APos) Rewards(tx kv.RwTx, header block.IHeader, state *state.IntraBlock
 	return rewards, nil
 }
 
+// isRewardBlock reports whether the rewards of an epoch are paid in the block.
+func (c *APos) isRewardBlock(number *uint256.Int) bool {
+	beijing, _ := uint256.FromBig(c.chainConfig.BeijingBlock)
+	return new(uint256.Int).Mod(new(uint256.Int).Sub(number, beijing), uint256.NewInt(c.config.APos.RewardEpoch)).
+		Cmp(uint256.NewInt(0)) == 0
+}
+
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (c *APos) Finalize(chain consensus.ChainHeaderReader, header block.IHeader, state *state.IntraBlockState, txs []*transaction.Transaction, uncles []block.IHeader) {
@@ -683,6 +697,18 @@ func (c *APos) Authorize(signer types.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// IsSigner reports whether the address is an authorized signer at the head of
+// the chain.
+func (c *APos) IsSigner(chain consensus.ChainHeaderReader, signer types.Address) (bool, error) {
+	header := chain.CurrentBlock().Header()
+	snap, err := c.snapshot(chain, header.Number64().Uint64(), header.Hash(), nil)
+	if err != nil {
+		return false, err
+	}
+	_, ok := snap.Signers[signer]
+	return ok, nil
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *APos) Seal(chain consensus.ChainHeaderReader, b block.IBlock, results chan<- block.IBlock, stop <-chan struct{}) error {
@@ -735,29 +761,17 @@ func (c *APos) Seal(chain consensus.ChainHeaderReader, b block.IBlock, results c
 	if c.chainConfig.IsBeijing(header.Number.Uint64()) {
 		ctx, cancle := context.WithTimeout(context.Background(), delay)
 		defer cancle()
-		member := c.CountDepositor()
-		aggSign, verifiers, err := api.SignMerge(ctx, header, member)
+		aggSign, verifiers, err := api.SignMerge(ctx, c.db, header, c.Quorum(c.CountDepositor()))
 		if nil != err {
 			return err
 		}
-		ss := make([]bls.PublicKey, len(verifiers))
-		for i, p := range verifiers {
-			blsP, err := bls.PublicKeyFromBytes(p.PublicKey[:])
-			if nil != err {
-				return err
-			}
-			ss[i] = blsP
-		}
 
-		sig, err := bls.SignatureFromBytes(aggSign[:])
-		if nil != err {
+		header.Signature = aggSign
+		if err := c.db.View(context.Background(), func(tx kv.Tx) error {
+			return c.VerifyAggSign(tx, header, verifiers)
+		}); nil != err {
 			return err
 		}
-		if false && !sig.FastAggregateVerify(ss, header.Root) {
-			return fmt.Errorf("AggSignature verify falied")
-		}
-
-		header.Signature = aggSign
 		body := b.Body().(*block.Body)
 		body.Verifiers = verifiers
 
@@ -899,6 +913,113 @@ func (c *APos) CountDepositor() uint64 {
 	return count
 }
 
+// Quorum returns the number of verifier signatures a block needs, given the
+// number of accounts that currently hold a deposit.
+func (c *APos) Quorum(depositNum uint64) uint64 {
+	quorum := (depositNum*c.config.APos.VerifierQuorum + 99) / 100
+	if quorum < c.config.APos.MinVerifiers {
+		quorum = c.config.APos.MinVerifiers
+	}
+	return quorum
+}
+
+// VerifyAggSign implements consensus.AggSignVerifier, checking the aggregated
+// BLS signature of the header against the verifiers listed in the block body.
+// Deposits are read as of the parent block, which must be canonical.
+func (c *APos) VerifyAggSign(tx kv.Tx, iHeader block.IHeader, verifiers []*block.Verify) error {
+	header := iHeader.(*block.Header)
+
+	number := header.Number.Uint64()
+	if number == 0 {
+		return consensus.ErrUnknownAncestor
+	}
+	parent := number - 1
+	if hash, err := rawdb.ReadCanonicalHash(tx, parent); nil != err {
+		return err
+	} else if hash != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+
+	depositNum, err := rawdb.DepositNumAt(tx, parent)
+	if nil != err {
+		return err
+	}
+	if quorum := c.Quorum(depositNum); uint64(len(verifiers)) < quorum {
+		return fmt.Errorf("%w: have %d, want %d", consensus.ErrNotEnoughSign, len(verifiers), quorum)
+	}
+
+	uniq := make(map[types.Address]struct{}, len(verifiers))
+	pubs := make([]bls.PublicKey, len(verifiers))
+	for i, v := range verifiers {
+		if _, ok := uniq[v.Address]; ok {
+			return fmt.Errorf("%w: %s", consensus.ErrDuplicateVerifier, v.Address)
+		}
+		uniq[v.Address] = struct{}{}
+
+		pub, _, err := rawdb.GetDepositAt(tx, v.Address, parent)
+		if errors.Is(err, rawdb.ErrNoDeposit) || (nil == err && pub != v.PublicKey) {
+			return fmt.Errorf("%w: %s", consensus.ErrUnknownVerifier, v.Address)
+		} else if nil != err {
+			return err
+		}
+		if pubs[i], err = bls.PublicKeyFromBytes(v.PublicKey[:]); nil != err {
+			return fmt.Errorf("%w: %s, %v", consensus.ErrUnknownVerifier, v.Address, err)
+		}
+	}
+
+	sig, err := bls.SignatureFromBytes(header.Signature[:])
+	if nil != err {
+		return fmt.Errorf("%w: %v", consensus.ErrInvalidAggSign, err)
+	}
+	if !sig.FastAggregateVerify(pubs, header.Root) {
+		return consensus.ErrInvalidAggSign
+	}
+	return nil
+}
+
+// ProcessDeposits implements consensus.DepositProcessor, applying the events of
+// the configured deposit contract to the deposit registry.
+func (c *APos) ProcessDeposits(tx kv.RwTx, header block.IHeader, txs []*transaction.Transaction, receipts block.Receipts) error {
+	return deposit.ProcessDeposits(tx, c.depositContract(), header.Number64().Uint64(), txs, receipts)
+}
+
+// RebuildDeposits implements consensus.DepositProcessor.
+func (c *APos) RebuildDeposits(tx kv.RwTx) error {
+	return deposit.RebuildDeposits(tx, c.depositContract(), c.replayRewards)
+}
+
+// ReplayDeposits implements consensus.DepositProcessor. The verifiers of each
+// block are checked against the registry of its parent before their rewards
+// are set.
+func (c *APos) ReplayDeposits(tx kv.RwTx, head uint64) error {
+	if err := deposit.ResetDeposits(tx); nil != err {
+		return err
+	}
+	return deposit.ReplayDeposits(tx, c.depositContract(), 1, head, func(tx kv.RwTx, b *block.Block) error {
+		if c.chainConfig.IsBeijing(b.Number64().Uint64()) {
+			if err := c.VerifyAggSign(tx, b.Header(), b.Body().Verifier()); nil != err {
+				return fmt.Errorf("block %d: %w", b.Number64().Uint64(), err)
+			}
+		}
+		return c.replayRewards(tx, b)
+	})
+}
+
+// replayRewards sets the rewards of a replayed block the way Rewards sets them
+// when the block is executed.
+func (c *APos) replayRewards(tx kv.RwTx, b *block.Block) error {
+	number := b.Number64()
+	if !c.chainConfig.IsBeijing(number.Uint64()) || !c.isRewardBlock(number) {
+		return nil
+	}
+	_, err := newReward(c.config, c.chainConfig).SetRewards(tx, number, true)
+	return err
+}
+
+func (c *APos) depositContract() types.Address {
+	return types.HexToAddress(c.config.APos.DepositContract)
+}
+
 func (c *APos) IsServiceTransaction(sender types.Address, syscall consensus.SystemCall) bool {
 	return false
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/amazechain/amc/accounts/keystore"
 	"github.com/amazechain/amc/common/crypto"
 	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/conf"
 	"github.com/amazechain/amc/internal/avm/common"
 	"github.com/amazechain/amc/internal/avm/rlp"
 	mvm_types "github.com/amazechain/amc/internal/avm/types"
@@ -238,3 +239,23 @@ func TestReimportMirroredState(t *testing.T) {
 		t.Fatalf("chain head mismatch: have %d, want %d", head, 3)
 	}
 }
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		percent, min, deposits, want uint64
+	}{
+		{0, 1, 0, 1},
+		{0, 1, 100, 1},
+		{50, 1, 0, 1},
+		{50, 1, 7, 4},
+		{67, 1, 100, 67},
+		{67, 1, 10, 7},
+		{100, 3, 2, 3},
+	}
+	for i, tt := range tests {
+		c := New(&conf.ConsensusConfig{APos: &conf.APosConfig{VerifierQuorum: tt.percent, MinVerifiers: tt.min}}, nil, nil).(*APos)
+		if have := c.Quorum(tt.deposits); have != tt.want {
+			t.Errorf("test %d: quorum mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
@@ -202,7 +202,7 @@ func (r *Reward) buildRewards(tx kv.RwTx, number *uint256.Int, setRewards bool)
 
 			depositInfo, ok := depositeMap[verifier.Address]
 			if !ok {
-				depositInfo = deposit.GetDepositInfo(tx, verifier.Address)
+				depositInfo = deposit.GetDepositInfo(tx, verifier.Address, r.config.APos.DepositSchedules, number.Uint64())
 				if depositInfo == nil {
 					continue
 				}
@@ -210,6 +210,10 @@ func (r *Reward) buildRewards(tx kv.RwTx, number *uint256.Int, setRewards bool)
 				log.Debug("account deposite infos", "addr", verifier.Address, "perblock", depositInfo.RewardPerBlock, "perepoch", depositInfo.MaxRewardPerEpoch)
 			}
 
+			if depositInfo.RewardPerBlock.IsZero() {
+				continue
+			}
+
 			rewardMap[verifier.Address.Hex()] = math.Min256(addrReward.Add(addrReward, depositInfo.RewardPerBlock), depositInfo.MaxRewardPerEpoch)
 		}
 
@@ -143,6 +143,31 @@ type EngineReader interface {
 	Type() params.ConsensusType
 }
 
+// AggSignVerifier is implemented by consensus engines whose blocks carry an
+// aggregated BLS signature of the verifiers listed in the block body.
+type AggSignVerifier interface {
+	// VerifyAggSign checks that the header's aggregated signature was produced by
+	// the given verifiers, that every verifier holds a live deposit and that the
+	// set of verifiers meets the engine's quorum.
+	VerifyAggSign(tx kv.Tx, header block.IHeader, verifiers []*block.Verify) error
+}
+
+// DepositProcessor is implemented by consensus engines that keep a registry of
+// deposits derived from the events of an on-chain deposit contract.
+type DepositProcessor interface {
+	// ProcessDeposits applies the deposit events found in the receipts of a
+	// canonical block, recording enough history to unwind them on reorg.
+	ProcessDeposits(tx kv.RwTx, header block.IHeader, txs []*transaction.Transaction, receipts block.Receipts) error
+
+	// RebuildDeposits brings the registry up to date with the canonical chain.
+	RebuildDeposits(tx kv.RwTx) error
+
+	// ReplayDeposits rebuilds the registry, and the rewards derived from it,
+	// from the receipts of the canonical blocks up to head, checking the
+	// verifiers of every block against the registry it was executed on.
+	ReplayDeposits(tx kv.RwTx, head uint64) error
+}
+
 var (
 	SystemAddress = types.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE")
 )
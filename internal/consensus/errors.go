@@ -42,4 +42,23 @@ var (
 	ErrInvalidNumber = errors.New("invalid block number")
 	// ErrNotEnoughSign bls Sign
 	ErrNotEnoughSign = errors.New("not enough sign")
+
+	// ErrInvalidAggSign is returned if a block's aggregated BLS signature does
+	// not verify against the public keys of the verifiers listed in its body.
+	ErrInvalidAggSign = errors.New("invalid aggregated signature")
+
+	// ErrUnknownVerifier is returned if a block lists a verifier that has no live
+	// deposit, or whose public key differs from the deposited one.
+	ErrUnknownVerifier = errors.New("unknown verifier")
+
+	// ErrDuplicateVerifier is returned if a block lists the same verifier twice.
+	ErrDuplicateVerifier = errors.New("duplicate verifier")
 )
+
+// IsBadVerifiers reports whether err rejects the verifiers or the aggregated
+// signature of a block. Such a block is invalid on every node, so the peer
+// that sent it is faulty or malicious.
+func IsBadVerifiers(err error) bool {
+	return errors.Is(err, ErrInvalidAggSign) || errors.Is(err, ErrUnknownVerifier) ||
+		errors.Is(err, ErrDuplicateVerifier) || errors.Is(err, ErrNotEnoughSign)
+}
@@ -18,6 +18,7 @@ package download
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/amazechain/amc/utils"
 	"github.com/golang/protobuf/proto"
@@ -58,6 +59,7 @@ const (
 	syncPeerIntervalRequest = time.Duration(3 * time.Second)
 	syncPeerInfoTimeTick    = time.Duration(10 * time.Second)
 	maxDifferenceNumber     = 2
+	maxStateFetch           = 4096 // Get the number of state entries at a time
 )
 
 type headerResponse struct {
@@ -69,6 +71,7 @@ type headerResponse struct {
 type bodyResponse struct {
 	taskID uint64
 	ok     bool
+	peer   peer.ID
 	bodies []*types_pb.Block
 }
 
@@ -123,9 +126,20 @@ type Downloader struct {
 	bodyTaskPool        []*blockTask
 	bodyProcessingTasks map[uint64]*blockTask
 	bodyResultStore     map[uint256.Int]*types_pb.Block
+	bodyPeers           map[uint256.Int]peer.ID // sender of each downloaded block
+
+	// snap sync
+	tmpDir      string
+	snapshot    *stateSnapshot
+	stateProcCh chan *stateResponse
+
+	// sync progress
+	syncStatsLock        sync.RWMutex
+	syncStatsChainOrigin uint64 // Block number where the last sync began
+	syncStatsState       common.SyncProgress
 }
 
-func NewDownloader(ctx context.Context, bc common.IBlockChain, network common.INetwork, pubsub common.IPubSub, peers common.PeerMap) common.IDownloader {
+func NewDownloader(ctx context.Context, bc common.IBlockChain, network common.INetwork, pubsub common.IPubSub, peers common.PeerMap, mode SyncMode, tmpDir string) common.IDownloader {
 	c, cancel := context.WithCancel(ctx)
 
 	highestNumber := bc.CurrentBlock().Number64().Clone()
@@ -136,7 +150,7 @@ func NewDownloader(ctx context.Context, bc common.IBlockChain, network common.IN
 	}
 
 	return &Downloader{
-		mode:                  uint32(FullSync),
+		mode:                  uint32(mode),
 		bc:                    bc,
 		network:               network,
 		ctx:                   c,
@@ -152,8 +166,12 @@ func NewDownloader(ctx context.Context, bc common.IBlockChain, network common.IN
 		bodyTaskPool:          make([]*blockTask, 0),
 		bodyProcessingTasks:   make(map[uint64]*blockTask),
 		bodyResultStore:       make(map[uint256.Int]*types_pb.Block),
+		bodyPeers:             make(map[uint256.Int]peer.ID),
 		highestNumber:         *highestNumber,
 		peersInfo:             newPeersInfo(c, peers),
+		tmpDir:                tmpDir,
+		snapshot:              newStateSnapshot(bc.DB()),
+		stateProcCh:           make(chan *stateResponse, 10),
 	}
 }
 
@@ -212,6 +230,20 @@ func (d *Downloader) doSync(mode SyncMode) error {
 	}
 	defer atomic.StoreInt32(&d.isDownloading, 0)
 
+	d.syncStatsLock.Lock()
+	d.syncStatsChainOrigin = d.bc.CurrentBlock().Number64().Uint64()
+	d.syncStatsLock.Unlock()
+
+	if mode == SnapSync {
+		if err := d.snapSync(); err == ErrCanceled {
+			return err
+		} else if err != nil {
+			log.Warn("Snap sync failed, falling back to full sync", "err", err)
+		}
+		atomic.StoreUint32(&d.mode, uint32(FullSync))
+		mode = FullSync
+	}
+
 	// blockChain current block height
 	origin, err := d.findAncestor()
 	if err != nil {
@@ -282,6 +314,46 @@ func (d *Downloader) IsDownloading() bool {
 	return true
 }
 
+// Progress returns the progress of the current or last sync. The state
+// progress is only set by snap sync.
+func (d *Downloader) Progress() common.SyncProgress {
+	d.syncStatsLock.RLock()
+	defer d.syncStatsLock.RUnlock()
+
+	current := d.bc.CurrentBlock().Number64().Uint64()
+	highest := d.highestNumber.Uint64()
+	if highest < current {
+		highest = current
+	}
+	return common.SyncProgress{
+		StartingBlock: d.syncStatsChainOrigin,
+		CurrentBlock:  current,
+		HighestBlock:  highest,
+		PulledStates:  d.syncStatsState.PulledStates,
+		SyncedTables:  d.syncStatsState.SyncedTables,
+		KnownTables:   d.syncStatsState.KnownTables,
+	}
+}
+
+// highest returns the highest block number announced by any peer.
+func (d *Downloader) highest() uint256.Int {
+	d.syncStatsLock.RLock()
+	defer d.syncStatsLock.RUnlock()
+	return d.highestNumber
+}
+
+// updateHighest raises the highest known block number to number, reporting
+// whether it changed.
+func (d *Downloader) updateHighest(number *uint256.Int) bool {
+	d.syncStatsLock.Lock()
+	defer d.syncStatsLock.Unlock()
+	if number.Uint64() <= d.highestNumber.Uint64() {
+		return false
+	}
+	d.highestNumber.Set(number)
+	return true
+}
+
 func (d *Downloader) findAncestor() (uint256.Int, error) {
 	return *d.bc.CurrentBlock().Number64(), nil
 }
@@ -290,7 +362,7 @@ func (d *Downloader) findHead() (uint256.Int, error) {
 	//if d.highestNumber.IsEmpty {
 	//	return d.highestNumber, ErrSyncBlock
 	//}
-	return d.highestNumber, nil
+	return d.highest(), nil
 }
 
 func (d *Downloader) pubSubLoop() {
@@ -312,9 +384,8 @@ func (d *Downloader) pubSubLoop() {
 			log.Debugf("receive a err from highestSub %v", err)
 			return
 		case highestBlock, ok := <-highestBlockCh:
-			if ok && highestBlock.Block.Number64().Uint64() > d.highestNumber.Uint64() {
+			if ok && d.updateHighest(highestBlock.Block.Number64()) {
 				log.Debugf("receive a new highestBlock block number: %d", highestBlock.Block.Number64().Uint64())
-				d.highestNumber = *highestBlock.Block.Number64()
 				if highestBlock.Inserted {
 					d.peersInfo.peerInfoBroadcast(highestBlock.Block.Number64())
 				}
@@ -351,10 +422,11 @@ func (d *Downloader) synchronise() {
 			}
 			return
 		case <-tick.C:
-			difference := new(uint256.Int).Sub(&d.highestNumber, d.bc.CurrentBlock().Number64())
-			log.Tracef("highest: %d, current: %d", d.highestNumber.Uint64(), d.bc.CurrentBlock().Number64().Uint64())
+			highest := d.highest()
+			difference := new(uint256.Int).Sub(&highest, d.bc.CurrentBlock().Number64())
+			log.Tracef("highest: %d, current: %d", highest.Uint64(), d.bc.CurrentBlock().Number64().Uint64())
 			if difference.Uint64() > 1 {
-				log.Infof("start downloader Compare Loop remote  highestNumber: %d, current number: %d, difference: %d", d.highestNumber.Uint64(), d.bc.CurrentBlock().Number64().Uint64(), difference.Uint64())
+				log.Infof("start downloader Compare Loop remote  highestNumber: %d, current number: %d, difference: %d", highest.Uint64(), d.bc.CurrentBlock().Number64().Uint64(), difference.Uint64())
 				err := d.doSync(d.getMode())
 				if err != nil {
 					log.Errorf("failed to running downloader, err:%v", err)
@@ -412,14 +484,31 @@ func (d *Downloader) ConnHandler(data []byte, ID peer.ID) error {
 
 	case sync_proto.SyncType_BodyRes:
 		bodiesResponse := syncTask.Payload.(*sync_proto.SyncTask_SyncBlockResponse).SyncBlockResponse
-		params = append(params, "blocksCount", len(bodiesResponse.Blocks), "bodyNumberFrom", utils.ConvertH256ToUint256Int(bodiesResponse.Blocks[0].Header.Number).Uint64(), "bodyNumberTo", utils.ConvertH256ToUint256Int(bodiesResponse.Blocks[len(bodiesResponse.Blocks)-1].Header.Number).Uint64())
-		d.blockProcCh <- &bodyResponse{taskID: taskID, ok: syncTask.Ok, bodies: bodiesResponse.Blocks}
+		params = append(params, "blocksCount", len(bodiesResponse.Blocks))
+		if len(bodiesResponse.Blocks) > 0 {
+			params = append(params, "bodyNumberFrom", utils.ConvertH256ToUint256Int(bodiesResponse.Blocks[0].Header.Number).Uint64(), "bodyNumberTo", utils.ConvertH256ToUint256Int(bodiesResponse.Blocks[len(bodiesResponse.Blocks)-1].Header.Number).Uint64())
+		}
+		d.blockProcCh <- &bodyResponse{taskID: taskID, ok: syncTask.Ok, peer: ID, bodies: bodiesResponse.Blocks}
 
 	case sync_proto.SyncType_BodyReq:
 		blockRequest := syncTask.Payload.(*sync_proto.SyncTask_SyncBlockRequest).SyncBlockRequest
 		params = append(params, "bodyNumberFrom", utils.ConvertH256ToUint256Int(blockRequest.Number[0]).Uint64(), "bodyNumberTo", utils.ConvertH256ToUint256Int(blockRequest.Number[len(blockRequest.Number)-1]).Uint64())
 		go d.responseBlocks(taskID, p, blockRequest)
 
+	case sync_proto.SyncType_StateRes:
+		stateResp := syncTask.Payload.(*sync_proto.SyncTask_SyncStateResponse).SyncStateResponse
+		params = append(params, "table", stateResp.Table, "count", len(stateResp.Keys))
+		select {
+		case d.stateProcCh <- &stateResponse{taskID: taskID, ok: syncTask.Ok, state: stateResp}:
+		default:
+			log.Debug("Dropped unexpected state response", "peerID", ID, "taskID", taskID)
+		}
+
+	case sync_proto.SyncType_StateReq:
+		stateRequest := syncTask.Payload.(*sync_proto.SyncTask_SyncStateRequest).SyncStateRequest
+		params = append(params, "table", stateRequest.Table)
+		go d.responseState(taskID, p, stateRequest)
+
 	case sync_proto.SyncType_PeerInfoBroadcast:
 		peerInfoBroadcast := syncTask.Payload.(*sync_proto.SyncTask_SyncPeerInfoBroadcast).SyncPeerInfoBroadcast
 		//
@@ -427,9 +516,7 @@ func (d *Downloader) ConnHandler(data []byte, ID peer.ID) error {
 		currentDifficulty := utils.ConvertH256ToUint256Int(peerInfoBroadcast.Difficulty)
 		params = append(params, "Number", currentNumber, "Difficulty", currentDifficulty)
 		//
-		if currentNumber.Uint64() > d.highestNumber.Uint64() {
-			d.highestNumber.Set(currentNumber.Clone())
-		}
+		d.updateHighest(currentNumber)
 		d.peersInfo.update(p.ID(), currentNumber, currentDifficulty)
 	}
 
@@ -443,5 +530,14 @@ func (d *Downloader) Close() error {
 	defer d.cancelLock.Unlock()
 	d.cancel()
 	d.cancelWg.Wait()
+	d.snapshot.close()
 	return nil
 }
+
+// reportBadPeer bans the peer if it failed a request with an invalid
+// response.
+func (d *Downloader) reportBadPeer(id peer.ID, err error) {
+	if errors.Is(err, ErrBadPeer) {
+		d.network.BanPeer(id, err.Error())
+	}
+}
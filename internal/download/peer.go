@@ -69,6 +69,27 @@ func (p *peersInfo) findPeers(number *uint256.Int, count int) common.PeerSet {
 	return set
 }
 
+// bestPeer returns the connected peer at the highest block among the peers
+// announcing the sync capability.
+func (p *peersInfo) bestPeer(capability string) (common.Peer, *uint256.Int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var (
+		best   common.Peer
+		number = uint256.NewInt(0)
+	)
+	for id, peer := range p.peers {
+		if !peer.HasCapability(capability) {
+			continue
+		}
+		if info, ok := p.info[id]; ok && info.Number != nil && info.Number.Cmp(number) > 0 {
+			best, number = peer, info.Number
+		}
+	}
+	return best, number
+}
+
 func (p peersInfo) get(id peer.ID) (common.Peer, bool) {
 	peer, ok := p.peers[id]
 	return peer, ok
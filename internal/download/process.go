@@ -17,13 +17,16 @@
 package download
 
 import (
+	"fmt"
 	"github.com/amazechain/amc/utils"
 	"github.com/holiman/uint256"
 	"math/rand"
 	"time"
 
 	block2 "github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/internal/consensus"
 	"github.com/amazechain/amc/log"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 func (d *Downloader) processHeaders() error {
@@ -124,7 +127,9 @@ func (d *Downloader) processBodies() error {
 
 			log.Debugf("received block from remote peers  , the block counts is  %v", len(response.bodies))
 			for _, body := range response.bodies {
-				d.bodyResultStore[*utils.ConvertH256ToUint256Int(body.Header.Number)] = body
+				number := *utils.ConvertH256ToUint256Int(body.Header.Number)
+				d.bodyResultStore[number] = body
+				d.bodyPeers[number] = response.peer
 			}
 			d.bodyTaskPoolLock.Unlock()
 		case <-tick.C:
@@ -161,6 +166,7 @@ func (d *Downloader) processChain() error {
 			log.Tracef("want block %d have blocks count is %d", wantBlockNumber.Uint64(), len(d.bodyResultStore))
 
 			blocks := make([]block2.IBlock, 0)
+			senders := make([]peer.ID, 0)
 			for i := 0; i < maxResultsProcess; i++ {
 				if blockMsg, ok := d.bodyResultStore[*wantBlockNumber]; ok {
 					var block block2.Block
@@ -171,6 +177,8 @@ func (d *Downloader) processChain() error {
 					}
 					delete(d.bodyResultStore, *wantBlockNumber)
 					blocks = append(blocks, &block)
+					senders = append(senders, d.bodyPeers[*wantBlockNumber])
+					delete(d.bodyPeers, *wantBlockNumber)
 				}
 				wantBlockNumber.AddUint64(wantBlockNumber, 1)
 			}
@@ -190,6 +198,9 @@ func (d *Downloader) processChain() error {
 				//inserted = false
 				if index < len(blocks) {
 					log.Errorf("downloader failed to inster new block in blockchain, err:%v", err)
+					if consensus.IsBadVerifiers(err) {
+						d.reportBadPeer(senders[index], fmt.Errorf("%w: %v", ErrBadPeer, err))
+					}
 				} else {
 				}
 				d.bodyTaskPoolLock.Unlock()
@@ -118,3 +118,36 @@ func (d *Downloader) responseBlocks(taskID uint64, p common.Peer, task *sync_pro
 	p.WriteMsg(message.MsgDownloader, payload)
 	log.Debugf("response sync task(blockRequest) ok: %v , taskID: %v, block count: %v", ok, taskID, len(task.Number))
 }
+
+// responseState serves a range of the state snapshot
+func (d *Downloader) responseState(taskID uint64, p common.Peer, task *sync_proto.SyncStateRequest) {
+
+	ok := true
+	res, err := d.snapshot.serve(task)
+	if err != nil {
+		log.Debugf("cannot serve state table %s, err: %v", task.Table, err)
+		ok = false
+		res = &sync_proto.SyncStateResponse{
+			Number: task.Number,
+			Table:  task.Table,
+		}
+	}
+
+	msg := &sync_proto.SyncTask{
+		Id:       taskID,
+		Ok:       ok,
+		SyncType: sync_proto.SyncType_StateRes,
+		Payload: &sync_proto.SyncTask_SyncStateResponse{
+			SyncStateResponse: res,
+		},
+	}
+	payload, err := proto.Marshal(msg)
+
+	if err != nil {
+		log.Errorf("proto Marshal err: %v", err)
+		return
+	}
+
+	p.WriteMsg(message.MsgDownloader, payload)
+	log.Debugf("response sync task(stateRequest) ok: %v , taskID: %v, table: %v, count: %v", ok, taskID, task.Table, len(res.Keys))
+}
@@ -0,0 +1,615 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/amazechain/amc/api/protocol/sync_proto"
+	"github.com/amazechain/amc/api/protocol/types_pb"
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/common/account"
+	block2 "github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/crypto"
+	"github.com/amazechain/amc/common/hashing"
+	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/internal/consensus"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/amazechain/amc/modules/state"
+	"github.com/amazechain/amc/utils"
+	"github.com/c2h5oh/datasize"
+	"github.com/golang/protobuf/proto"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+)
+
+var (
+	ErrPivotMoved        = fmt.Errorf("state pivot moved")
+	ErrInvalidChain      = fmt.Errorf("downloaded chain does not link to genesis")
+	ErrNoMerkleState     = fmt.Errorf("state before the merkle fork cannot be verified")
+	ErrStateRootMismatch = fmt.Errorf("downloaded state does not match the pivot state root")
+	ErrReceiptsMismatch  = fmt.Errorf("downloaded receipts do not match the receipt root")
+)
+
+const (
+	snapSyncMinDistance = 1024 // Distance to the highest peer below which the chain is executed instead
+	snapSyncAttempts    = 3    // Number of pivots tried before falling back to full sync
+	snapStagingMapSize  = 1 * datasize.TB
+)
+
+type stateResponse struct {
+	taskID uint64
+	ok     bool
+	state  *sync_proto.SyncStateResponse
+}
+
+// snapSync brings a fresh node close to the head of the chain by downloading
+// the state at a recent pivot block instead of executing every block up to it.
+// It does nothing if the node already has blocks or the chain is short.
+func (d *Downloader) snapSync() error {
+	current := d.bc.CurrentBlock().Number64().Uint64()
+	highest := d.highest()
+	if current != 0 || highest.Uint64() < snapSyncMinDistance {
+		log.Info("Skipping snap sync", "current", current, "highest", highest.Uint64())
+		return nil
+	}
+	// Only the state roots from the merkle fork on commit to the whole state
+	if !d.bc.Config().IsMerkle(highest.Uint64()) {
+		log.Info("Skipping snap sync before the merkle fork", "highest", highest.Uint64())
+		return nil
+	}
+
+	var err error
+	for i := 0; i < snapSyncAttempts; i++ {
+		if err = d.syncState(); nil == err || errors.Is(err, ErrCanceled) {
+			return err
+		}
+		log.Warn("Snap sync attempt failed", "attempt", i+1, "err", err)
+	}
+	return err
+}
+
+// syncState makes one attempt at a snap sync:
+//
+//  1. the state tables at the pivot block and the receipts of the blocks up to
+//     it are downloaded from a single peer into a staging database,
+//  2. the blocks up to the pivot are downloaded, checked to link up with the
+//     local genesis block and their headers verified by the consensus engine,
+//  3. the state is imported and its trie built, the receipts are checked
+//     against the headers and the deposit registry is replayed from them, and
+//     the pivot block becomes the head of the chain if the root of the trie is
+//     the pivot's state root.
+//
+// The import is a single transaction, a state failing to verify leaves the
+// node at its genesis state.
+func (d *Downloader) syncState() error {
+	p, number := d.peersInfo.bestPeer(common.CapabilitySnap)
+	if p.IPeer == nil {
+		return ErrNoPeers
+	}
+	log.Info("Starting snap sync", "peer", p.ID(), "peerNumber", number.Uint64())
+
+	staging, err := mdbx.NewMDBX(nil).InMem(d.tmpDir).MapSize(snapStagingMapSize).Open()
+	if nil != err {
+		return err
+	}
+	defer staging.Close()
+
+	pivot, err := d.fetchState(p, staging)
+	if nil != err {
+		d.reportBadPeer(p.ID(), err)
+		return err
+	}
+	if !d.bc.Config().IsMerkle(pivot) {
+		return fmt.Errorf("%w: pivot %d", ErrNoMerkleState, pivot)
+	}
+	pivotBlock, err := d.fetchPivotChain(pivot, staging)
+	if nil != err {
+		return err
+	}
+	if err := d.importState(staging, pivotBlock); nil != err {
+		if errors.Is(err, ErrStateRootMismatch) || errors.Is(err, ErrReceiptsMismatch) {
+			d.reportBadPeer(p.ID(), fmt.Errorf("%w: %v", ErrBadPeer, err))
+		}
+		return err
+	}
+	log.Info("Snap sync finished", "pivot", pivot, "hash", pivotBlock.Hash())
+	return nil
+}
+
+// fetchState downloads the state tables at the pivot block of the peer's state
+// snapshot and the receipts of the blocks up to it, and returns the pivot.
+func (d *Downloader) fetchState(p common.Peer, staging kv.RwDB) (uint64, error) {
+	tables := append(append([]string{}, snapStateTables...), snapChainTables...)
+	d.syncStatsLock.Lock()
+	d.syncStatsState = common.SyncProgress{KnownTables: uint64(len(tables))}
+	d.syncStatsLock.Unlock()
+
+	var pivot uint64
+	for _, table := range tables {
+		var origin []byte
+		for {
+			res, err := d.requestState(p, pivot, table, origin)
+			if nil != err {
+				return 0, err
+			}
+			if pivot == 0 {
+				pivot = utils.ConvertH256ToUint256Int(res.Number).Uint64()
+				log.Info("Downloading state", "peer", p.ID(), "pivot", pivot)
+			}
+			if err := staging.Update(d.ctx, func(tx kv.RwTx) error {
+				return putStateRange(tx, table, res)
+			}); nil != err {
+				return 0, err
+			}
+			log.Debug("Downloaded state range", "table", table, "count", len(res.Keys))
+			d.syncStatsLock.Lock()
+			d.syncStatsState.PulledStates += uint64(len(res.Keys))
+			d.syncStatsLock.Unlock()
+			if len(res.Next) == 0 {
+				break
+			}
+			// The chain tables are keyed by block number, the blocks above
+			// the pivot are not needed
+			if number, err := modules.DecodeBlockNumber(res.Next); table == modules.Receipts && nil == err && number > pivot {
+				break
+			}
+			origin = res.Next
+		}
+		d.syncStatsLock.Lock()
+		d.syncStatsState.SyncedTables++
+		d.syncStatsLock.Unlock()
+	}
+	return pivot, nil
+}
+
+func putStateRange(tx kv.RwTx, table string, res *sync_proto.SyncStateResponse) error {
+	for i, k := range res.Keys {
+		// Contract code is not part of the state trie, it is checked against
+		// the code hash it is stored by
+		if table == modules.Code && crypto.Keccak256Hash(res.Values[i]) != types.BytesToHash(k) {
+			return fmt.Errorf("%w: code of hash %x does not match", ErrBadPeer, k)
+		}
+		if err := tx.Put(table, k, res.Values[i]); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestState asks the peer for a range of a state table and waits for the
+// answer. A zero pivot accepts whichever block the peer's snapshot is at.
+func (d *Downloader) requestState(p common.Peer, pivot uint64, table string, origin []byte) (*sync_proto.SyncStateResponse, error) {
+	taskID := rand.Uint64()
+	msg := &sync_proto.SyncTask{
+		Id:       taskID,
+		SyncType: sync_proto.SyncType_StateReq,
+		Payload: &sync_proto.SyncTask_SyncStateRequest{
+			SyncStateRequest: &sync_proto.SyncStateRequest{
+				Number: utils.ConvertUint256IntToH256(uint256.NewInt(pivot)),
+				Table:  table,
+				Origin: origin,
+				Limit:  maxStateFetch,
+			},
+		},
+	}
+	payload, _ := proto.Marshal(msg)
+	if err := p.WriteMsg(message.MsgDownloader, payload); nil != err {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(syncTimeOutPerRequest)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return nil, ErrCanceled
+		case <-timeout.C:
+			return nil, ErrTimeout
+		case res := <-d.stateProcCh:
+			if res.taskID != taskID {
+				continue
+			}
+			if !res.ok {
+				return nil, ErrStateUnavailable
+			}
+			if !validH256(res.state.Number) || res.state.Table != table || len(res.state.Keys) != len(res.state.Values) {
+				return nil, ErrBadPeer
+			}
+			if number := utils.ConvertH256ToUint256Int(res.state.Number).Uint64(); pivot != 0 && number != pivot {
+				return nil, ErrPivotMoved
+			}
+			return res.state, nil
+		}
+	}
+}
+
+// fetchPivotChain downloads the blocks up to the pivot into the staging
+// database, verifying their headers, and returns the pivot block.
+func (d *Downloader) fetchPivotChain(pivot uint64, staging kv.RwDB) (block2.IBlock, error) {
+	genesis := d.bc.GenesisBlock()
+	parent := genesis.Hash()
+	td := d.bc.GetTd(parent, genesis.Number64())
+	if td == nil {
+		return nil, fmt.Errorf("unknown genesis total difficulty")
+	}
+	td = td.Clone()
+	chain := &stagingChain{IBlockChain: d.bc, ctx: d.ctx, staging: staging}
+
+	log.Info("Downloading blocks up to the state pivot", "pivot", pivot)
+	var pivotBlock block2.IBlock
+	for from := uint64(1); from <= pivot; from += maxBodiesFetch {
+		to := from + maxBodiesFetch - 1
+		if to > pivot {
+			to = pivot
+		}
+		blocks, err := d.requestBlocks(from, to)
+		if nil != err {
+			return nil, err
+		}
+		headers := make([]block2.IHeader, len(blocks))
+		for i, b := range blocks {
+			if b.ParentHash() != parent {
+				return nil, ErrInvalidChain
+			}
+			parent = b.Hash()
+			headers[i] = b.Header()
+		}
+		if err := d.verifyHeaders(chain, headers); nil != err {
+			return nil, err
+		}
+
+		if err := staging.Update(d.ctx, func(tx kv.RwTx) error {
+			for _, b := range blocks {
+				td.Add(td, b.Difficulty())
+				if err := rawdb.WriteBlock(tx, b); nil != err {
+					return err
+				}
+				if err := rawdb.WriteTd(tx, b.Hash(), b.Number64().Uint64(), td); nil != err {
+					return err
+				}
+				if err := rawdb.WriteCanonicalHash(tx, b.Hash(), b.Number64().Uint64()); nil != err {
+					return err
+				}
+			}
+			return nil
+		}); nil != err {
+			return nil, err
+		}
+		pivotBlock = blocks[len(blocks)-1]
+		log.Debug("Downloaded blocks", "from", from, "to", to)
+	}
+	return pivotBlock, nil
+}
+
+// verifyHeaders checks a batch of consecutive downloaded headers, seals
+// included, against the consensus rules.
+func (d *Downloader) verifyHeaders(chain consensus.ChainHeaderReader, headers []block2.IHeader) error {
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	abort, results := d.bc.Engine().VerifyHeaders(chain, headers, seals)
+	defer close(abort)
+	for _, header := range headers {
+		select {
+		case <-d.ctx.Done():
+			return ErrCanceled
+		case err := <-results:
+			if nil != err {
+				return fmt.Errorf("%w: invalid header %d: %v", ErrBadPeer, header.Number64().Uint64(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// stagingChain reads the headers of the chain downloaded into the staging
+// database, and of the local chain below it, for header verification.
+type stagingChain struct {
+	common.IBlockChain
+	ctx     context.Context
+	staging kv.RoDB
+}
+
+func (c *stagingChain) readHeader(f func(tx kv.Tx) *block2.Header) block2.IHeader {
+	var header *block2.Header
+	if err := c.staging.View(c.ctx, func(tx kv.Tx) error {
+		header = f(tx)
+		return nil
+	}); nil != err || header == nil {
+		return nil
+	}
+	return header
+}
+
+func (c *stagingChain) GetHeader(hash types.Hash, number *uint256.Int) block2.IHeader {
+	if header := c.readHeader(func(tx kv.Tx) *block2.Header {
+		return rawdb.ReadHeader(tx, hash, number.Uint64())
+	}); header != nil {
+		return header
+	}
+	return c.IBlockChain.GetHeader(hash, number)
+}
+
+func (c *stagingChain) GetHeaderByNumber(number *uint256.Int) block2.IHeader {
+	if header := c.readHeader(func(tx kv.Tx) *block2.Header {
+		hash, err := rawdb.ReadCanonicalHash(tx, number.Uint64())
+		if nil != err || hash == (types.Hash{}) {
+			return nil
+		}
+		return rawdb.ReadHeader(tx, hash, number.Uint64())
+	}); header != nil {
+		return header
+	}
+	return c.IBlockChain.GetHeaderByNumber(number)
+}
+
+func (c *stagingChain) GetHeaderByHash(hash types.Hash) (block2.IHeader, error) {
+	if header := c.readHeader(func(tx kv.Tx) *block2.Header {
+		header, _ := rawdb.ReadHeaderByHash(tx, hash)
+		return header
+	}); header != nil {
+		return header, nil
+	}
+	return c.IBlockChain.GetHeaderByHash(hash)
+}
+
+func (c *stagingChain) GetTd(hash types.Hash, number *uint256.Int) *uint256.Int {
+	var td *uint256.Int
+	if err := c.staging.View(c.ctx, func(tx kv.Tx) error {
+		var err error
+		td, err = rawdb.ReadTd(tx, hash, number.Uint64())
+		return err
+	}); nil == err && td != nil {
+		return td
+	}
+	return c.IBlockChain.GetTd(hash, number)
+}
+
+// requestBlocks downloads the blocks in [from, to], trying the peers that have
+// them in turn.
+func (d *Downloader) requestBlocks(from, to uint64) ([]*block2.Block, error) {
+	numbers := make([]*types_pb.H256, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, utils.ConvertUint256IntToH256(uint256.NewInt(n)))
+	}
+
+	err := ErrNoPeers
+	for _, p := range d.peersInfo.findPeers(uint256.NewInt(to), syncPeerCount) {
+		var blocks []*block2.Block
+		if blocks, err = d.requestBlocksFrom(p, from, numbers); nil == err {
+			return blocks, nil
+		}
+		if errors.Is(err, ErrCanceled) {
+			return nil, err
+		}
+		d.reportBadPeer(p.ID(), err)
+		log.Debug("Failed to download blocks", "peer", p.ID(), "from", from, "to", to, "err", err)
+	}
+	return nil, err
+}
+
+func (d *Downloader) requestBlocksFrom(p common.Peer, from uint64, numbers []*types_pb.H256) ([]*block2.Block, error) {
+	taskID := rand.Uint64()
+	msg := &sync_proto.SyncTask{
+		Id:       taskID,
+		SyncType: sync_proto.SyncType_BodyReq,
+		Payload: &sync_proto.SyncTask_SyncBlockRequest{
+			SyncBlockRequest: &sync_proto.SyncBlockRequest{
+				Number: numbers,
+			},
+		},
+	}
+	payload, _ := proto.Marshal(msg)
+	if err := p.WriteMsg(message.MsgDownloader, payload); nil != err {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(syncTimeOutPerRequest)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return nil, ErrCanceled
+		case <-timeout.C:
+			return nil, ErrTimeout
+		case res := <-d.blockProcCh:
+			if res.taskID != taskID {
+				continue
+			}
+			if !res.ok || len(res.bodies) != len(numbers) {
+				return nil, ErrSyncBlock
+			}
+			blocks := make([]*block2.Block, 0, len(res.bodies))
+			for i, body := range res.bodies {
+				var b block2.Block
+				if err := b.FromProtoMessage(body); nil != err {
+					return nil, fmt.Errorf("%w: %v", ErrBadPeer, err)
+				}
+				if b.Number64().Uint64() != from+uint64(i) {
+					return nil, ErrBadPeer
+				}
+				if hashing.DeriveSha(transaction.Transactions(b.Transactions())) != b.TxHash() {
+					return nil, fmt.Errorf("%w: transaction root mismatch in block %d", ErrBadPeer, b.Number64().Uint64())
+				}
+				blocks = append(blocks, &b)
+			}
+			return blocks, nil
+		}
+	}
+}
+
+// snapImportTables are the tables replaced by a snap sync import: the state
+// tables, the state derived from the receipts and the history and state tries
+// of the genesis state.
+func snapImportTables() []string {
+	return append(append([]string{}, snapStateTables...),
+		modules.IncarnationMap,
+		modules.AccountChangeSet,
+		modules.StorageChangeSet,
+		modules.DepositChangeSet,
+		modules.DepositHistory,
+		modules.AccountsHistory,
+		modules.StorageHistory,
+		modules.TrieNode,
+		modules.TrieNodeRefs,
+		modules.TrieRoot,
+	)
+}
+
+// importState replaces the genesis state with the downloaded one and makes the
+// downloaded chain up to the pivot canonical, with the pivot block as head.
+func (d *Downloader) importState(staging kv.RoDB, pivotBlock block2.IBlock) error {
+	log.Info("Importing snap synced state", "pivot", pivotBlock.Number64().Uint64())
+	return d.bc.ResetHead(func(tx kv.RwTx) (block2.IBlock, error) {
+		if !d.bc.CurrentBlock().Number64().IsZero() {
+			return nil, fmt.Errorf("chain advanced to %d during snap sync", d.bc.CurrentBlock().Number64().Uint64())
+		}
+		dp, _ := d.bc.Engine().(consensus.DepositProcessor)
+		if err := staging.View(d.ctx, func(stx kv.Tx) error {
+			return importStagedState(tx, stx, pivotBlock, dp)
+		}); nil != err {
+			return nil, err
+		}
+		return pivotBlock, nil
+	})
+}
+
+// importStagedState copies the state and the chain up to the pivot block from
+// the staging database. The state trie is built from the imported state, the
+// import fails unless its root is the state root of the pivot block. The
+// receipts of every block must match its receipt root, the deposit registry of
+// dp is then replayed from them.
+func importStagedState(tx kv.RwTx, stx kv.Tx, pivotBlock block2.IBlock, dp consensus.DepositProcessor) error {
+	pivot := pivotBlock.Number64().Uint64()
+	for _, table := range snapImportTables() {
+		if err := tx.ClearBucket(table); nil != err {
+			return err
+		}
+	}
+	if err := importAccounts(tx, stx); nil != err {
+		return err
+	}
+
+	root, err := state.BuildStateTrie(tx, pivot)
+	if nil != err {
+		return err
+	}
+	if root != pivotBlock.StateRoot() {
+		return fmt.Errorf("%w: have %x, want %x", ErrStateRootMismatch, root, pivotBlock.StateRoot())
+	}
+
+	for n := uint64(1); n <= pivot; n++ {
+		hash, err := rawdb.ReadCanonicalHash(stx, n)
+		if nil != err {
+			return err
+		}
+		b := rawdb.ReadBlock(stx, hash, n)
+		if b == nil {
+			return fmt.Errorf("missing downloaded block %d", n)
+		}
+		td, err := rawdb.ReadTd(stx, hash, n)
+		if nil != err {
+			return err
+		}
+		receipts := rawdb.ReadRawReceipts(stx, n)
+		if len(receipts) != len(b.Transactions()) || hashing.DeriveSha(receipts) != b.Header().(*block2.Header).ReceiptHash {
+			return fmt.Errorf("%w: block %d", ErrReceiptsMismatch, n)
+		}
+		if err := rawdb.WriteBlock(tx, b); nil != err {
+			return err
+		}
+		if err := rawdb.WriteTd(tx, hash, n, td); nil != err {
+			return err
+		}
+		if err := rawdb.WriteCanonicalHash(tx, hash, n); nil != err {
+			return err
+		}
+		rawdb.WriteTxLookupEntries(tx, b)
+		if err := rawdb.WriteReceipts(tx, n, receipts); nil != err {
+			return err
+		}
+		if err := rawdb.WriteLogIndex(tx, n); nil != err {
+			return err
+		}
+	}
+
+	if dp != nil {
+		if err := dp.ReplayDeposits(tx, pivot); nil != err {
+			return err
+		}
+	}
+	// The downloaded state is the state after the pivot block. The blocks up
+	// to the pivot are not executed, so their change sets are never stored.
+	if err := rawdb.WriteDepositProgress(tx, pivot); nil != err {
+		return err
+	}
+	return rawdb.WritePruneBoundary(tx, rawdb.PruneKindHistory, pivot+1)
+}
+
+// importAccounts copies the downloaded accounts and contract code. The storage
+// and code hashes are only copied for the current incarnation of an account,
+// the only one the state root commits to.
+func importAccounts(tx kv.RwTx, stx kv.Tx) error {
+	if err := copyTable(tx, stx, modules.Code); nil != err {
+		return err
+	}
+	return stx.ForEach(modules.Account, nil, func(k, v []byte) error {
+		var acc account.StateAccount
+		if err := acc.DecodeForStorage(v); nil != err {
+			return fmt.Errorf("%w: %v", ErrStateRootMismatch, err)
+		}
+		if err := tx.Put(modules.Account, k, v); nil != err {
+			return err
+		}
+		if acc.Incarnation == 0 {
+			return nil
+		}
+		storage := modules.PlainGenerateCompositeStorageKey(k, acc.Incarnation, nil)[:types.AddressLength+types.IncarnationLength]
+		if err := stx.ForPrefix(modules.Storage, storage, func(k, v []byte) error {
+			return tx.Put(modules.Storage, k, v)
+		}); nil != err {
+			return err
+		}
+		code := modules.PlainGenerateStoragePrefix(k, acc.Incarnation)
+		codeHash, err := stx.GetOne(modules.PlainContractCode, code)
+		if nil != err || len(codeHash) == 0 {
+			return err
+		}
+		return tx.Put(modules.PlainContractCode, code, codeHash)
+	})
+}
+
+func copyTable(dst kv.RwTx, src kv.Tx, table string) error {
+	return src.ForEach(table, nil, func(k, v []byte) error {
+		return dst.Put(table, k, v)
+	})
+}
+
+func validH256(h *types_pb.H256) bool {
+	return h != nil && h.Hi != nil && h.Lo != nil
+}
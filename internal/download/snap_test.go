@@ -0,0 +1,139 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package download
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/hashing"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/amazechain/amc/modules/state"
+	"github.com/amazechain/amc/params"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+// writeSnapState writes the state after block 1 to tx and returns its root,
+// computed on the state trie the way block 1 is executed.
+func writeSnapState(t *testing.T, tx kv.RwTx) types.Hash {
+	t.Helper()
+	slot := types.Hash{0x01}
+	rules := &params.Rules{}
+	ibs := state.New(state.NewPlainStateReader(tx))
+	ibs.UseStateTrie(tx, 1, state.EmptyTrieRoot, rules)
+	ibs.AddBalance(types.Address{0x0a}, uint256.NewInt(10))
+	ibs.CreateAccount(types.Address{0x0b}, true)
+	ibs.SetNonce(types.Address{0x0b}, 1)
+	ibs.SetState(types.Address{0x0b}, &slot, *uint256.NewInt(1))
+	if err := ibs.CommitBlock(rules, state.NewPlainStateWriterNoHistory(tx)); err != nil {
+		t.Fatal(err)
+	}
+	root := ibs.IntermediateRoot()
+	if err := ibs.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// writeSnapBlock stores a canonical block 1 without transactions with the
+// given state and receipt roots.
+func writeSnapBlock(t *testing.T, tx kv.RwTx, root, receiptHash types.Hash) *block.Block {
+	t.Helper()
+	header := &block.Header{
+		Number:      uint256.NewInt(1),
+		Difficulty:  uint256.NewInt(1),
+		BaseFee:     uint256.NewInt(0),
+		Root:        root,
+		ReceiptHash: receiptHash,
+	}
+	b := block.NewBlock(header, nil).(*block.Block)
+	if err := rawdb.WriteBlock(tx, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := rawdb.WriteTd(tx, b.Hash(), 1, uint256.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rawdb.WriteCanonicalHash(tx, b.Hash(), 1); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestImportStagedState(t *testing.T) {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+
+	// The expected root comes from a chain that executed the block
+	_, etx := memdb.NewTestTx(t)
+	root := writeSnapState(t, etx)
+
+	// The downloaded state holds the same accounts without any trie
+	_, stx := memdb.NewTestTx(t)
+	if err := copyTable(stx, etx, modules.Account); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyTable(stx, etx, modules.Storage); err != nil {
+		t.Fatal(err)
+	}
+	// Storage of an earlier incarnation is not committed to by the state root
+	stale := modules.PlainGenerateCompositeStorageKey(types.Address{0x0b}.Bytes(), 5, types.Hash{0x01}.Bytes())
+	if err := stx.Put(modules.Storage, stale, []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A state that does not match the pivot header is refused
+	receiptHash := hashing.DeriveSha(block.Receipts(nil))
+	_, tx := memdb.NewTestTx(t)
+	bad := writeSnapBlock(t, stx, types.Hash{0x01}, receiptHash)
+	if err := importStagedState(tx, stx, bad, nil); !errors.Is(err, ErrStateRootMismatch) {
+		t.Fatalf("have %v, want %v", err, ErrStateRootMismatch)
+	}
+
+	// So are receipts that do not match the receipt root
+	_, tx = memdb.NewTestTx(t)
+	bad = writeSnapBlock(t, stx, root, types.Hash{0x01})
+	if err := importStagedState(tx, stx, bad, nil); !errors.Is(err, ErrReceiptsMismatch) {
+		t.Fatalf("have %v, want %v", err, ErrReceiptsMismatch)
+	}
+
+	_, tx = memdb.NewTestTx(t)
+	good := writeSnapBlock(t, stx, root, receiptHash)
+	if err := importStagedState(tx, stx, good, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := rawdb.ReadCanonicalHash(tx, 1); err != nil || hash != good.Hash() {
+		t.Errorf("canonical hash %x (err %v), want %x", hash, err, good.Hash())
+	}
+	if boundary, err := rawdb.ReadPruneBoundary(tx, rawdb.PruneKindHistory); err != nil || boundary != 2 {
+		t.Errorf("history boundary %d (err %v), want 2", boundary, err)
+	}
+	if !rawdb.HasReceipts(tx, 1) {
+		t.Error("receipts of block 1 not imported")
+	}
+	if v, err := tx.GetOne(modules.Storage, stale); err != nil || v != nil {
+		t.Errorf("storage of an earlier incarnation imported: %x (err %v)", v, err)
+	}
+	s := state.New(state.NewPlainStateReader(tx))
+	if have := s.GetBalance(types.Address{0x0a}).Uint64(); have != 10 {
+		t.Errorf("balance %d, want 10", have)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package download
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/amazechain/amc/api/protocol/sync_proto"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/amazechain/amc/utils"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+var (
+	ErrStateUnavailable = fmt.Errorf("state snapshot unavailable")
+	ErrUnknownTable     = fmt.Errorf("unknown state table")
+)
+
+const (
+	stateResponseSoftLimit = 2 * 1024 * 1024 // Size of the state entries served in one response
+	stateSnapshotIdle      = 2 * time.Minute // Idle time after which a served state snapshot is released
+	stateSnapshotMaxAge    = 5 * time.Minute // Age after which a served state snapshot is released, even in use
+)
+
+// snapStateTables are the tables making up the state at the pivot block. Only
+// what the state root commits to is downloaded: the deposit registry and the
+// rewards are replayed from the receipts, the incarnations of deleted contracts
+// only number storage locally.
+var snapStateTables = []string{
+	modules.Account,
+	modules.Storage,
+	modules.Code,
+	modules.PlainContractCode,
+}
+
+// snapChainTables are the tables of the chain up to the pivot block that are
+// downloaded with the state, they are checked against the block headers.
+var snapChainTables = []string{
+	modules.Receipts,
+}
+
+func isStateTable(table string) bool {
+	for _, tables := range [][]string{snapStateTables, snapChainTables} {
+		for _, t := range tables {
+			if t == table {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stateSnapshot serves ranges of the plain state to syncing peers. All ranges
+// are read from one read transaction, so that a peer downloading the state
+// table by table sees the state of a single block. The transaction keeps the
+// database from reusing the pages freed after it started, it is released after
+// stateSnapshotMaxAge and a peer still downloading restarts on a new snapshot.
+type stateSnapshot struct {
+	db kv.RoDB
+
+	lock    sync.Mutex
+	tx      kv.Tx
+	number  uint64
+	created time.Time
+	used    time.Time
+	timer   *time.Timer
+}
+
+func newStateSnapshot(db kv.RoDB) *stateSnapshot {
+	return &stateSnapshot{db: db}
+}
+
+// serve reads the requested range. A request for block 0 is served from the
+// current snapshot, which is opened on demand; a request for any other block
+// fails unless the snapshot is still at that block.
+func (s *stateSnapshot) serve(req *sync_proto.SyncStateRequest) (*sync_proto.SyncStateResponse, error) {
+	if !isStateTable(req.Table) {
+		return nil, ErrUnknownTable
+	}
+	var number uint64
+	if validH256(req.Number) {
+		number = utils.ConvertH256ToUint256Int(req.Number).Uint64()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.tx != nil && time.Since(s.created) >= stateSnapshotMaxAge {
+		s.release()
+	}
+	if s.tx == nil {
+		if number != 0 {
+			return nil, ErrStateUnavailable
+		}
+		if err := s.open(); nil != err {
+			return nil, err
+		}
+	}
+	if number != 0 && number != s.number {
+		return nil, ErrStateUnavailable
+	}
+	// Receipts are served for every block up to the snapshot or not at all
+	if req.Table == modules.Receipts {
+		if err := rawdb.CheckPruned(s.tx, rawdb.PruneKindReceipts, 1); nil != err {
+			return nil, ErrStateUnavailable
+		}
+	}
+	s.used = time.Now()
+
+	res := &sync_proto.SyncStateResponse{
+		Number: utils.ConvertUint256IntToH256(uint256.NewInt(s.number)),
+		Table:  req.Table,
+	}
+	var err error
+	res.Keys, res.Values, res.Next, err = s.readRange(req.Table, req.Origin, req.Limit)
+	if nil != err {
+		return nil, err
+	}
+	return res, nil
+}
+
+// open starts a read transaction at the head of the chain. The plain state is
+// committed before the head is moved, so a transaction that sees change sets
+// above the head is not usable as a snapshot.
+func (s *stateSnapshot) open() error {
+	tx, err := s.db.BeginRo(context.Background())
+	if nil != err {
+		return err
+	}
+	head := rawdb.ReadCurrentBlock(tx)
+	if head == nil || head.Number64().IsZero() {
+		tx.Rollback()
+		return ErrStateUnavailable
+	}
+	number := head.Number64().Uint64()
+	for _, table := range []string{modules.AccountChangeSet, modules.StorageChangeSet} {
+		k, err := rawdb.LastKey(tx, table)
+		if nil != err {
+			tx.Rollback()
+			return err
+		}
+		if len(k) >= 8 && binary.BigEndian.Uint64(k[:8]) > number {
+			tx.Rollback()
+			return ErrStateUnavailable
+		}
+	}
+
+	s.tx, s.number, s.created = tx, number, time.Now()
+	s.timer = time.AfterFunc(stateSnapshotIdle, s.expire)
+	log.Info("Opened state snapshot for syncing peers", "number", number)
+	return nil
+}
+
+func (s *stateSnapshot) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.tx == nil {
+		return
+	}
+	idle, age := time.Since(s.used), time.Since(s.created)
+	if idle < stateSnapshotIdle && age < stateSnapshotMaxAge {
+		wait := stateSnapshotIdle - idle
+		if left := stateSnapshotMaxAge - age; left < wait {
+			wait = left
+		}
+		s.timer.Reset(wait)
+		return
+	}
+	s.release()
+}
+
+// release closes the snapshot. The caller must hold the lock.
+func (s *stateSnapshot) release() {
+	if s.tx == nil {
+		return
+	}
+	s.timer.Stop()
+	s.tx.Rollback()
+	s.tx = nil
+	log.Info("Released state snapshot", "number", s.number)
+}
+
+func (s *stateSnapshot) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.release()
+}
+
+// readRange returns up to limit entries of table starting at origin and the key
+// the next range starts at, which is nil once the table is exhausted.
+func (s *stateSnapshot) readRange(table string, origin []byte, limit uint64) (keys, values [][]byte, next []byte, err error) {
+	if limit == 0 || limit > maxStateFetch {
+		limit = maxStateFetch
+	}
+	c, err := s.tx.Cursor(table)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+	defer c.Close()
+
+	size := 0
+	for k, v, err := c.Seek(origin); k != nil; k, v, err = c.Next() {
+		if nil != err {
+			return nil, nil, nil, err
+		}
+		if uint64(len(keys)) >= limit || size >= stateResponseSoftLimit {
+			return keys, values, types.CopyBytes(k), nil
+		}
+		keys = append(keys, types.CopyBytes(k))
+		values = append(values, types.CopyBytes(v))
+		size += len(k) + len(v)
+	}
+	return keys, values, nil, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkid implements EIP-2124 fork identifiers, which let peers tell
+// during the handshake whether they follow the same chain and fork schedule.
+package forkid
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/params"
+)
+
+var (
+	// ErrRemoteStale is returned by Validate if a remote fork ID is a subset of
+	// the local forks, but the remote is missing a fork the local node passed.
+	ErrRemoteStale = errors.New("remote needs update")
+
+	// ErrLocalIncompatibleOrStale is returned by Validate if a remote fork ID
+	// does not match any local fork, or the local node is past a fork the
+	// remote announces as upcoming.
+	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+)
+
+// ID is a fork identifier as defined by EIP-2124.
+type ID struct {
+	Hash [4]byte // CRC32 checksum of the genesis hash and passed fork block numbers
+	Next uint64  // Block number of the next upcoming fork, or 0 if no forks are known
+}
+
+// NewID calculates the fork ID of the chain at the given head block.
+func NewID(config *params.ChainConfig, genesis types.Hash, head uint64) ID {
+	hash := crc32.ChecksumIEEE(genesis[:])
+
+	var next uint64
+	for _, fork := range gatherForks(config) {
+		if fork <= head {
+			hash = checksumUpdate(hash, fork)
+			continue
+		}
+		next = fork
+		break
+	}
+	return ID{Hash: checksumToBytes(hash), Next: next}
+}
+
+// Validate checks whether a remote fork ID is compatible with the local chain
+// at the given head block, following the rules of EIP-2124.
+func Validate(config *params.ChainConfig, genesis types.Hash, head uint64, id ID) error {
+	// Calculate the checksums of all fork states, the first being genesis
+	forks := gatherForks(config)
+	sums := make([][4]byte, len(forks)+1)
+
+	hash := crc32.ChecksumIEEE(genesis[:])
+	sums[0] = checksumToBytes(hash)
+	for i, fork := range forks {
+		hash = checksumUpdate(hash, fork)
+		sums[i+1] = checksumToBytes(hash)
+	}
+	// Add a sentinel fork so the loop below always finds an unpassed fork
+	forks = append(forks, math.MaxUint64)
+
+	for i, fork := range forks {
+		if head >= fork {
+			continue
+		}
+		// Rule 1: the remote is in the same fork state. It is only incompatible
+		// if it announces a next fork the local head has passed already.
+		if sums[i] == id.Hash {
+			if id.Next > 0 && head >= id.Next {
+				return ErrLocalIncompatibleOrStale
+			}
+			return nil
+		}
+		// Rule 2: the remote is in an earlier fork state. It is compatible as
+		// long as it knows about the fork that follows, so it is only syncing.
+		for j := 0; j < i; j++ {
+			if sums[j] == id.Hash {
+				if forks[j] != id.Next {
+					return ErrRemoteStale
+				}
+				return nil
+			}
+		}
+		// Rule 3: the remote is in a later fork state, the local node is the one
+		// syncing.
+		for j := i + 1; j < len(sums); j++ {
+			if sums[j] == id.Hash {
+				return nil
+			}
+		}
+		// Rule 4: the remote is on another chain or fork schedule.
+		return ErrLocalIncompatibleOrStale
+	}
+	return ErrLocalIncompatibleOrStale
+}
+
+// gatherForks returns the sorted and deduplicated fork block numbers of the
+// chain config. Forks activated at genesis are left out.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+
+	kind := reflect.TypeOf(params.ChainConfig{})
+	conf := reflect.ValueOf(config).Elem()
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		if !strings.HasSuffix(field.Name, "Block") || field.Type != reflect.TypeOf(new(big.Int)) {
+			continue
+		}
+		if rule := conf.Field(i).Interface().(*big.Int); rule != nil && rule.Sign() > 0 {
+			forks = append(forks, rule.Uint64())
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	for i := 1; i < len(forks); i++ {
+		if forks[i] == forks[i-1] {
+			forks = append(forks[:i], forks[i+1:]...)
+			i--
+		}
+	}
+	return forks
+}
+
+// checksumUpdate adds a fork block number to a fork checksum.
+func checksumUpdate(hash uint32, fork uint64) uint32 {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], fork)
+	return crc32.Update(hash, crc32.IEEETable, blob[:])
+}
+
+func checksumToBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/amazechain/amc/params"
+)
+
+// mainnetConfig has the fork blocks of the Ethereum mainnet up to Gray Glacier,
+// for which the EIP-2124 test vectors are known.
+var mainnetConfig = &params.ChainConfig{
+	ChainID:               big.NewInt(1),
+	HomesteadBlock:        big.NewInt(1150000),
+	DAOForkBlock:          big.NewInt(1920000),
+	TangerineWhistleBlock: big.NewInt(2463000),
+	SpuriousDragonBlock:   big.NewInt(2675000),
+	ByzantiumBlock:        big.NewInt(4370000),
+	ConstantinopleBlock:   big.NewInt(7280000),
+	PetersburgBlock:       big.NewInt(7280000),
+	IstanbulBlock:         big.NewInt(9069000),
+	MuirGlacierBlock:      big.NewInt(9200000),
+	BerlinBlock:           big.NewInt(12244000),
+	LondonBlock:           big.NewInt(12965000),
+	ArrowGlacierBlock:     big.NewInt(13773000),
+	GrayGlacierBlock:      big.NewInt(15050000),
+}
+
+func TestNewID(t *testing.T) {
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: checksumToBytes(0xfc64ec04), Next: 1150000}},
+		{1149999, ID{Hash: checksumToBytes(0xfc64ec04), Next: 1150000}},
+		{1150000, ID{Hash: checksumToBytes(0x97c2c34c), Next: 1920000}},
+		{1920000, ID{Hash: checksumToBytes(0x91d1f948), Next: 2463000}},
+		{2463000, ID{Hash: checksumToBytes(0x7a64da13), Next: 2675000}},
+		{2675000, ID{Hash: checksumToBytes(0x3edd5b10), Next: 4370000}},
+		{4370000, ID{Hash: checksumToBytes(0xa00bc324), Next: 7280000}},
+		{7280000, ID{Hash: checksumToBytes(0x668db0af), Next: 9069000}},
+		{9069000, ID{Hash: checksumToBytes(0x879d6e30), Next: 9200000}},
+		{9200000, ID{Hash: checksumToBytes(0xe029e991), Next: 12244000}},
+		{12244000, ID{Hash: checksumToBytes(0x0eb440f6), Next: 12965000}},
+		{12965000, ID{Hash: checksumToBytes(0xb715077d), Next: 13773000}},
+		{13773000, ID{Hash: checksumToBytes(0x20c327fc), Next: 15050000}},
+		{15050000, ID{Hash: checksumToBytes(0xf0afd0e3), Next: 0}},
+		{20000000, ID{Hash: checksumToBytes(0xf0afd0e3), Next: 0}},
+	}
+	for i, tt := range tests {
+		if have := NewID(mainnetConfig, params.MainnetGenesisHash, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Same fork state, no upcoming fork announced by the remote
+		{7987396, ID{Hash: checksumToBytes(0x668db0af), Next: 0}, nil},
+		// Same fork state, the remote announces the upcoming local fork
+		{7987396, ID{Hash: checksumToBytes(0x668db0af), Next: 9069000}, nil},
+		// Same fork state, the remote announces a fork the local node does not know
+		{7279999, ID{Hash: checksumToBytes(0xa00bc324), Next: math.MaxUint64}, nil},
+		// The remote is at an earlier fork state and knows the next fork
+		{7987396, ID{Hash: checksumToBytes(0xa00bc324), Next: 7280000}, nil},
+		// The remote is at an earlier fork state and syncing
+		{7987396, ID{Hash: checksumToBytes(0x3edd5b10), Next: 4370000}, nil},
+		// The local node is at an earlier fork state and syncing
+		{7279999, ID{Hash: checksumToBytes(0x668db0af), Next: 0}, nil},
+		// The remote is at an earlier fork state and missing the next fork
+		{7987396, ID{Hash: checksumToBytes(0xa00bc324), Next: 0}, ErrRemoteStale},
+		// The remote announces a fork the local node passed without it
+		{7987396, ID{Hash: checksumToBytes(0x668db0af), Next: 7987396}, ErrLocalIncompatibleOrStale},
+		// The remote is on another chain
+		{7987396, ID{Hash: checksumToBytes(0xafec6b27), Next: 0}, ErrLocalIncompatibleOrStale},
+		// The local node is past the last fork and the fork the remote announces
+		{88888888, ID{Hash: checksumToBytes(0xf0afd0e3), Next: 88888888}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		if err := Validate(mainnetConfig, params.MainnetGenesisHash, tt.head, tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestGatherForks(t *testing.T) {
+	config := &params.ChainConfig{
+		HomesteadBlock:      big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(10),
+		ConstantinopleBlock: big.NewInt(10),
+		BeijingBlock:        big.NewInt(5),
+	}
+	forks := gatherForks(config)
+	if len(forks) != 2 || forks[0] != 5 || forks[1] != 10 {
+		t.Fatalf("forks mismatch: have %v, want [5 10]", forks)
+	}
+}
@@ -131,6 +131,14 @@ func (g *GenesisBlock) ToBlock() (*block2.Block, *state.IntraBlockState, error)
 		if err := statedb.FinalizeTx(g.GenesisBlockConfig.Config.Rules(0), w); err != nil {
 			panic(err)
 		}
+		if g.GenesisBlockConfig.Config.IsMerkle(0) {
+			statedb.UseStateTrie(tx, 0, state.EmptyTrieRoot, g.GenesisBlockConfig.Config.Rules(0))
+			root = statedb.IntermediateRoot()
+			if err := statedb.Error(); err != nil {
+				panic(err)
+			}
+			return
+		}
 		root = statedb.GenerateRootHash()
 	}()
 	wg.Wait()
@@ -238,6 +246,17 @@ func (g *GenesisBlock) WriteGenesisState(tx kv.RwTx) (*block2.Block, *state.Intr
 	if err := blockWriter.WriteHistory(); err != nil {
 		return nil, statedb, fmt.Errorf("cannot write history: %w", err)
 	}
+	// The root is computed in a temporary database, the trie the next block
+	// is computed on is stored along with the state.
+	if g.GenesisBlockConfig.Config.IsMerkle(0) {
+		root, err := state.BuildStateTrie(tx, 0)
+		if err != nil {
+			return nil, statedb, fmt.Errorf("cannot write state trie: %w", err)
+		}
+		if root != block.StateRoot() {
+			return nil, statedb, fmt.Errorf("genesis state trie root mismatch: have %x, want %x", root, block.StateRoot())
+		}
+	}
 
 	return block, statedb, nil
 }
@@ -3,6 +3,7 @@ package internal
 import (
 	"github.com/amazechain/amc/common/block"
 	"github.com/amazechain/amc/modules/state"
+	"github.com/ledgerwatch/erigon-lib/kv"
 )
 
 // Validator is an interface which defines the standard for block validation. It
@@ -15,4 +16,8 @@ type Validator interface {
 	// ValidateState validates the given statedb and optionally the receipts and
 	// gas used.
 	ValidateState(block block.IBlock, state *state.IntraBlockState, receipts block.Receipts, usedGas uint64) error
+
+	// ValidateAggSign validates the given block's aggregated signature against
+	// the deposits at its parent.
+	ValidateAggSign(tx kv.Tx, block block.IBlock) error
 }
@@ -18,23 +18,34 @@ package miner
 
 import (
 	"context"
+	"fmt"
+	"github.com/amazechain/amc/accounts"
 	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/block"
 	"github.com/amazechain/amc/common/txs_pool"
 	"github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/conf"
 	"github.com/amazechain/amc/internal/consensus"
+	"github.com/amazechain/amc/internal/consensus/apoa"
+	"github.com/amazechain/amc/internal/consensus/apos"
 	"github.com/amazechain/amc/log"
 	event "github.com/amazechain/amc/modules/event/v2"
+	"github.com/amazechain/amc/params"
 	"golang.org/x/sync/errgroup"
 	"time"
 )
 
+// signerEngine is a consensus engine sealing blocks with the keys of a set of
+// authorized signers.
+type signerEngine interface {
+	IsSigner(chain consensus.ChainHeaderReader, signer types.Address) (bool, error)
+}
+
 type Miner struct {
-	coinbase types.Address
-	engine   consensus.Engine
-	worker   *worker
-	txsPool  txs_pool.ITxsPool
+	engine  consensus.Engine
+	worker  *worker
+	txsPool txs_pool.ITxsPool
+	accman  *accounts.Manager
 
 	startCh chan types.Address
 	stopCh  chan struct{}
@@ -48,32 +59,50 @@ type Miner struct {
 
 //When the NewMiner function is called, it will create a new Miner instance
 
-func NewMiner(ctx context.Context, cfg *conf.Config, bc common.IBlockChain, engine consensus.Engine, txsPool txs_pool.ITxsPool, isLocalBlock func(header *block.Header) bool) *Miner {
+func NewMiner(ctx context.Context, cfg *conf.Config, bc common.IBlockChain, engine consensus.Engine, txsPool txs_pool.ITxsPool, accman *accounts.Manager, isLocalBlock func(header *block.Header) bool) *Miner {
 
 	//Create a new error group and error context object and add them to the errgroup.WithContext function.
 	group, errCtx := errgroup.WithContext(ctx)
+	c, cancel := context.WithCancel(errCtx)
 
 	//Initializes the properties of the Miner instance, including the consensus engine, the transaction pool,
 	//start and stop channels, error groups and error context objects, and the worker instance.
 	miner := &Miner{
 		engine:  engine,
 		txsPool: txsPool,
+		accman:  accman,
 		startCh: make(chan types.Address),
 		stopCh:  make(chan struct{}),
 		group:   group,
-		ctx:     errCtx,
+		ctx:     c,
+		cancel:  cancel,
 		worker:  newWorker(errCtx, group, cfg.GenesisBlockCfg.Engine, cfg.GenesisBlockCfg.Config, engine, bc, txsPool, isLocalBlock, false, cfg.Miner),
 	}
+	// The loop runs from the start, so that it learns when the node is synced
+	// even if mining is only started later on.
+	group.Go(func() error {
+		return miner.runLoop()
+	})
 
 	return miner
 }
 
 func (m *Miner) Start() {
-	log.Info("start miner", "coinbase", m.coinbase)
-	m.group.Go(func() error {
-		return m.runLoop()
-	})
-	m.startCh <- m.coinbase
+	coinbase := m.Coinbase()
+	log.Info("start miner", "coinbase", coinbase)
+	select {
+	case m.startCh <- coinbase:
+	case <-m.ctx.Done():
+	}
+}
+
+// Stop stops sealing blocks until Start is called again.
+func (m *Miner) Stop() {
+	log.Info("stop miner")
+	select {
+	case m.stopCh <- struct{}{}:
+	case <-m.ctx.Done():
+	}
 }
 
 func (m *Miner) runLoop() error {
@@ -110,7 +139,6 @@ func (m *Miner) runLoop() error {
 			if ok {
 				canStart = true
 				if !m.Mining() && shouldStart {
-					m.SetCoinbase(m.coinbase)
 					m.worker.start()
 				}
 			}
@@ -148,10 +176,56 @@ func (m *Miner) Mining() bool {
 }
 
 func (m *Miner) SetCoinbase(addr types.Address) {
-	m.coinbase = addr
 	m.worker.setCoinbase(addr)
 }
 
+// SetEtherbase makes the address the coinbase of mined blocks and authorizes
+// the consensus engine to seal blocks with its key, which must be in one of
+// the local wallets. The APoa and APos engines require the address to be an
+// authorized signer at the head of the chain.
+func (m *Miner) SetEtherbase(addr types.Address) error {
+	wallet, err := m.accman.Find(accounts.Account{Address: addr})
+	if err != nil {
+		return fmt.Errorf("etherbase account unavailable locally: %w", err)
+	}
+	if engine, ok := m.engine.(signerEngine); ok {
+		authorized, err := engine.IsSigner(m.worker.chain, addr)
+		if err != nil {
+			return err
+		}
+		if !authorized {
+			return fmt.Errorf("etherbase %v is not an authorized signer", addr)
+		}
+	}
+	switch engine := m.engine.(type) {
+	case *apoa.Apoa:
+		engine.Authorize(addr, wallet.SignData)
+	case *apos.APos:
+		engine.Authorize(addr, wallet.SignData)
+	}
+	m.SetCoinbase(addr)
+	log.Info("Changed etherbase", "etherbase", addr)
+	return nil
+}
+
+func (m *Miner) Coinbase() types.Address {
+	return m.worker.getCoinbase()
+}
+
+// SetGasCeil sets the gas ceiling the gas limit of mined blocks moves towards.
+func (m *Miner) SetGasCeil(ceil uint64) {
+	m.worker.setGasCeil(ceil)
+}
+
+// SetExtra sets the extra data of mined blocks.
+func (m *Miner) SetExtra(extra []byte) error {
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("extra exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
+	}
+	m.worker.setExtra(extra)
+	return nil
+}
+
 func (m *Miner) PendingBlockAndReceipts() (block.IBlock, block.Receipts) {
 	return m.worker.pendingBlockAndReceipts()
 }
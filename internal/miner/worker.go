@@ -20,7 +20,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/amazechain/amc/internal/api"
 	"github.com/amazechain/amc/internal/consensus/misc"
 	"github.com/holiman/uint256"
 	"sort"
@@ -107,13 +106,25 @@ func (env *environment) copy() *environment {
 }
 
 const (
-	minPeriodInterval         = 1 // 1s
-	staleThreshold            = 7
+	minPeriodInterval = 1 // 1s
+	staleThreshold    = 7
+
+	// minFillInterval is the least time spent packing transactions, even if
+	// the block is already due.
+	minFillInterval = 500 * time.Millisecond
+)
+
+// Signals sent to a block being built through its interrupt flag.
+const (
 	commitInterruptNone int32 = iota
 	commitInterruptNewHead
 	commitInterruptResubmit
 )
 
+// errBlockInterruptedByNewHead is returned when a new head arrives while the
+// block on top of the previous one is being built.
+var errBlockInterruptedByNewHead = errors.New("new head arrived while building block")
+
 type worker struct {
 	minerConf conf.MinerConfig
 	engine    consensus.Engine
@@ -121,6 +132,8 @@ type worker struct {
 	txsPool   txs_pool.ITxsPool
 
 	coinbase    types.Address
+	gasCeil     uint64 // Target gas ceiling for mined blocks
+	extra       []byte // Extra data of mined blocks
 	conf        *conf.ConsensusConfig
 	chainConfig *params.ChainConfig
 
@@ -168,6 +181,7 @@ func newWorker(ctx context.Context, group *errgroup.Group, conf *conf.ConsensusC
 		resultCh:     make(chan block.IBlock),
 		pendingTasks: make(map[types.Hash]*task),
 		minerConf:    minerConf,
+		gasCeil:      conf.GasCeil,
 	}
 	period := worker.conf.Period
 	if period < minPeriodInterval {
@@ -186,11 +200,6 @@ func newWorker(ctx context.Context, group *errgroup.Group, conf *conf.ConsensusC
 		return worker.taskLoop()
 	})
 
-	// machine verify
-	group.Go(func() error {
-		return api.MachineVerify(ctx)
-	})
-
 	group.Go(func() error {
 		return worker.resultLoop()
 	})
@@ -224,6 +233,26 @@ func (w *worker) setCoinbase(addr types.Address) {
 	w.coinbase = addr
 }
 
+func (w *worker) getCoinbase() types.Address {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.coinbase
+}
+
+// setGasCeil sets the gas ceiling the gas limit of mined blocks moves towards.
+func (w *worker) setGasCeil(ceil uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gasCeil = ceil
+	w.minerConf.GasCeil = ceil
+}
+
+func (w *worker) setExtra(extra []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.extra = types.CopyBytes(extra)
+}
+
 func (w *worker) runLoop() error {
 	defer w.cancel()
 	defer w.stop()
@@ -336,13 +365,15 @@ func (w *worker) taskLoop() error {
 
 func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) error {
 	start := time.Now()
+	// The coinbase may be changed while mining.
+	coinbase := w.getCoinbase()
 	if w.isRunning() {
-		if w.coinbase == (types.Address{}) {
+		if coinbase == (types.Address{}) {
 			return fmt.Errorf("coinbase is empty")
 		}
 	}
 
-	current, err := w.prepareWork(&generateParams{timestamp: uint64(timestamp), coinbase: w.coinbase})
+	current, err := w.prepareWork(&generateParams{timestamp: uint64(timestamp), coinbase: coinbase})
 	if err != nil {
 		log.Error("cannot prepare work", "err", err)
 		return err
@@ -360,6 +391,17 @@ func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) err
 	// generate state for mobile verify
 	ibs.BeginWriteSnapshot()
 	ibs.BeginWriteCodes()
+	if number := current.header.Number.Uint64(); w.chainConfig.IsMerkle(number) {
+		parent := rawdb.ReadHeader(tx, current.header.ParentHash, number-1)
+		if parent == nil {
+			return fmt.Errorf("missing parent")
+		}
+		parentRoot, err := state.ParentTrieRoot(tx, w.chainConfig, number, parent.Root)
+		if err != nil {
+			return err
+		}
+		ibs.UseStateTrie(tx, number, parentRoot, w.chainConfig.Rules(number))
+	}
 	headers := make([]*block.Header, 0)
 	//stateWriter := state.NewPlainStateWriter(tx, tx, current.header.Number.Uint64())
 	getHeader := func(hash types.Hash, number uint64) *block.Header {
@@ -370,7 +412,16 @@ func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) err
 		return h
 	}
 
-	if err := w.fillTransactions(interrupt, current, ibs, getHeader); err != nil {
+	// Transactions are packed until the block is due.
+	deadline := time.Unix(int64(current.header.Time), 0)
+	if earliest := start.Add(minFillInterval); deadline.Before(earliest) {
+		deadline = earliest
+	}
+	if err := w.fillTransactions(interrupt, deadline, current, ibs, getHeader); err != nil {
+		if errors.Is(err, errBlockInterruptedByNewHead) {
+			log.Debug("Discarded block built on a stale head", "number", current.header.Number.Uint64())
+			return err
+		}
 		log.Errorf("w.fillTransactions failed, error %v\n", err)
 		return err
 	}
@@ -449,44 +500,78 @@ func (w *worker) workLoop(period time.Duration) error {
 	}
 }
 
-func (w *worker) fillTransactions(interrupt *int32, env *environment, ibs *state.IntraBlockState, getHeader func(hash types.Hash, number uint64) *block.Header) error {
-	// todo fillTx
+// fillTransactions packs the pending transactions of the pool into the block,
+// the best paying first while keeping the nonce order of each sender.
+func (w *worker) fillTransactions(interrupt *int32, deadline time.Time, env *environment, ibs *state.IntraBlockState, getHeader func(hash types.Hash, number uint64) *block.Header) error {
 	env.txs = []*transaction.Transaction{}
-	txs, err := w.txsPool.GetTransaction()
-	if err != nil {
-		log.Warn("get transaction error", "err", err)
-		return err
+	pending := w.txsPool.Pending(true)
+	if len(pending) == 0 {
+		return nil
 	}
+	txs := transaction.NewTransactionsByPriceAndNonce(pending, env.header.BaseFee)
+	return w.commitTransactions(env, txs, interrupt, deadline, ibs, getHeader)
+}
 
+// commitTransactions applies the transactions in the order given by txs until
+// the block runs out of gas or the deadline passes. A sender whose transaction
+// fails is skipped for the rest of the block, as its later transactions can
+// not be applied either. Building stops with errBlockInterruptedByNewHead once
+// a new head arrives.
+func (w *worker) commitTransactions(env *environment, txs *transaction.TransactionsByPriceAndNonce, interrupt *int32, deadline time.Time, ibs *state.IntraBlockState, getHeader func(hash types.Hash, number uint64) *block.Header) error {
 	header := env.header
 	noop := state.NewNoopWriter()
-	var miningCommitTx = func(txn *transaction.Transaction, coinbase types.Address, vmConfig *vm2.Config, chainConfig *params.ChainConfig, ibs *state.IntraBlockState, current *environment) ([]*block.Log, error) {
-		ibs.Prepare(txn.Hash(), types.Hash{}, env.tcount)
-		gasSnap := current.gasPool.Gas()
-		snap := ibs.Snapshot()
-		log.Debug("addTransactionsToMiningBlock", "txn hash", txn.Hash())
-		receipt, _, err := internal.ApplyTransaction(chainConfig, internal.GetHashFn(header, getHeader), w.engine, &coinbase, env.gasPool, ibs, noop, current.header, txn, &header.GasUsed, *vmConfig)
-		if err != nil {
-			ibs.RevertToSnapshot(snap)
-			env.gasPool = new(common.GasPool).AddGas(gasSnap) // restore gasPool as well as ibs
-			return nil, err
+	for {
+		if interrupt != nil {
+			if signal := atomic.LoadInt32(interrupt); signal == commitInterruptNewHead {
+				return errBlockInterruptedByNewHead
+			} else if signal != commitInterruptNone {
+				break
+			}
+		}
+		if !time.Now().Before(deadline) {
+			log.Debug("Block building deadline reached", "number", header.Number.Uint64(), "txs", env.tcount)
+			break
+		}
+		if env.gasPool.Gas() < params.TxGas {
+			log.Trace("Not enough gas for further transactions", "have", env.gasPool.Gas(), "want", params.TxGas)
+			break
+		}
+		tx := txs.Peek()
+		if tx == nil {
+			break
+		}
+		if env.gasPool.Gas() < tx.Gas() {
+			log.Trace("Not enough gas left for transaction", "hash", tx.Hash(), "left", env.gasPool.Gas(), "needed", tx.Gas())
+			txs.Pop()
+			continue
 		}
 
-		current.txs = append(current.txs, txn)
-		current.receipts = append(current.receipts, receipt)
-		return receipt.Logs, nil
-	}
+		ibs.Prepare(tx.Hash(), types.Hash{}, env.tcount)
+		gasSnap := env.gasPool.Gas()
+		snap := ibs.Snapshot()
+		receipt, _, err := internal.ApplyTransaction(w.chainConfig, internal.GetHashFn(header, getHeader), w.engine, &env.coinbase, env.gasPool, ibs, noop, header, tx, &header.GasUsed, vm2.Config{})
+		switch {
+		case err == nil:
+			env.txs = append(env.txs, tx)
+			env.receipts = append(env.receipts, receipt)
+			env.tcount++
+			txs.Shift()
+
+		case errors.Is(err, internal.ErrNonceTooLow):
+			// New head notification data race between the transaction pool and miner, shift
+			ibs.RevertToSnapshot(snap)
+			env.gasPool = new(common.GasPool).AddGas(gasSnap)
+			log.Trace("Skipping transaction with low nonce", "sender", tx.From(), "nonce", tx.Nonce())
+			txs.Shift()
 
-	log.Tracef("fillTransactions txs len:%d", len(txs))
-	for _, tx := range txs {
-		// Start executing the transaction
-		_, err := miningCommitTx(tx, env.coinbase, &vm2.Config{}, w.chainConfig, ibs, env)
-		if nil != err {
-			log.Error("miningCommitTx failed ", "error", err)
-			//continue
+		default:
+			// Skip the sender's remaining transactions, they depend on this one
+			ibs.RevertToSnapshot(snap)
+			env.gasPool = new(common.GasPool).AddGas(gasSnap)
+			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "sender", tx.From(), "err", err)
+			txs.Pop()
 		}
 	}
-
 	return nil
 }
 
@@ -548,7 +633,8 @@ func (w *worker) prepareWork(param *generateParams) (*environment, error) {
 		ParentHash: parent.Hash(),
 		Coinbase:   param.coinbase,
 		Number:     uint256.NewInt(0).Add(parent.Number64(), uint256.NewInt(1)),
-		GasLimit:   CalcGasLimit(parent.GasLimit, w.conf.GasCeil),
+		GasLimit:   CalcGasLimit(parent.GasLimit, w.gasCeil),
+		Extra:      types.CopyBytes(w.extra),
 		Time:       uint64(timestamp),
 		Difficulty: uint256.NewInt(0),
 		// just for now
@@ -636,7 +722,7 @@ func (w *worker) commit(env *environment, interval func(), ibs *state.IntraBlock
 			}
 			sort.Sort(hs)
 
-			event.GlobalFeed.Send(common.MinedEntireEvent{Entire: state.EntireCode{Codes: hs, Headers: needHeaders, Entire: entri, Rewards: rewards, CoinBase: env.coinbase}})
+			event.GlobalFeed.Send(common.MinedEntireEvent{Entire: state.EntireCode{Codes: hs, Headers: needHeaders, Entire: entri, Rewards: rewards, CoinBase: env.coinbase, Trie: ibs.TrieWitness()}})
 		}
 
 		//
@@ -30,6 +30,19 @@ var (
 	notFoundPeer           = errors.New("p2p: not found peer info")
 )
 
+// Reasons for rejecting a peer in the protocol handshake.
+var (
+	ErrBadHandshake         = errors.New("p2p: invalid handshake message")
+	ErrLegacyHandshake      = errors.New("p2p: handshake of an older protocol version")
+	ErrChainIDMismatch      = errors.New("p2p: chain id mismatch")
+	ErrGenesisMismatch      = errors.New("p2p: genesis hash mismatch")
+	ErrForkIDRejected       = errors.New("p2p: fork id rejected")
+	ErrNoCommonCapability   = errors.New("p2p: no common sync capability")
+	ErrPeerAlreadyConnected = errors.New("p2p: peer already connected")
+	ErrTooManyPeers         = errors.New("p2p: too many peers")
+	ErrTooManyPendingPeers  = errors.New("p2p: too many pending peers")
+)
+
 type panicErr struct {
 	err error
 }
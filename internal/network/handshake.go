@@ -0,0 +1,137 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+
+	"github.com/amazechain/amc/api/protocol/msg_proto"
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/internal/forkid"
+	"github.com/amazechain/amc/params"
+	"github.com/amazechain/amc/utils"
+)
+
+// handshakeMessage encodes the local chain status for the protocol handshake.
+func handshakeMessage(version string, status *common.ProtocolStatus) *msg_proto.ProtocolHandshakeMessage {
+	return &msg_proto.ProtocolHandshakeMessage{
+		Version:       version,
+		GenesisHash:   utils.ConvertHashToH256(status.GenesisHash),
+		CurrentHeight: utils.ConvertUint256IntToH256(status.CurrentHeight),
+		ChainID:       status.ChainID,
+		ForkID: &msg_proto.ForkID{
+			Hash: status.ForkID.Hash[:],
+			Next: status.ForkID.Next,
+		},
+		Td:           utils.ConvertUint256IntToH256(status.TD),
+		Capabilities: status.Capabilities,
+	}
+}
+
+// protocolStatus decodes the chain status a peer sent in the protocol
+// handshake.
+func protocolStatus(h *msg_proto.ProtocolHandshakeMessage) (*common.ProtocolStatus, error) {
+	if h.GenesisHash == nil || h.CurrentHeight == nil {
+		return nil, ErrBadHandshake
+	}
+	// Peers of an older protocol version send neither a fork ID nor a total
+	// difficulty
+	if h.ForkID == nil || h.Td == nil {
+		return nil, fmt.Errorf("%w: version %q", ErrLegacyHandshake, h.Version)
+	}
+	if len(h.ForkID.Hash) != 4 {
+		return nil, fmt.Errorf("%w: fork id hash of %d bytes", ErrBadHandshake, len(h.ForkID.Hash))
+	}
+	status := &common.ProtocolStatus{
+		ChainID:       h.ChainID,
+		GenesisHash:   utils.ConvertH256ToHash(h.GenesisHash),
+		CurrentHeight: utils.ConvertH256ToUint256Int(h.CurrentHeight),
+		TD:            utils.ConvertH256ToUint256Int(h.Td),
+		Capabilities:  h.Capabilities,
+	}
+	copy(status.ForkID.Hash[:], h.ForkID.Hash)
+	status.ForkID.Next = h.ForkID.Next
+	return status, nil
+}
+
+// CheckProtocolStatus returns the reason why a peer announcing the remote
+// status cannot join the local chain, or nil if it can.
+func CheckProtocolStatus(config *params.ChainConfig, local, remote *common.ProtocolStatus) error {
+	if remote.ChainID != local.ChainID {
+		return fmt.Errorf("%w: have %d, want %d", ErrChainIDMismatch, remote.ChainID, local.ChainID)
+	}
+	if remote.GenesisHash != local.GenesisHash {
+		return fmt.Errorf("%w: have %x, want %x", ErrGenesisMismatch, remote.GenesisHash, local.GenesisHash)
+	}
+	if err := forkid.Validate(config, local.GenesisHash, local.CurrentHeight.Uint64(), remote.ForkID); err != nil {
+		return fmt.Errorf("%w: %v", ErrForkIDRejected, err)
+	}
+	for _, have := range remote.Capabilities {
+		for _, want := range local.Capabilities {
+			if have == want {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: have %v, want any of %v", ErrNoCommonCapability, remote.Capabilities, local.Capabilities)
+}
+
+// handshake exchanges the chain status with the peer of the node and admits
+// the peer through the handshake callback if the connection limits allow.
+// Inbound nodes wait for the status of the peer before sending their own.
+// Peers of other chains are banned.
+func (s *Service) handshake(node *Node, inbound bool) (common.Peer, error) {
+	done, err := s.startHandshake(node.ID())
+	if err != nil {
+		return common.Peer{}, err
+	}
+	defer done()
+	if !s.privileged(node.ID()) {
+		if err := s.checkPeerLimits(inbound); err != nil {
+			return common.Peer{}, err
+		}
+	}
+
+	local, err := s.peerInfo()
+	if err != nil {
+		return common.Peer{}, err
+	}
+
+	var h msg_proto.ProtocolHandshakeMessage
+	if inbound {
+		err = node.AcceptHandshake(&h, AppProtocol, local)
+	} else {
+		err = node.ProtocolHandshake(&h, AppProtocol, local, true)
+	}
+	if err != nil {
+		return common.Peer{}, err
+	}
+
+	remote, err := protocolStatus(&h)
+	if err != nil {
+		s.banOnHandshakeError(node.ID(), err)
+		return common.Peer{}, err
+	}
+	node.version = h.Version
+	node.inbound = inbound
+	p, err := s.peerCallback(node, remote)
+	if err != nil {
+		s.banOnHandshakeError(node.ID(), err)
+		return common.Peer{}, err
+	}
+	return p, nil
+}
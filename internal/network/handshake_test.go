@@ -0,0 +1,239 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/amazechain/amc/api/protocol/msg_proto"
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/conf"
+	"github.com/amazechain/amc/internal/forkid"
+	"github.com/amazechain/amc/params"
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+var testGenesis = types.HexToHash("0x1fa1c8e1ba3e2bd3ad8c9e4a1a7cf15a3b3b2c6e7c1d2a0e6c7b3a2d4e5f6a7b")
+
+// testChainConfig returns a chain config with a fork at the given block, or
+// without forks after genesis if it is 0.
+func testChainConfig(chainID int64, fork int64) *params.ChainConfig {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(chainID),
+		HomesteadBlock: big.NewInt(0),
+		LondonBlock:    big.NewInt(0),
+	}
+	if fork > 0 {
+		config.BeijingBlock = big.NewInt(fork)
+	}
+	return config
+}
+
+func testStatus(config *params.ChainConfig, genesis types.Hash, head uint64, capabilities ...string) *common.ProtocolStatus {
+	if len(capabilities) == 0 {
+		capabilities = []string{common.CapabilityFull, common.CapabilitySnap}
+	}
+	return &common.ProtocolStatus{
+		ChainID:       config.ChainID.Uint64(),
+		GenesisHash:   genesis,
+		ForkID:        forkid.NewID(config, genesis, head),
+		CurrentHeight: uint256.NewInt(head),
+		TD:            uint256.NewInt(2*head + 1),
+		Capabilities:  capabilities,
+	}
+}
+
+// newTestService creates a service on an in-memory host, admitting the peers
+// whose status passes CheckProtocolStatus and banning peers of other chains.
+func newTestService(t *testing.T, mn mocknet.Mocknet, config *params.ChainConfig, status *common.ProtocolStatus) *Service {
+	h, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	staticPeers, _ := loadPeerList("", nil)
+	trustedPeers, _ := loadPeerList("", nil)
+	bans, _ := loadBanList("")
+	s := &Service{
+		networkConfig: &conf.NetWorkConfig{BanDuration: time.Hour},
+		staticPeers:   staticPeers,
+		trustedPeers:  trustedPeers,
+		bans:          bans,
+		ctx:           ctx,
+		cancel:        cancel,
+		host:          h,
+		nodes:         make(common.PeerMap),
+		removeCh:      make(chan peer.ID, 10),
+		addCh:         make(chan peer.AddrInfo, 10),
+		handlers:      make(map[message.MessageType]common.ConnHandler),
+		peerInfo: func() (*common.ProtocolStatus, error) {
+			return status, nil
+		},
+		peerCallback: func(p common.IPeer, remote *common.ProtocolStatus) (common.Peer, error) {
+			if err := CheckProtocolStatus(config, status, remote); err != nil {
+				return common.Peer{}, err
+			}
+			return common.Peer{IPeer: p, CurrentHeight: remote.CurrentHeight, TD: remote.TD, Capabilities: remote.Capabilities}, nil
+		},
+	}
+	h.SetStreamHandler(MSGProtocol, s.handleStream)
+	return s
+}
+
+// testHandshake runs the handshake from a service with the local status to a
+// service with the remote status.
+func testHandshake(t *testing.T, localConfig *params.ChainConfig, local *common.ProtocolStatus, remoteConfig *params.ChainConfig, remote *common.ProtocolStatus) (a, b *Service, p common.Peer, err error) {
+	mn := mocknet.New()
+	t.Cleanup(func() { mn.Close() })
+
+	a = newTestService(t, mn, localConfig, local)
+	b = newTestService(t, mn, remoteConfig, remote)
+	// A remote banning the local node could disconnect before its status is
+	// read.
+	b.networkConfig.BanDuration = 0
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := NewNode(a.ctx, a.host, a, peer.AddrInfo{ID: b.host.ID()}, a.handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { node.Close() })
+
+	p, err = a.handshake(node, false)
+	return a, b, p, err
+}
+
+func TestHandshakeCompatible(t *testing.T) {
+	config := testChainConfig(100, 50)
+	local := testStatus(config, testGenesis, 60)
+	// The remote is still syncing before the fork at block 50
+	remote := testStatus(config, testGenesis, 10, common.CapabilityFull)
+
+	a, b, p, err := testHandshake(t, config, local, config, remote)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if p.CurrentHeight.Uint64() != 10 || p.TD.Uint64() != 21 {
+		t.Errorf("peer status mismatch: have height %v td %v, want height 10 td 21", p.CurrentHeight, p.TD)
+	}
+	if !p.HasCapability(common.CapabilityFull) || p.HasCapability(common.CapabilitySnap) {
+		t.Errorf("peer capabilities mismatch: have %v, want [full]", p.Capabilities)
+	}
+	if a.bans.banned(b.host.ID()) {
+		t.Error("compatible peer banned")
+	}
+
+	// The remote admits the local node as well
+	for i := 0; !b.checkNode(a.host.ID()); i++ {
+		if i == 100 {
+			t.Fatal("remote did not admit the local node")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProtocolStatus(t *testing.T) {
+	config := testChainConfig(100, 50)
+	status := testStatus(config, testGenesis, 60)
+
+	h := handshakeMessage("1.0", status)
+	if remote, err := protocolStatus(h); err != nil || remote.ForkID != status.ForkID || remote.TD.Uint64() != 121 {
+		t.Fatalf("have status %v (err %v), want %v", remote, err, status)
+	}
+
+	// A peer of an older protocol version only sends its genesis and height
+	legacy := &msg_proto.ProtocolHandshakeMessage{Version: h.Version, GenesisHash: h.GenesisHash, CurrentHeight: h.CurrentHeight}
+	if _, err := protocolStatus(legacy); !errors.Is(err, ErrLegacyHandshake) {
+		t.Errorf("legacy handshake: have %v, want %v", err, ErrLegacyHandshake)
+	}
+	malformed := handshakeMessage("1.0", status)
+	malformed.ForkID.Hash = malformed.ForkID.Hash[:2]
+	if _, err := protocolStatus(malformed); !errors.Is(err, ErrBadHandshake) {
+		t.Errorf("malformed fork id: have %v, want %v", err, ErrBadHandshake)
+	}
+	malformed = handshakeMessage("1.0", status)
+	malformed.GenesisHash = nil
+	if _, err := protocolStatus(malformed); !errors.Is(err, ErrBadHandshake) {
+		t.Errorf("missing genesis: have %v, want %v", err, ErrBadHandshake)
+	}
+}
+
+func TestHandshakeIncompatible(t *testing.T) {
+	config := testChainConfig(100, 50)
+
+	tests := []struct {
+		name         string
+		remoteConfig *params.ChainConfig
+		remote       *common.ProtocolStatus
+		err          error
+		ban          bool
+	}{
+		{
+			name:         "chain id",
+			remoteConfig: testChainConfig(101, 50),
+			remote:       testStatus(testChainConfig(101, 50), testGenesis, 60),
+			err:          ErrChainIDMismatch,
+			ban:          true,
+		},
+		{
+			name:         "genesis",
+			remoteConfig: config,
+			remote:       testStatus(config, types.Hash{0x01}, 60),
+			err:          ErrGenesisMismatch,
+			ban:          true,
+		},
+		{
+			name:         "fork id",
+			remoteConfig: testChainConfig(100, 0),
+			remote:       testStatus(testChainConfig(100, 0), testGenesis, 60),
+			err:          ErrForkIDRejected,
+		},
+		{
+			name:         "capabilities",
+			remoteConfig: config,
+			remote:       testStatus(config, testGenesis, 60, "light"),
+			err:          ErrNoCommonCapability,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := testStatus(config, testGenesis, 60)
+			a, b, _, err := testHandshake(t, config, local, tt.remoteConfig, tt.remote)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("handshake error mismatch: have %v, want %v", err, tt.err)
+			}
+			if banned := a.bans.banned(b.host.ID()); banned != tt.ban {
+				t.Errorf("peer ban mismatch: have %v, want %v", banned, tt.ban)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/amazechain/amc/log"
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// inboundRatio is the share of MaxPeers inbound peers may take, so that
+	// there is always room for outbound peers.
+	inboundRatio = 2.0 / 3.0
+
+	// protocolPeerTag is the connection manager tag of the connections to
+	// protocol peers, which are trimmed after connections used for discovery
+	// only.
+	protocolPeerTag   = "amc"
+	protocolPeerValue = 100
+)
+
+// banList is a set of banned peers with the time their ban ends, persisted
+// as a JSON object in its file. Without a path it is only kept in memory.
+type banList struct {
+	lock  sync.Mutex
+	path  string
+	peers map[peer.ID]time.Time
+}
+
+// loadBanList loads the ban list stored at path, dropping the bans that have
+// ended. A missing file is an empty list.
+func loadBanList(path string) (*banList, error) {
+	l := &banList{
+		path:  path,
+		peers: make(map[peer.ID]time.Time),
+	}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bans map[string]time.Time
+	if err := json.Unmarshal(data, &bans); err != nil {
+		return nil, fmt.Errorf("invalid ban list %s: %w", path, err)
+	}
+	now := time.Now()
+	for s, until := range bans {
+		id, err := peer.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ban list %s: %w", path, err)
+		}
+		if until.After(now) {
+			l.peers[id] = until
+		}
+	}
+	return l, nil
+}
+
+// ban bans the peer until the given time, unless it is banned longer already.
+func (l *banList) ban(id peer.ID, until time.Time) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if until.Before(l.peers[id]) {
+		return nil
+	}
+	l.peers[id] = until
+	return l.save()
+}
+
+// banned reports whether the peer is banned.
+func (l *banList) banned(id peer.ID) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	until, ok := l.peers[id]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	delete(l.peers, id)
+	return false
+}
+
+// save writes the bans that have not ended to the file. The lock must be
+// held.
+func (l *banList) save() error {
+	if l.path == "" {
+		return nil
+	}
+	now := time.Now()
+	bans := make(map[string]time.Time, len(l.peers))
+	for id, until := range l.peers {
+		if until.After(now) {
+			bans[id.String()] = until
+		}
+	}
+	data, err := json.MarshalIndent(bans, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(l.path+".tmp", l.path)
+}
+
+// connectionGater refuses connections to and from banned peers, and inbound
+// connections from new peers when the inbound peer slots are taken.
+type connectionGater struct {
+	s *Service
+}
+
+func (g *connectionGater) InterceptPeerDial(id peer.ID) bool {
+	return !g.s.bans.banned(id)
+}
+
+func (g *connectionGater) InterceptAddrDial(id peer.ID, _ multiaddr.Multiaddr) bool {
+	return true
+}
+
+func (g *connectionGater) InterceptAccept(network.ConnMultiaddrs) bool {
+	return true
+}
+
+func (g *connectionGater) InterceptSecured(dir network.Direction, id peer.ID, _ network.ConnMultiaddrs) bool {
+	if g.s.bans.banned(id) {
+		return false
+	}
+	if dir == network.DirInbound && !g.s.privileged(id) && !g.s.checkNode(id) {
+		return g.s.checkPeerLimits(true) == nil
+	}
+	return true
+}
+
+func (g *connectionGater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
+
+// privileged reports whether the peer is exempt from the connection limits.
+func (s *Service) privileged(id peer.ID) bool {
+	return s.staticPeers.has(id) || s.trustedPeers.has(id) || s.checkBootsStrap(id.String())
+}
+
+// checkPeerLimits returns ErrTooManyPeers if there is no room for another
+// inbound or outbound peer.
+func (s *Service) checkPeerLimits(inbound bool) error {
+	maxPeers := s.networkConfig.MaxPeers
+	if maxPeers <= 0 {
+		return nil
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.nodes) >= maxPeers {
+		return ErrTooManyPeers
+	}
+	if !inbound {
+		return nil
+	}
+	var inboundPeers int
+	for _, p := range s.nodes {
+		if node, ok := p.IPeer.(*Node); ok && node.inbound {
+			inboundPeers++
+		}
+	}
+	if inboundPeers >= int(float64(maxPeers)*inboundRatio) {
+		return ErrTooManyPeers
+	}
+	return nil
+}
+
+// startHandshake reserves one of the pending peer slots, returning the
+// function releasing it.
+func (s *Service) startHandshake(id peer.ID) (func(), error) {
+	if s.pending == nil || s.privileged(id) {
+		return func() {}, nil
+	}
+	select {
+	case s.pending <- struct{}{}:
+		return func() { <-s.pending }, nil
+	default:
+		return nil, ErrTooManyPendingPeers
+	}
+}
+
+// BanPeer disconnects the peer and refuses connections to and from it for
+// the configured ban duration. Trusted peers are never banned.
+func (s *Service) BanPeer(id peer.ID, reason string) {
+	if s.networkConfig.BanDuration <= 0 || s.trustedPeers.has(id) {
+		return
+	}
+	log.Warn("Banning peer", "PeerID", id, "duration", s.networkConfig.BanDuration, "reason", reason)
+	if err := s.bans.ban(id, time.Now().Add(s.networkConfig.BanDuration)); err != nil {
+		log.Warn("Failed to save the ban list", "err", err)
+	}
+	// The peer may be banned from its own message handler.
+	go func() {
+		if err := s.ClosePeer(id); err == notFoundPeer {
+			_ = s.host.Network().ClosePeer(id)
+		}
+	}()
+}
+
+// banOnHandshakeError bans a peer that failed the handshake with an invalid
+// handshake or a status of another chain.
+func (s *Service) banOnHandshakeError(id peer.ID, err error) {
+	if bannableHandshakeError(err) {
+		s.BanPeer(id, err.Error())
+	}
+}
+
+// bannableHandshakeError reports whether a failed handshake warrants a ban. A
+// rejected fork ID or a handshake of an older protocol version only
+// disconnects, as either side may simply be stale.
+func bannableHandshakeError(err error) bool {
+	return errors.Is(err, ErrBadHandshake) ||
+		errors.Is(err, ErrChainIDMismatch) ||
+		errors.Is(err, ErrGenesisMismatch)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/conf"
+	"github.com/amazechain/amc/internal/forkid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/test"
+)
+
+func TestBanList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned-peers.json")
+	bans, err := loadBanList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	banned, ended := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+	if err := bans.ban(banned, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bans.ban(ended, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// A shorter ban does not shorten the current one
+	if err := bans.ban(banned, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := loadBanList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []*banList{bans, reloaded} {
+		if !l.banned(banned) {
+			t.Errorf("peer %s not banned", banned)
+		}
+		if l.banned(ended) {
+			t.Errorf("peer %s still banned", ended)
+		}
+	}
+}
+
+func TestCheckPeerLimits(t *testing.T) {
+	s := &Service{
+		networkConfig: &conf.NetWorkConfig{MaxPeers: 3},
+		nodes:         make(common.PeerMap),
+	}
+	add := func(id peer.ID, inbound bool) {
+		s.nodes[id] = common.Peer{IPeer: &Node{inbound: inbound}}
+	}
+
+	add("in1", true)
+	if err := s.checkPeerLimits(true); err != nil {
+		t.Fatalf("inbound peer rejected: %v", err)
+	}
+	// Two thirds of the peers may be inbound
+	add("in2", true)
+	if err := s.checkPeerLimits(true); err != ErrTooManyPeers {
+		t.Fatalf("inbound limit error mismatch: have %v, want %v", err, ErrTooManyPeers)
+	}
+	if err := s.checkPeerLimits(false); err != nil {
+		t.Fatalf("outbound peer rejected: %v", err)
+	}
+	add("out1", false)
+	if err := s.checkPeerLimits(false); err != ErrTooManyPeers {
+		t.Fatalf("peer limit error mismatch: have %v, want %v", err, ErrTooManyPeers)
+	}
+
+	s.networkConfig.MaxPeers = 0
+	if err := s.checkPeerLimits(true); err != nil {
+		t.Fatalf("peer rejected without limit: %v", err)
+	}
+}
+
+func TestBannableHandshakeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{ErrBadHandshake, true},
+		{fmt.Errorf("%w: fork id hash of 2 bytes", ErrBadHandshake), true},
+		{fmt.Errorf("%w: version %q", ErrLegacyHandshake, "1.0"), false},
+		{ErrChainIDMismatch, true},
+		{ErrGenesisMismatch, true},
+		{fmt.Errorf("%w: %v", ErrForkIDRejected, forkid.ErrRemoteStale), false},
+		{fmt.Errorf("%w: %v", ErrForkIDRejected, forkid.ErrLocalIncompatibleOrStale), false},
+		{ErrTooManyPeers, false},
+	}
+	for i, tt := range tests {
+		if have := bannableHandshakeError(tt.err); have != tt.want {
+			t.Errorf("test %d (%v): have %v, want %v", i, tt.err, have, tt.want)
+		}
+	}
+}
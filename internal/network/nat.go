@@ -0,0 +1,163 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/amazechain/amc/conf"
+	"github.com/amazechain/amc/log"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/event"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// natOptions returns the host options of the NAT traversal settings.
+func natOptions(config *conf.NetWorkConfig) ([]libp2p.Option, error) {
+	var opts []libp2p.Option
+
+	announce, err := parseAddrs(config.AnnounceAddresses)
+	if err != nil {
+		return nil, err
+	}
+	switch nat := strings.ToLower(config.NAT); {
+	case nat == "" || nat == "none":
+	case nat == "any" || nat == "upnp" || nat == "pmp":
+		opts = append(opts, libp2p.NATPortMap())
+	case strings.HasPrefix(nat, "extip:"):
+		ip := net.ParseIP(nat[len("extip:"):])
+		if ip == nil {
+			return nil, fmt.Errorf("invalid external ip in nat %q", config.NAT)
+		}
+		listen, err := parseAddrs(config.ListenersAddress)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range listen {
+			if addr, err := replaceIP(addr, ip); err == nil {
+				announce = append(announce, addr)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown nat mechanism %q", config.NAT)
+	}
+	if len(announce) > 0 {
+		opts = append(opts, libp2p.AddrsFactory(announceAddrs(announce)))
+	}
+
+	if config.AutoNAT {
+		opts = append(opts, libp2p.EnableNATService())
+	}
+	if len(config.Relays) > 0 {
+		relays := make([]peer.AddrInfo, 0, len(config.Relays))
+		for _, addr := range config.Relays {
+			info, err := parsePeerAddr(addr)
+			if err != nil {
+				return nil, err
+			}
+			relays = append(relays, *info)
+		}
+		opts = append(opts, libp2p.EnableRelay(), libp2p.EnableAutoRelayWithStaticRelays(relays))
+	}
+	if config.HolePunching {
+		opts = append(opts, libp2p.EnableHolePunching())
+	}
+	return opts, nil
+}
+
+func parseAddrs(addrs []string) ([]multiaddr.Multiaddr, error) {
+	maddrs := make([]multiaddr.Multiaddr, 0, len(addrs))
+	for _, addr := range addrs {
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	return maddrs, nil
+}
+
+// replaceIP returns the address with its IP replaced by the given one.
+func replaceIP(addr multiaddr.Multiaddr, ip net.IP) (multiaddr.Multiaddr, error) {
+	first, rest := multiaddr.SplitFirst(addr)
+	if first == nil || (first.Protocol().Code != multiaddr.P_IP4 && first.Protocol().Code != multiaddr.P_IP6) {
+		return nil, fmt.Errorf("address %s has no ip", addr)
+	}
+	var prefix string
+	if ip4 := ip.To4(); ip4 != nil {
+		prefix = "/ip4/" + ip4.String()
+	} else {
+		prefix = "/ip6/" + ip.String()
+	}
+	external, err := multiaddr.NewMultiaddr(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if rest == nil {
+		return external, nil
+	}
+	return external.Encapsulate(rest), nil
+}
+
+// announceAddrs returns an address factory announcing the given addresses
+// instead of the listen addresses. Relayed addresses are still announced.
+func announceAddrs(announce []multiaddr.Multiaddr) func([]multiaddr.Multiaddr) []multiaddr.Multiaddr {
+	return func(addrs []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+		result := append([]multiaddr.Multiaddr{}, announce...)
+		for _, addr := range addrs {
+			if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+				result = append(result, addr)
+			}
+		}
+		return result
+	}
+}
+
+// Reachability returns whether the node is reachable from the outside.
+func (s *Service) Reachability() network.Reachability {
+	return network.Reachability(atomic.LoadInt32(&s.reachability))
+}
+
+// reachabilityLoop tracks whether the node is reachable from the outside, as
+// found by AutoNAT.
+func (s *Service) reachabilityLoop() {
+	sub, err := s.host.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		log.Warn("Failed to subscribe to reachability changes", "err", err)
+		return
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case e, ok := <-sub.Out():
+			if !ok {
+				return
+			}
+			reachability := e.(event.EvtLocalReachabilityChanged).Reachability
+			atomic.StoreInt32(&s.reachability, int32(reachability))
+			log.Info("Network reachability changed", "reachability", reachability, "addrs", s.host.Addrs())
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/multiformats/go-multiaddr"
+)
+
+func TestReplaceIP(t *testing.T) {
+	tests := []struct {
+		addr, ip, want string
+	}{
+		{"/ip4/0.0.0.0/tcp/61015", "203.0.113.7", "/ip4/203.0.113.7/tcp/61015"},
+		{"/ip4/10.0.0.2/udp/61015/quic", "203.0.113.7", "/ip4/203.0.113.7/udp/61015/quic"},
+		{"/ip6/::/tcp/61015", "2001:db8::1", "/ip6/2001:db8::1/tcp/61015"},
+	}
+	for _, tt := range tests {
+		have, err := replaceIP(multiaddr.StringCast(tt.addr), net.ParseIP(tt.ip))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.addr, err)
+		}
+		if have.String() != tt.want {
+			t.Errorf("%s: address mismatch: have %s, want %s", tt.addr, have, tt.want)
+		}
+	}
+	if _, err := replaceIP(multiaddr.StringCast("/dns4/example.com/tcp/61015"), net.ParseIP("203.0.113.7")); err == nil {
+		t.Error("replaced the ip of a dns address")
+	}
+}
+
+func TestAnnounceAddrs(t *testing.T) {
+	announce := []multiaddr.Multiaddr{multiaddr.StringCast("/ip4/203.0.113.7/tcp/61015")}
+	relayed := multiaddr.StringCast("/ip4/198.51.100.1/tcp/4001/p2p/16Uiu2HAmQXJcPjkA1aV4LgWxD1ZgbcGFs2cdWMtw3pFzprCkvz3C/p2p-circuit")
+	addrs := []multiaddr.Multiaddr{
+		multiaddr.StringCast("/ip4/10.0.0.2/tcp/61015"),
+		multiaddr.StringCast("/ip4/127.0.0.1/tcp/61015"),
+		relayed,
+	}
+
+	have := announceAddrs(announce)(addrs)
+	if len(have) != 2 || !have[0].Equal(announce[0]) || !have[1].Equal(relayed) {
+		t.Errorf("announced addresses mismatch: have %v, want %v", have, []multiaddr.Multiaddr{announce[0], relayed})
+	}
+}
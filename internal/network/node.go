@@ -23,9 +23,7 @@ import (
 	"fmt"
 	"github.com/amazechain/amc/common/hexutil"
 	"github.com/amazechain/amc/log"
-	"github.com/amazechain/amc/utils"
 	"github.com/golang/protobuf/proto"
-	"github.com/holiman/uint256"
 	"io"
 	"sync"
 	"time"
@@ -33,7 +31,6 @@ import (
 	"github.com/amazechain/amc/api/protocol/msg_proto"
 	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/message"
-	"github.com/amazechain/amc/common/types"
 	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/network"
@@ -91,7 +88,9 @@ type Node struct {
 	msgCallback map[message.MessageType]common.ConnHandler
 	msgLock     sync.RWMutex
 
-	isOK bool
+	isOK    bool
+	inbound bool
+	version string // protocol version announced in the handshake
 }
 
 func NewNode(ctx context.Context, h host.Host, s *Service, peer libpeer.AddrInfo, callback map[message.MessageType]common.ConnHandler, opts ...NodeOption) (*Node, error) {
@@ -146,7 +145,7 @@ func (n *Node) Start() {
 	}
 }
 
-// ProcessHandshake read peer's genesisHash and currentHeight
+// ProcessHandshake read peer's chain status
 func (n *Node) ProcessHandshake(h *msg_proto.ProtocolHandshakeMessage) error {
 	stream, ok := n.streams[string(n.config.Protocol)]
 	if !ok {
@@ -212,27 +211,21 @@ func (n *Node) ProcessHandshake(h *msg_proto.ProtocolHandshakeMessage) error {
 	return fmt.Errorf("unknown cause failure")
 }
 
-func (n *Node) AcceptHandshake(h *msg_proto.ProtocolHandshakeMessage, version string, genesisHash types.Hash, currentHeight *uint256.Int) error {
+func (n *Node) AcceptHandshake(h *msg_proto.ProtocolHandshakeMessage, version string, status *common.ProtocolStatus) error {
 	if err := n.ProcessHandshake(h); err != nil {
 		return err
 	}
 
-	if err := n.ProtocolHandshake(h, version, genesisHash, currentHeight, false); err != nil {
+	if err := n.ProtocolHandshake(h, version, status, false); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// ProtocolHandshake send current peer's genesisHash and height
-func (n *Node) ProtocolHandshake(h *msg_proto.ProtocolHandshakeMessage, version string, genesisHash types.Hash, currentHeight *uint256.Int, process bool) error {
-	phm := msg_proto.ProtocolHandshakeMessage{
-		Version:       version,
-		GenesisHash:   utils.ConvertHashToH256(genesisHash),
-		CurrentHeight: utils.ConvertUint256IntToH256(currentHeight),
-	}
-
-	b, err := proto.Marshal(&phm)
+// ProtocolHandshake send current peer's chain status
+func (n *Node) ProtocolHandshake(h *msg_proto.ProtocolHandshakeMessage, version string, status *common.ProtocolStatus, process bool) error {
+	b, err := proto.Marshal(handshakeMessage(version, status))
 	if err != nil {
 		return err
 	}
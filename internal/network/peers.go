@@ -0,0 +1,328 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/log"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// staticDialInterval is the interval at which static peers that are not
+// connected are dialed again.
+const staticDialInterval = 30 * time.Second
+
+// peerList is a set of peers persisted as a JSON array of their p2p
+// multiaddrs. Without a path it is only kept in memory.
+type peerList struct {
+	lock  sync.RWMutex
+	path  string
+	peers map[peer.ID]peer.AddrInfo
+}
+
+// loadPeerList loads the peer list stored at path and adds the given peer
+// addresses of the config. A missing file is an empty list.
+func loadPeerList(path string, addrs []string) (*peerList, error) {
+	l := &peerList{
+		path:  path,
+		peers: make(map[peer.ID]peer.AddrInfo),
+	}
+	for _, addr := range addrs {
+		info, err := parsePeerAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		l.peers[info.ID] = *info
+	}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored []string
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid peer list %s: %w", path, err)
+	}
+	for _, addr := range stored {
+		info, err := parsePeerAddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer list %s: %w", path, err)
+		}
+		l.peers[info.ID] = *info
+	}
+	return l, nil
+}
+
+// add adds the peer to the list, replacing its addresses if it is listed
+// already.
+func (l *peerList) add(info peer.AddrInfo) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.peers[info.ID] = info
+	return l.save()
+}
+
+// remove removes the peer from the list. It reports whether the peer was
+// listed.
+func (l *peerList) remove(id peer.ID) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.peers[id]; !ok {
+		return false, nil
+	}
+	delete(l.peers, id)
+	return true, l.save()
+}
+
+func (l *peerList) has(id peer.ID) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	_, ok := l.peers[id]
+	return ok
+}
+
+func (l *peerList) list() []peer.AddrInfo {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	infos := make([]peer.AddrInfo, 0, len(l.peers))
+	for _, info := range l.peers {
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// save writes the list to its file. The lock must be held.
+func (l *peerList) save() error {
+	if l.path == "" {
+		return nil
+	}
+	addrs := make([]string, 0, len(l.peers))
+	for _, info := range l.peers {
+		p2pAddrs, err := peer.AddrInfoToP2pAddrs(&info)
+		if err != nil {
+			return err
+		}
+		for _, addr := range p2pAddrs {
+			addrs = append(addrs, addr.String())
+		}
+	}
+	sort.Strings(addrs)
+
+	data, err := json.MarshalIndent(addrs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(l.path+".tmp", l.path)
+}
+
+// parsePeerAddr parses a p2p multiaddr such as
+// /ip4/127.0.0.1/tcp/21324/p2p/16Uiu2HAm...
+func parsePeerAddr(addr string) (*peer.AddrInfo, error) {
+	maddr, err := multiaddr.NewMultiaddr(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer address %q: %w", addr, err)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer address %q: %w", addr, err)
+	}
+	return info, nil
+}
+
+// AddPeer adds a static peer, which is kept connected, and connects to it.
+func (s *Service) AddPeer(addr string) error {
+	info, err := parsePeerAddr(addr)
+	if err != nil {
+		return err
+	}
+	if info.ID == s.host.ID() {
+		return errors.New("p2p: cannot add the local node")
+	}
+	if err := s.staticPeers.add(*info); err != nil {
+		return err
+	}
+	s.host.ConnManager().Protect(info.ID, "static")
+	s.dial(*info)
+	return nil
+}
+
+// RemovePeer removes a static peer and disconnects it.
+func (s *Service) RemovePeer(addr string) error {
+	info, err := parsePeerAddr(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := s.staticPeers.remove(info.ID); err != nil {
+		return err
+	}
+	s.host.ConnManager().Unprotect(info.ID, "static")
+	if err := s.ClosePeer(info.ID); err != nil && err != notFoundPeer {
+		return err
+	}
+	return nil
+}
+
+// AddTrustedPeer marks a peer as trusted.
+func (s *Service) AddTrustedPeer(addr string) error {
+	info, err := parsePeerAddr(addr)
+	if err != nil {
+		return err
+	}
+	if err := s.trustedPeers.add(*info); err != nil {
+		return err
+	}
+	s.host.ConnManager().Protect(info.ID, "trusted")
+	return nil
+}
+
+// RemoveTrustedPeer removes a peer from the trusted peers.
+func (s *Service) RemoveTrustedPeer(addr string) error {
+	info, err := parsePeerAddr(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := s.trustedPeers.remove(info.ID); err != nil {
+		return err
+	}
+	s.host.ConnManager().Unprotect(info.ID, "trusted")
+	return nil
+}
+
+// Peers returns the connection information of the connected peers.
+func (s *Service) Peers() []common.PeerInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	infos := make([]common.PeerInfo, 0, len(s.nodes))
+	for id, p := range s.nodes {
+		info := common.PeerInfo{
+			ID:           id,
+			Height:       p.CurrentHeight,
+			TD:           p.TD,
+			Capabilities: p.Capabilities,
+			Static:       s.staticPeers.has(id),
+			Trusted:      s.trustedPeers.has(id),
+			ConnectedAt:  p.AddTimer,
+		}
+		if node, ok := p.IPeer.(*Node); ok {
+			info.Version = node.version
+			info.Inbound = node.inbound
+			if stream, ok := node.streams[string(node.config.Protocol)]; ok {
+				info.Addrs = []string{stream.Conn().RemoteMultiaddr().String()}
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// NodeInfo returns the connection information and chain status of the local
+// node.
+func (s *Service) NodeInfo() (common.NodeInfo, error) {
+	status, err := s.peerInfo()
+	if err != nil {
+		return common.NodeInfo{}, err
+	}
+	p2pAddrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: s.host.ID(), Addrs: s.host.Addrs()})
+	if err != nil {
+		return common.NodeInfo{}, err
+	}
+	addrs := make([]string, 0, len(p2pAddrs))
+	for _, addr := range p2pAddrs {
+		addrs = append(addrs, addr.String())
+	}
+	listenAddrs := make([]string, 0)
+	for _, addr := range s.host.Network().ListenAddresses() {
+		listenAddrs = append(listenAddrs, addr.String())
+	}
+	return common.NodeInfo{
+		ID:           s.host.ID(),
+		Addrs:        addrs,
+		ListenAddrs:  listenAddrs,
+		Reachability: s.Reachability().String(),
+		Version:      AppProtocol,
+		Status:       status,
+	}, nil
+}
+
+// dial connects to the peer unless it is connected already.
+func (s *Service) dial(info peer.AddrInfo) {
+	if s.checkNode(info.ID) {
+		return
+	}
+	s.host.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+	go s.HandlePeerFound(info)
+}
+
+// staticDialLoop keeps the static peers connected.
+func (s *Service) staticDialLoop() {
+	ticker := time.NewTicker(staticDialInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, info := range s.staticPeers.list() {
+			s.dial(info)
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClosePeer disconnects the peer and removes it from the connected peers.
+func (s *Service) ClosePeer(id peer.ID) error {
+	s.lock.RLock()
+	p, ok := s.nodes[id]
+	s.lock.RUnlock()
+	if !ok {
+		return notFoundPeer
+	}
+	log.Info("Disconnecting peer", "PeerID", id)
+	_ = p.Close()
+
+	select {
+	case s.removeCh <- id:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	return s.host.Network().ClosePeer(id)
+}
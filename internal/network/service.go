@@ -19,7 +19,7 @@ package network
 import (
 	"context"
 	"crypto/rand"
-	"github.com/amazechain/amc/api/protocol/msg_proto"
+	"fmt"
 	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/message"
 	"github.com/amazechain/amc/conf"
@@ -34,6 +34,7 @@ import (
 	kaddht "github.com/libp2p/go-libp2p-kad-dht"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	tls "github.com/libp2p/go-libp2p-tls"
+	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/rcrowley/go-metrics"
 	"os"
@@ -84,6 +85,13 @@ type Service struct {
 	peerCallback common.ProtocolHandshakeFn
 	peerInfo     common.ProtocolHandshakeInfo
 
+	staticPeers  *peerList // peers kept connected
+	trustedPeers *peerList // peers exempt from the connection limits
+	bans         *banList
+	pending      chan struct{} // slots of the peers in the handshake
+
+	reachability int32 // network.Reachability found by AutoNAT, accessed atomically
+
 	amcPubSub common.IPubSub
 }
 
@@ -102,8 +110,21 @@ func NewService(ctx context.Context, config *conf.NetWorkConfig, peers common.Pe
 		handlers:      make(map[message.MessageType]common.ConnHandler),
 	}
 
-	var peerKey crypto.PrivKey
 	var err error
+	if s.staticPeers, err = loadPeerList(config.StaticPeersFile, config.StaticPeers); err != nil {
+		return nil, err
+	}
+	if s.trustedPeers, err = loadPeerList(config.TrustedPeersFile, config.TrustedPeers); err != nil {
+		return nil, err
+	}
+	if s.bans, err = loadBanList(config.BannedPeersFile); err != nil {
+		return nil, err
+	}
+	if config.MaxPendingPeers > 0 {
+		s.pending = make(chan struct{}, config.MaxPendingPeers)
+	}
+
+	var peerKey crypto.PrivKey
 	if len(s.networkConfig.LocalPeerKey) <= 0 {
 		peerKey, _, err = crypto.GenerateEd25519Key(rand.Reader)
 		if err != nil {
@@ -118,7 +139,28 @@ func NewService(ctx context.Context, config *conf.NetWorkConfig, peers common.Pe
 		}
 	}
 
-	h, err := libp2p.New(libp2p.Identity(peerKey), libp2p.ListenAddrStrings(s.networkConfig.ListenersAddress...), libp2p.Security(tls.ID, tls.New))
+	opts := []libp2p.Option{
+		libp2p.Identity(peerKey),
+		libp2p.ListenAddrStrings(s.networkConfig.ListenersAddress...),
+		libp2p.Security(tls.ID, tls.New),
+		libp2p.ConnectionGater(&connectionGater{s: &s}),
+	}
+	natOpts, err := natOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, natOpts...)
+	if config.MaxPeers > 0 {
+		// Leave room for the connections of discovery and pending peers,
+		// the protocol peers are limited in the handshake.
+		cm, err := connmgr.NewConnManager(config.MaxPeers, config.MaxPeers+config.MaxPendingPeers)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.ConnectionManager(cm))
+	}
+
+	h, err := libp2p.New(opts...)
 	if err != nil {
 		log.Error("create p2p host failed", "err", err)
 		return nil, err
@@ -159,6 +201,13 @@ func (s *Service) Start() error {
 		}
 		peersInfo = append(peersInfo, *peerInfo)
 		s.boots = append(s.boots, peerAddr)
+		s.host.ConnManager().Protect(peerInfo.ID, "bootstrap")
+	}
+	for _, info := range s.staticPeers.list() {
+		s.host.ConnManager().Protect(info.ID, "static")
+	}
+	for _, info := range s.trustedPeers.list() {
+		s.host.ConnManager().Protect(info.ID, "trusted")
 	}
 
 	kadDHT, err := NewKadDht(s.ctx, s, s.networkConfig.Bootstrapped, peersInfo...)
@@ -176,10 +225,13 @@ func (s *Service) Start() error {
 		s.connectBootsStraps(peersInfo)
 	}
 
-	hash, blockNr, _ := s.peerInfo()
-	log.Info("local peer", "PeerId", s.host.ID(), "PeerAddress", s.host.Addrs(), "BlockNr", blockNr.Uint64(), "genesisHash", hash)
+	if status, err := s.peerInfo(); err == nil {
+		log.Info("local peer", "PeerId", s.host.ID(), "PeerAddress", s.host.Addrs(), "BlockNr", status.CurrentHeight.Uint64(), "genesisHash", status.GenesisHash, "forkID", fmt.Sprintf("%x", status.ForkID.Hash))
+	}
 
 	go s.nodeManager(s.addCh)
+	go s.staticDialLoop()
+	go s.reachabilityLoop()
 
 	return nil
 }
@@ -204,25 +256,14 @@ func (s *Service) nodeManager(peerCh chan peer.AddrInfo) {
 				if !s.checkNode(p.ID) {
 					log.Debug("discover new peer", "PeerID", p.ID, "PeerAddress", p.Addrs)
 					if node, err := NewNode(s.ctx, s.host, s, p, s.handlers); err == nil {
-						hash, number, err := s.peerInfo()
-						if err != nil {
-							_ = node.Close()
-							continue
-						}
-						var h msg_proto.ProtocolHandshakeMessage
-						if err := node.ProtocolHandshake(&h, AppProtocol, hash, number, true); err != nil {
-							log.Warn("cannot Handshake", "PeerID", p.ID, "PeerAddress", p.Addrs, "ProtocolID", AppProtocol, "err", err)
+						if cPeer, err := s.handshake(node, false); err != nil {
+							log.Warn("Peer Handshake failed", "PeerID", p.ID, "PeerAddress", p.Addrs, "ProtocolID", AppProtocol, "err", err)
 							_ = node.Close()
 						} else {
-							if cPeer, ok := s.peerCallback(node, utils.ConvertH256ToHash(h.GenesisHash), utils.ConvertH256ToUint256Int(h.CurrentHeight)); ok {
-								node.Start()
-								s.addNode(cPeer)
-								log.Info("connected peer", "peerInfo", p.String(), "blockNumber", utils.ConvertH256ToUint256Int(h.CurrentHeight).Uint64())
-								event.GlobalEvent.Send(&common.PeerJoinEvent{Peer: cPeer.ID()})
-							} else {
-								log.Error("Peer Handshake failed", "PeerID", p.ID, "PeerAddress", p.Addrs)
-								_ = node.Close()
-							}
+							node.Start()
+							s.addNode(cPeer)
+							log.Info("connected peer", "peerInfo", p.String(), "blockNumber", cPeer.CurrentHeight.Uint64(), "capabilities", cPeer.Capabilities)
+							event.GlobalEvent.Send(&common.PeerJoinEvent{Peer: cPeer.ID()})
 						}
 					}
 				}
@@ -232,7 +273,7 @@ func (s *Service) nodeManager(peerCh chan peer.AddrInfo) {
 				log.Infof("delete node id:%s", id.String())
 				s.deleteNode(id)
 				event.GlobalEvent.Send(&common.PeerDropEvent{Peer: id})
-				if !s.checkBootsStrap(id.String()) {
+				if !s.checkBootsStrap(id.String()) && !s.staticPeers.has(id) {
 					s.host.Peerstore().RemovePeer(id)
 				}
 			}
@@ -298,6 +339,7 @@ func (s *Service) addNode(node common.Peer) {
 	defer s.lock.Unlock()
 	if _, ok := s.nodes[node.ID()]; !ok {
 		s.nodes[node.ID()] = node
+		s.host.ConnManager().TagPeer(node.ID(), protocolPeerTag, protocolPeerValue)
 	}
 }
 
@@ -306,6 +348,7 @@ func (s *Service) deleteNode(id peer.ID) {
 	defer s.lock.Unlock()
 	if _, ok := s.nodes[id]; ok {
 		delete(s.nodes, id)
+		s.host.ConnManager().UntagPeer(id, protocolPeerTag)
 	}
 }
 
@@ -330,21 +373,12 @@ func (s *Service) handleStream(stream network.Stream) {
 			log.Errorf("failed to new node %v, err %v", p.String(), err)
 			return
 		} else {
-			hash, number, err := s.peerInfo()
-			if err != nil {
-				log.Errorf("failed to get peer info, err:%v", err)
-				return
-			}
-			var h msg_proto.ProtocolHandshakeMessage
-			if err := node.AcceptHandshake(&h, AppProtocol, hash, number); err == nil {
-				if cp, ok := s.peerCallback(node, hash, number); ok {
-					node.Start()
-					s.addNode(cp)
-				} else {
-					log.Debugf("AcceptHandshake")
-				}
+			if cp, err := s.handshake(node, true); err == nil {
+				node.Start()
+				s.addNode(cp)
 			} else {
-				log.Debugf("failed accept handshake, err: %v", err)
+				log.Debug("failed accept handshake", "PeerID", p.ID, "err", err)
+				_ = node.Close()
 			}
 
 		}
@@ -411,7 +445,3 @@ func (s *Service) SetHandler(mt message.MessageType, handler common.ConnHandler)
 	}
 	return nil
 }
-
-func (s *Service) ClosePeer(id peer.ID) error {
-	return nil
-}
@@ -17,9 +17,22 @@
 package node
 
 import (
+	"strings"
+
 	"github.com/amazechain/amc/modules/rpc/jsonrpc"
 )
 
+// splitAndTrim splits a comma separated list and removes the empty and
+// surrounding white space of its entries.
+func splitAndTrim(input string) (ret []string) {
+	for _, r := range strings.Split(input, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
 func checkModuleAvailability(modules []string, apis []jsonrpc.API) (bad, available []string) {
 	availableSet := make(map[string]struct{})
 	for _, api := range apis {
@@ -17,12 +17,70 @@
 package node
 
 import (
-	"github.com/golang-jwt/jwt/v4"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/amazechain/amc/common/hexutil"
+	"github.com/amazechain/amc/log"
+	"github.com/golang-jwt/jwt/v4"
 )
 
+// ReadJWTSecret loads the hex encoded jwt secret from the given file.
+func ReadJWTSecret(fileName string) ([]byte, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT secret in %s: %w", fileName, err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("invalid JWT secret in %s: want 32 bytes, have %d", fileName, len(secret))
+	}
+	return secret, nil
+}
+
+// NewJWTToken issues a token authenticating a client against a node using the
+// given secret. Tokens are only accepted within jwtExpiryTimeout of being
+// issued.
+func NewJWTToken(secret []byte) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+	})
+	return token.SignedString(secret)
+}
+
+// obtainJWTSecret loads the hex encoded jwt secret from the given file, or
+// generates a new one and stores it there if the file does not exist.
+func obtainJWTSecret(fileName string) ([]byte, error) {
+	if secret, err := ReadJWTSecret(fileName); err == nil {
+		log.Info("Loaded JWT secret file", "path", fileName)
+		return secret, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fileName, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", fileName)
+	return secret, nil
+}
+
 const jwtExpiryTimeout = 60 * time.Second
 
 type jwtHandler struct {
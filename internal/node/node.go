@@ -20,7 +20,6 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
-	"github.com/amazechain/amc/contracts/deposit"
 	"github.com/amazechain/amc/internal/tracers"
 	"github.com/golang/protobuf/proto"
 	"github.com/holiman/uint256"
@@ -67,11 +66,13 @@ import (
 	"github.com/amazechain/amc/internal/consensus/apoa"
 	"github.com/amazechain/amc/internal/consensus/apos"
 	"github.com/amazechain/amc/internal/download"
+	"github.com/amazechain/amc/internal/forkid"
 	"github.com/amazechain/amc/internal/miner"
 	"github.com/amazechain/amc/internal/network"
 	"github.com/amazechain/amc/internal/pubsub"
 	"github.com/amazechain/amc/internal/txspool"
 	event "github.com/amazechain/amc/modules/event/v2"
+	"github.com/amazechain/amc/modules/prune"
 	"github.com/amazechain/amc/modules/rawdb"
 	"github.com/amazechain/amc/modules/rpc/jsonrpc"
 	"github.com/amazechain/amc/params"
@@ -81,30 +82,35 @@ import (
 	"go.uber.org/zap"
 )
 
+// logIndexBatchBlocks is the number of blocks added to the log indexes per
+// transaction while they are built in the background, small enough not to
+// hold up block processing for long.
+const logIndexBatchBlocks = 1000
+
 type Node struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 	config *conf.Config
 
 	//engine       consensus.IEngine
-	miner           *miner.Miner
-	pubsubServer    common.IPubSub
-	genesisBlock    block.IBlock
-	service         common.INetwork
-	peers           map[peer.ID]common.Peer
-	blocks          common.IBlockChain
-	engine          consensus.Engine
-	db              kv.RwDB
-	txspool         txs_pool.ITxsPool
-	txsFetcher      *txspool.TxsFetcher
-	nodeKey         crypto.PrivKey
-	depositContract *deposit.Deposit
+	miner        *miner.Miner
+	pubsubServer common.IPubSub
+	genesisBlock block.IBlock
+	service      common.INetwork
+	peers        map[peer.ID]common.Peer
+	blocks       common.IBlockChain
+	engine       consensus.Engine
+	db           kv.RwDB
+	txspool      txs_pool.ITxsPool
+	txsFetcher   *txspool.TxsFetcher
+	nodeKey      crypto.PrivKey
 	//nodeKey      *ecdsa.PrivateKey
 
 	//downloader
 	downloader common.IDownloader
 
 	shutDown chan struct{}
+	wg       sync.WaitGroup // background jobs writing to the database
 
 	peerLock sync.RWMutex
 	//feed     *event.Event
@@ -196,13 +202,15 @@ func NewNode(ctx context.Context, cfg *conf.Config) (*Node, error) {
 		panic(err)
 	}
 
+	cfg.NetworkCfg.StaticPeersFile = filepath.Join(cfg.NodeCfg.DataDir, "static-peers.json")
+	cfg.NetworkCfg.TrustedPeersFile = filepath.Join(cfg.NodeCfg.DataDir, "trusted-peers.json")
+	cfg.NetworkCfg.BannedPeersFile = filepath.Join(cfg.NodeCfg.DataDir, "banned-peers.json")
 	s, err := network.NewService(ctx, &cfg.NetworkCfg, peers, node.ProtocolHandshake, node.ProtocolHandshakeInfo)
 	if err != nil {
 		panic("new service failed")
 	}
 
-	//todo deal chainid？
-	pubsubServer, err = pubsub.NewPubSub(ctx, s, 1)
+	pubsubServer, err = pubsub.NewPubSub(ctx, s, &cfg.NetworkCfg, cfg.GenesisBlockCfg.Config.ChainID.Uint64())
 	if err != nil {
 		return nil, err
 	}
@@ -216,37 +224,46 @@ func NewNode(ctx context.Context, cfg *conf.Config) (*Node, error) {
 		return nil, fmt.Errorf("invalid engine name %s", cfg.GenesisBlockCfg.Engine.EngineName)
 	}
 
-	bc, _ := internal.NewBlockChain(ctx, genesisBlock, engine, downloader, chainKv, pubsubServer, cfg.GenesisBlockCfg.Config)
-	pool, _ := txspool.NewTxsPool(ctx, bc)
-
-	//todo
-	var txs []*transaction.Transaction
-	pending := pool.Pending(false)
-	for _, batch := range pending {
-		txs = append(txs, batch...)
+	pruneMode, err := prune.FromCli(cfg.DatabaseCfg.Prune, cfg.DatabaseCfg.PruneHistoryOlder, cfg.DatabaseCfg.PruneReceiptsOlder)
+	if nil != err {
+		return nil, err
 	}
-	var bloom *types.Bloom
-	if len(txs) > 0 {
-		bloom, _ = types.NewBloom(uint64(len(txs)))
-		for _, tx := range txs {
-			hash := tx.Hash()
-			bloom.Add(hash.Bytes())
+	bc, _ := internal.NewBlockChain(ctx, genesisBlock, engine, downloader, chainKv, pubsubServer, cfg.GenesisBlockCfg.Config, pruneMode)
+
+	// Bring the deposit registry up to date with the canonical chain; it is kept
+	// in sync by block processing from here on.
+	if dp, ok := engine.(consensus.DepositProcessor); ok {
+		if err := chainKv.Update(ctx, dp.RebuildDeposits); nil != err {
+			return nil, err
 		}
 	}
 
-	txsFetcher := txspool.NewTxsFetcher(ctx, pool.GetTx, pool.AddRemotes, pool.Pending, s, peers, bloom)
+	txsPoolCfg, err := txsPoolConfig(cfg)
+	if nil != err {
+		return nil, err
+	}
+	pool, err := txspool.NewTxsPool(ctx, txsPoolCfg, bc)
+	if nil != err {
+		return nil, err
+	}
+
+	txsFetcher := txspool.NewTxsFetcher(ctx, pool.GetTx, pool.AddRemotes, pool.Pending, s, peers)
 
 	//bc.SetEngine(engine)
 
 	c, cancel := context.WithCancel(ctx)
 
-	downloader = download.NewDownloader(ctx, bc, s, pubsubServer, peers)
+	syncMode := download.FullSync
+	if cfg.NodeCfg.SyncMode != "" {
+		if err := syncMode.UnmarshalText([]byte(cfg.NodeCfg.SyncMode)); nil != err {
+			return nil, err
+		}
+	}
+	downloader = download.NewDownloader(ctx, bc, s, pubsubServer, peers, syncMode, filepath.Join(cfg.NodeCfg.DataDir, "tmp"))
 
 	_ = s.SetHandler(message.MsgDownloader, downloader.ConnHandler)
 	_ = s.SetHandler(message.MsgTransaction, txsFetcher.ConnHandler)
 
-	miner := miner.NewMiner(ctx, cfg, bc, engine, pool, nil)
-
 	keyDir, isEphem, err := getKeyStoreDir(&cfg.NodeCfg)
 	if err != nil {
 		return nil, err
@@ -255,24 +272,25 @@ func NewNode(ctx context.Context, cfg *conf.Config) (*Node, error) {
 	// are required to add the backends later on.
 	accman := accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: cfg.NodeCfg.InsecureUnlockAllowed})
 
+	miner := miner.NewMiner(ctx, cfg, bc, engine, pool, accman, nil)
+
 	node = Node{
-		ctx:             c,
-		cancel:          cancel,
-		config:          cfg,
-		miner:           miner,
-		genesisBlock:    genesisBlock,
-		service:         s,
-		nodeKey:         privateKey,
-		blocks:          bc,
-		db:              chainKv,
-		shutDown:        make(chan struct{}),
-		pubsubServer:    pubsubServer,
-		peers:           peers,
-		downloader:      downloader,
-		txspool:         pool,
-		txsFetcher:      txsFetcher,
-		engine:          engine,
-		depositContract: deposit.NewDeposit(ctx, cfg.GenesisBlockCfg.Engine, bc, chainKv),
+		ctx:          c,
+		cancel:       cancel,
+		config:       cfg,
+		miner:        miner,
+		genesisBlock: genesisBlock,
+		service:      s,
+		nodeKey:      privateKey,
+		blocks:       bc,
+		db:           chainKv,
+		shutDown:     make(chan struct{}),
+		pubsubServer: pubsubServer,
+		peers:        peers,
+		downloader:   downloader,
+		txspool:      pool,
+		txsFetcher:   txsFetcher,
+		engine:       engine,
 
 		inprocHandler: jsonrpc.NewServer(),
 		http:          newHTTPServer(),
@@ -306,8 +324,9 @@ func NewNode(ctx context.Context, cfg *conf.Config) (*Node, error) {
 	log.Info(strings.Repeat("-", 153))
 	log.Info("")
 
-	node.api = api.NewAPI(pubsubServer, s, peers, bc, chainKv, engine, pool, downloader, node.AccountManager(), cfg.GenesisBlockCfg.Config)
+	node.api = api.NewAPI(pubsubServer, s, peers, bc, chainKv, engine, pool, downloader, miner, node.AccountManager(), cfg.GenesisBlockCfg.Config)
 	node.api.SetGpo(api.NewOracle(bc, miner, cfg.GenesisBlockCfg.Config, gpoParams))
+	node.api.SetExtRPCEnabled(cfg.NodeCfg.ExtRPCEnabled())
 	return &node, nil
 }
 
@@ -322,6 +341,11 @@ func (n *Node) Start() error {
 		return err
 	}
 
+	if err := n.registerValidators(); err != nil {
+		log.Errorf("failed register amc pubsub validators, err: %v", err)
+		return err
+	}
+
 	if err := n.blocks.Start(); err != nil {
 		log.Errorf("failed setup blocks service, err: %v", err)
 		return err
@@ -385,8 +409,8 @@ func (n *Node) Start() error {
 
 	go n.txsBroadcastLoop()
 	go n.txsMessageFetcherLoop()
-
-	n.depositContract.Start()
+	n.wg.Add(1)
+	go n.logIndexLoop()
 
 	//rwTx, _ := n.db.BeginRw(n.ctx)
 	//defer rwTx.Rollback()
@@ -410,32 +434,63 @@ func (n *Node) Start() error {
 
 // ProtocolHandshake is part of the node's protocol handshake process,
 // where the node checks the consistency of the blockchain with the peer and updates its list of connected peers.
-func (n *Node) ProtocolHandshake(peer common.IPeer, genesisHash types.Hash, currentHeight *uint256.Int) (common.Peer, bool) {
-
-	//Compares the hash of the peer's genesis block with the hash of the node's genesis block,
-	//and returns (common.Peer{}, false) if they are not equal.
-	if n.blocks.GenesisBlock().Hash().String() != genesisHash.String() {
-		return common.Peer{}, false
+// Peers on another chain or fork schedule, or without a common sync capability, are rejected.
+func (n *Node) ProtocolHandshake(peer common.IPeer, status *common.ProtocolStatus) (common.Peer, error) {
+	local, err := n.ProtocolHandshakeInfo()
+	if err != nil {
+		return common.Peer{}, err
 	}
-
-	//is part of the node's protocol handshake process,
-	//where the node checks the consistency of the blockchain with the peer and updates its list of connected peers.
-	if _, ok := n.peers[peer.ID()]; !ok {
-		return common.Peer{
-			IPeer:         peer,
-			CurrentHeight: currentHeight,
-			AddTimer:      time.Now(),
-		}, true
+	if err := network.CheckProtocolStatus(n.blocks.Config(), local, status); err != nil {
+		return common.Peer{}, err
 	}
 
-	return common.Peer{}, false
+	if _, ok := n.peers[peer.ID()]; ok {
+		return common.Peer{}, network.ErrPeerAlreadyConnected
+	}
+	return common.Peer{
+		IPeer:         peer,
+		CurrentHeight: status.CurrentHeight,
+		TD:            status.TD,
+		Capabilities:  status.Capabilities,
+		AddTimer:      time.Now(),
+	}, nil
 }
 
 // ProtocolHandshakeInfo provides information about the local peer's blockchain to other peers during the protocol handshake process.
-func (n *Node) ProtocolHandshakeInfo() (types.Hash, *uint256.Int, error) {
-	current := n.blocks.CurrentBlock() //Calls the CurrentBlock method of the blocks object to get the current block in the blockchain.
-	log.Infof("local peer info: height %d, genesis hash %v", current.Number64().Uint64(), n.blocks.GenesisBlock().Hash())
-	return n.blocks.GenesisBlock().Hash(), current.Number64(), nil
+func (n *Node) ProtocolHandshakeInfo() (*common.ProtocolStatus, error) {
+	var (
+		config  = n.blocks.Config()
+		genesis = n.blocks.GenesisBlock().Hash()
+		current = n.blocks.CurrentBlock()
+	)
+	td := n.blocks.GetTd(current.Hash(), current.Number64())
+	if td == nil {
+		return nil, fmt.Errorf("missing total difficulty of block %d", current.Number64().Uint64())
+	}
+	capabilities := []string{common.CapabilityFull}
+	if n.servesSnap(current.Number64().Uint64()) {
+		capabilities = append(capabilities, common.CapabilitySnap)
+	}
+	return &common.ProtocolStatus{
+		ChainID:       config.ChainID.Uint64(),
+		GenesisHash:   genesis,
+		ForkID:        forkid.NewID(config, genesis, current.Number64().Uint64()),
+		CurrentHeight: current.Number64(),
+		TD:            td,
+		Capabilities:  capabilities,
+	}, nil
+}
+
+// servesSnap reports whether snap syncing peers can be served the state at the
+// head block. Only the state roots from the Merkle fork on commit to the state,
+// and the receipts of every block up to the head are served with it.
+func (n *Node) servesSnap(head uint64) bool {
+	if head == 0 || !n.blocks.Config().IsMerkle(head) {
+		return false
+	}
+	return n.db.View(n.ctx, func(tx kv.Tx) error {
+		return rawdb.CheckPruned(tx, rawdb.PruneKindReceipts, 1)
+	}) == nil
 }
 
 //Network provides access to an object that can be used to communicate with other nodes
@@ -448,7 +503,8 @@ func (n *Node) Network() common.INetwork {
 	return nil
 }
 
-// txBroadcastLoop announces new transactions to all.
+// txsBroadcastLoop publishes the local transactions over pubsub. All new
+// transactions of the pool are also announced to the peers by the txs fetcher.
 func (n *Node) txsBroadcastLoop() {
 	// local txs
 	txsCh := make(chan common.NewLocalTxsEvent)
@@ -470,7 +526,36 @@ func (n *Node) txsBroadcastLoop() {
 	}
 }
 
-// txBroadcastLoop announces new transactions to all.
+// logIndexLoop brings the log indexes serving eth_getLogs up to date with the
+// canonical chain, in batches so that no transaction spans the whole chain.
+// The progress is stored with every block: an interrupted build resumes at the
+// next start, and block processing takes over once the indexes caught up.
+func (n *Node) logIndexLoop() {
+	defer n.wg.Done()
+	for {
+		var done bool
+		if err := n.db.Update(n.ctx, func(tx kv.RwTx) error {
+			var err error
+			done, err = rawdb.RebuildLogIndex(tx, logIndexBatchBlocks)
+			return err
+		}); nil != err {
+			if n.ctx.Err() == nil {
+				log.Error("Failed to build log indexes", "err", err)
+			}
+			return
+		}
+		if done {
+			return
+		}
+		select {
+		case <-n.shutDown:
+			return
+		default:
+		}
+	}
+}
+
+// txsMessageFetcherLoop adds the transactions published over pubsub to the pool.
 func (n *Node) txsMessageFetcherLoop() {
 
 	topic, err := n.pubsubServer.JoinTopic(message.GossipTransactionMessage)
@@ -485,7 +570,10 @@ func (n *Node) txsMessageFetcherLoop() {
 		case <-n.ctx.Done():
 			return
 		default:
-			msg, _ := sub.Next(n.ctx)
+			msg, err := sub.Next(n.ctx)
+			if err != nil {
+				return
+			}
 			var protoMsg types_pb.Transaction
 			if err := proto.Unmarshal(msg.Data, &protoMsg); err == nil {
 				tx, err := transaction.FromProtoMessage(&protoMsg)
@@ -528,11 +616,10 @@ func (n *Node) startRPC() error {
 	}
 	if n.config.NodeCfg.HTTPHost != "" {
 
-		//todo
 		config := httpConfig{
-			CorsAllowedOrigins: []string{},
-			Vhosts:             []string{"*"},
-			Modules:            []string{"eth", "web3", "debug", "net", "apoa", "txpool", "apos"},
+			CorsAllowedOrigins: splitAndTrim(n.config.NodeCfg.HTTPCors),
+			Vhosts:             splitAndTrim(n.config.NodeCfg.HTTPVirtualHosts),
+			Modules:            splitAndTrim(n.config.NodeCfg.HTTPApi),
 			prefix:             "",
 		}
 		port, _ := strconv.Atoi(n.config.NodeCfg.HTTPPort)
@@ -553,12 +640,19 @@ func (n *Node) startRPC() error {
 		if err := n.ws.setListenAddr(n.config.NodeCfg.WSHost, port); err != nil {
 			return err
 		}
-		//todo
+		var jwtSecret []byte
+		if n.config.NodeCfg.JWTSecret != "" {
+			secret, err := obtainJWTSecret(n.config.NodeCfg.JWTSecret)
+			if err != nil {
+				return err
+			}
+			jwtSecret = secret
+		}
 		config := wsConfig{
-			Modules:   []string{"eth", "web3", "debug", "net", "apoa", "txpool", "apos"},
-			Origins:   []string{"*"},
+			Modules:   splitAndTrim(n.config.NodeCfg.WSApi),
+			Origins:   splitAndTrim(n.config.NodeCfg.WSOrigins),
 			prefix:    "",
-			jwtSecret: []byte{},
+			jwtSecret: jwtSecret,
 		}
 		if err := n.ws.enableWS(n.rpcAPIs, config); err != nil {
 			return err
@@ -628,6 +722,7 @@ func (n *Node) Close() {
 	default:
 		n.cancel()
 		close(n.shutDown)
+		n.wg.Wait()
 		n.db.Close()
 	}
 }
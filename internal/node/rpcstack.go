@@ -325,8 +325,11 @@ func (h *httpServer) wsAllowed() bool {
 	return h.wsHandler.Load().(*rpcHandler) != nil
 }
 
+// NewHTTPHandlerStack returns wrapped http-related handlers
 func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
-	handler := newVHostHandler(vhosts, srv)
+	// Wrap the CORS-handler within a host-handler
+	handler := newCorsHandler(srv, cors)
+	handler = newVHostHandler(vhosts, handler)
 	return newGzipHandler(handler)
 }
 
@@ -338,6 +341,66 @@ func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
 	return srv
 }
 
+// corsHandler answers the CORS requests of browsers for the allowed origins.
+type corsHandler struct {
+	origins  map[string]struct{}
+	allowAll bool
+	next     http.Handler
+}
+
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
+	// disable CORS support if user has not specified a custom CORS configuration
+	if len(allowedOrigins) == 0 {
+		return srv
+	}
+	h := &corsHandler{origins: make(map[string]struct{}), next: srv}
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			h.allowAll = true
+		}
+		h.origins[strings.ToLower(origin)] = struct{}{}
+	}
+	return h
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if _, ok := h.origins[strings.ToLower(origin)]; !ok && !h.allowAll {
+		// Not a cross origin request this server answers, the browser blocks
+		// the response.
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+	if h.allowAll {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	// Preflight request
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
 type virtualHostHandler struct {
 	vhosts map[string]struct{}
 	next   http.Handler
@@ -459,7 +522,7 @@ func (is *ipcServer) stop() error {
 
 func RegisterApisFromWhitelist(apis []jsonrpc.API, modules []string, srv *jsonrpc.Server, exposeAll bool) error {
 	if bad, available := checkModuleAvailability(modules, apis); len(bad) > 0 {
-		log.Error("Unavailable modules in HTTP API list", "unavailable", bad, "available", available)
+		log.Warn("Unavailable modules in API list", "unavailable", bad, "available", available)
 	}
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -0,0 +1,59 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/conf"
+	"github.com/amazechain/amc/internal/txspool"
+)
+
+// txsPoolConfig converts the txpool section of the node config into the
+// transaction pool config. Unset limits fall back to the pool defaults and
+// a relative journal path is resolved against the data directory.
+func txsPoolConfig(cfg *conf.Config) (txspool.TxsPoolConfig, error) {
+	c := cfg.TxPoolCfg
+	pc := txspool.TxsPoolConfig{
+		NoLocals:     c.NoLocals,
+		Journal:      c.Journal,
+		Rejournal:    c.Rejournal,
+		PriceLimit:   c.PriceLimit,
+		PriceBump:    c.PriceBump,
+		AccountSlots: c.AccountSlots,
+		GlobalSlots:  c.GlobalSlots,
+		AccountQueue: c.AccountQueue,
+		GlobalQueue:  c.GlobalQueue,
+		Lifetime:     c.Lifetime,
+	}
+	for _, account := range c.Locals {
+		if account = strings.TrimSpace(account); account == "" {
+			continue
+		}
+		if !types.IsHexAddress(account) {
+			return pc, fmt.Errorf("invalid txpool local account %q", account)
+		}
+		pc.Locals = append(pc.Locals, types.HexToAddress(account))
+	}
+	if pc.Journal != "" && !filepath.IsAbs(pc.Journal) {
+		pc.Journal = filepath.Join(cfg.NodeCfg.DataDir, pc.Journal)
+	}
+	return pc, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/conf"
+)
+
+func TestTxsPoolConfig(t *testing.T) {
+	cfg := &conf.Config{
+		NodeCfg: conf.NodeConfig{DataDir: "/data"},
+		TxPoolCfg: conf.TxPoolConfig{
+			Locals:     []string{" 0x0a00000000000000000000000000000000000000", ""},
+			Journal:    "transactions.journal",
+			PriceLimit: 2,
+		},
+	}
+	pc, err := txsPoolConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []types.Address{{0x0a}}; !reflect.DeepEqual(pc.Locals, want) {
+		t.Errorf("have locals %v, want %v", pc.Locals, want)
+	}
+	if want := filepath.Join("/data", "transactions.journal"); pc.Journal != want {
+		t.Errorf("have journal %q, want %q", pc.Journal, want)
+	}
+	if pc.PriceLimit != 2 {
+		t.Errorf("have price limit %d, want 2", pc.PriceLimit)
+	}
+
+	// An absolute journal path is kept
+	cfg.TxPoolCfg.Journal = "/tmp/transactions.journal"
+	if pc, err = txsPoolConfig(cfg); err != nil || pc.Journal != cfg.TxPoolCfg.Journal {
+		t.Errorf("have journal %q (err %v), want %q", pc.Journal, err, cfg.TxPoolCfg.Journal)
+	}
+
+	cfg.TxPoolCfg.Locals = []string{"0xzz"}
+	if _, err := txsPoolConfig(cfg); err == nil {
+		t.Error("invalid local account accepted")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"time"
+
+	"github.com/amazechain/amc/api/protocol/types_pb"
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/internal"
+	"github.com/amazechain/amc/internal/consensus"
+	"github.com/amazechain/amc/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// registerValidators registers the validators of the block and transaction
+// topics, so that invalid messages are neither delivered nor forwarded and
+// lower the peer score of their sender.
+func (n *Node) registerValidators() error {
+	if err := n.pubsubServer.RegisterValidator(message.GossipTransactionMessage, n.validateTransaction); nil != err {
+		return err
+	}
+	return n.pubsubServer.RegisterValidator(message.GossipBlockMessage, n.validateBlock)
+}
+
+// validateTransaction accepts a published transaction if it decodes and is
+// signed by its sender. Transactions already in the pool are ignored.
+func (n *Node) validateTransaction(_ context.Context, id peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	// Our own transactions are in the pool already.
+	if id == n.service.Host().ID() {
+		return pubsub.ValidationAccept
+	}
+
+	var protoMsg types_pb.Transaction
+	if err := proto.Unmarshal(msg.Data, &protoMsg); nil != err {
+		return pubsub.ValidationReject
+	}
+	tx, err := transaction.FromProtoMessage(&protoMsg)
+	if nil != err {
+		return pubsub.ValidationReject
+	}
+	if n.txspool.Has(tx.Hash()) {
+		return pubsub.ValidationIgnore
+	}
+
+	number := new(uint256.Int).AddUint64(n.blocks.CurrentBlock().Number64(), 1)
+	sender, err := transaction.Sender(transaction.MakeSigner(n.blocks.Config(), number.ToBig()), tx)
+	if nil != err {
+		log.Debug("Invalid pubsub transaction signature", "hash", tx.Hash(), "peer", id, "err", err)
+		return pubsub.ValidationReject
+	}
+	if from := tx.From(); from == nil || *from != sender {
+		log.Debug("Invalid pubsub transaction sender", "hash", tx.Hash(), "peer", id, "sender", sender)
+		return pubsub.ValidationReject
+	}
+	return pubsub.ValidationAccept
+}
+
+// validateBlock accepts a published block if it decodes, its transactions
+// match its header and its header and verifiers pass the consensus checks.
+// Known blocks, blocks from the future and blocks of unknown parents are
+// ignored, as are blocks whose verifiers cannot be checked against the
+// deposits yet.
+func (n *Node) validateBlock(ctx context.Context, id peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	if id == n.service.Host().ID() {
+		return pubsub.ValidationAccept
+	}
+
+	var blockMsg types_pb.Block
+	if err := proto.Unmarshal(msg.Data, &blockMsg); nil != err {
+		return pubsub.ValidationReject
+	}
+	var blk block.Block
+	if err := blk.FromProtoMessage(&blockMsg); nil != err {
+		return pubsub.ValidationReject
+	}
+	if blk.Number64().IsZero() {
+		return pubsub.ValidationReject
+	}
+
+	if n.blocks.GetHeader(blk.Hash(), blk.Number64()) != nil {
+		return pubsub.ValidationIgnore
+	}
+	// The peer may be ahead of us or its clock off, neither is malicious.
+	if blk.Time() > uint64(time.Now().Unix()) {
+		return pubsub.ValidationIgnore
+	}
+	if n.blocks.GetHeader(blk.ParentHash(), new(uint256.Int).SubUint64(blk.Number64(), 1)) == nil {
+		return pubsub.ValidationIgnore
+	}
+
+	if hash := internal.DeriveSha(transaction.Transactions(blk.Transactions())); hash != blk.TxHash() {
+		log.Debug("Invalid pubsub block transactions", "number", blk.Number64(), "hash", blk.Hash(), "peer", id)
+		return pubsub.ValidationReject
+	}
+	if err := n.engine.VerifyHeader(n.blocks, blk.Header(), true); nil != err {
+		log.Debug("Invalid pubsub block header", "number", blk.Number64(), "hash", blk.Hash(), "peer", id, "err", err)
+		return pubsub.ValidationReject
+	}
+
+	verifier, ok := n.engine.(consensus.AggSignVerifier)
+	if !ok || !n.blocks.Config().IsBeijing(blk.Number64().Uint64()) {
+		return pubsub.ValidationAccept
+	}
+	if err := n.db.View(ctx, func(tx kv.Tx) error {
+		return verifier.VerifyAggSign(tx, blk.Header(), blk.Body().Verifier())
+	}); consensus.IsBadVerifiers(err) {
+		log.Debug("Invalid pubsub block verifiers", "number", blk.Number64(), "hash", blk.Hash(), "peer", id, "err", err)
+		return pubsub.ValidationReject
+	} else if nil != err {
+		return pubsub.ValidationIgnore
+	}
+	return pubsub.ValidationAccept
+}
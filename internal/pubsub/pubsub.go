@@ -25,6 +25,7 @@ import (
 
 	"github.com/amazechain/amc/common"
 	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/conf"
 	"github.com/amazechain/amc/log"
 	"github.com/libp2p/go-libp2p-core/host"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -49,16 +50,18 @@ type AmcPubSub struct {
 
 	ctx context.Context
 
+	config  *conf.NetWorkConfig
 	chainID uint64
 }
 
-func NewPubSub(ctx context.Context, p2pserver common.INetwork, chainid uint64) (common.IPubSub, error) {
+func NewPubSub(ctx context.Context, p2pserver common.INetwork, config *conf.NetWorkConfig, chainid uint64) (common.IPubSub, error) {
 	amc := AmcPubSub{
 		ctx:       ctx,
 		host:      p2pserver.Host(),
 		p2pserver: p2pserver,
 		running:   0,
 		topicsMap: make(map[string]*pubsub.Topic),
+		config:    config,
 		chainID:   chainid,
 	}
 
@@ -75,6 +78,9 @@ func (m *AmcPubSub) Start() error {
 	var options []pubsub.Option
 
 	options = append(options, pubsub.WithRawTracer(newRawTracer()))
+	if m.config.PeerScoring {
+		options = append(options, pubsub.WithPeerScore(peerScoreParams(m.config), peerScoreThresholds(m.config)))
+	}
 	// todo for test
 	if false {
 		tracer, err := pubsub.NewJSONTracer("./trace.json")
@@ -119,6 +125,19 @@ func (m *AmcPubSub) JoinTopic(topic string) (*pubsub.Topic, error) {
 	return nil, errorInvalidTopic
 }
 
+// RegisterValidator registers the validator of the messages of a topic. The
+// messages it rejects are not forwarded and count against the peer score of
+// their sender.
+func (m *AmcPubSub) RegisterValidator(topic string, val pubsub.ValidatorEx) error {
+	if !m.isRunning() {
+		return errorNotRunning
+	}
+	if _, ok := message.TopicMappings[topic]; !ok {
+		return errorInvalidTopic
+	}
+	return m.pubsub.RegisterTopicValidator(topic, val)
+}
+
 func (m *AmcPubSub) isRunning() bool {
 	if atomic.LoadInt32(&m.running) <= 0 {
 		return false
@@ -0,0 +1,61 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package pubsub
+
+import (
+	"time"
+
+	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/conf"
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// peerScoreParams scores the peers by the invalid messages they deliver on the
+// block and transaction topics. The other score components are disabled, as
+// the message rates of these topics are too irregular to expect deliveries.
+func peerScoreParams(config *conf.NetWorkConfig) *pubsub.PeerScoreParams {
+	decay := pubsub.ScoreParameterDecay(config.InvalidMessageDecay)
+	topic := func(weight float64) *pubsub.TopicScoreParams {
+		return &pubsub.TopicScoreParams{
+			TopicWeight:                    weight,
+			TimeInMeshQuantum:              time.Second,
+			InvalidMessageDeliveriesWeight: config.InvalidMessageWeight,
+			InvalidMessageDeliveriesDecay:  decay,
+		}
+	}
+	return &pubsub.PeerScoreParams{
+		Topics: map[string]*pubsub.TopicScoreParams{
+			message.GossipBlockMessage:       topic(config.BlockTopicWeight),
+			message.GossipTransactionMessage: topic(config.TxTopicWeight),
+		},
+		AppSpecificScore: func(peer.ID) float64 { return 0 },
+		DecayInterval:    pubsub.DefaultDecayInterval,
+		DecayToZero:      pubsub.DefaultDecayToZero,
+		// Keep the score of disconnected peers so that reconnecting does
+		// not clear their penalties.
+		RetainScore: config.InvalidMessageDecay,
+	}
+}
+
+func peerScoreThresholds(config *conf.NetWorkConfig) *pubsub.PeerScoreThresholds {
+	return &pubsub.PeerScoreThresholds{
+		GossipThreshold:   config.GossipThreshold,
+		PublishThreshold:  config.PublishThreshold,
+		GraylistThreshold: config.GraylistThreshold,
+	}
+}
@@ -17,6 +17,7 @@
 package pubsub
 
 import (
+	"github.com/amazechain/amc/log"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/protocol"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -26,6 +27,9 @@ import (
 var (
 	egressPubsubTrafficMeter  = metrics.GetOrRegisterMeter("p2p/pubsub/egress", nil)
 	ingressPubsubTrafficMeter = metrics.GetOrRegisterMeter("p2p/pubsub/ingress", nil)
+	rejectedPubsubMeter       = metrics.GetOrRegisterMeter("p2p/pubsub/rejected", nil)
+	ignoredPubsubMeter        = metrics.GetOrRegisterMeter("p2p/pubsub/ignored", nil)
+	throttledPubsubMeter      = metrics.GetOrRegisterMeter("p2p/pubsub/throttled", nil)
 )
 
 type rawTracer struct {
@@ -35,19 +39,31 @@ func newRawTracer() *rawTracer {
 	return &rawTracer{}
 }
 
-func (m rawTracer) AddPeer(p peer.ID, proto protocol.ID)             {}
-func (m rawTracer) RemovePeer(p peer.ID)                             {}
-func (m rawTracer) Join(topic string)                                {}
-func (m rawTracer) Leave(topic string)                               {}
-func (m rawTracer) Graft(p peer.ID, topic string)                    {}
-func (m rawTracer) Prune(p peer.ID, topic string)                    {}
-func (m rawTracer) ValidateMessage(msg *pubsub.Message)              {}
-func (m rawTracer) DeliverMessage(msg *pubsub.Message)               {}
-func (m rawTracer) RejectMessage(msg *pubsub.Message, reason string) {}
-func (m rawTracer) DuplicateMessage(msg *pubsub.Message)             {}
-func (m rawTracer) ThrottlePeer(p peer.ID)                           {}
-func (m rawTracer) UndeliverableMessage(msg *pubsub.Message)         {}
-func (m rawTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)               {}
+func (m rawTracer) AddPeer(p peer.ID, proto protocol.ID)     {}
+func (m rawTracer) RemovePeer(p peer.ID)                     {}
+func (m rawTracer) Join(topic string)                        {}
+func (m rawTracer) Leave(topic string)                       {}
+func (m rawTracer) Graft(p peer.ID, topic string)            {}
+func (m rawTracer) Prune(p peer.ID, topic string)            {}
+func (m rawTracer) ValidateMessage(msg *pubsub.Message)      {}
+func (m rawTracer) DeliverMessage(msg *pubsub.Message)       {}
+func (m rawTracer) DuplicateMessage(msg *pubsub.Message)     {}
+func (m rawTracer) UndeliverableMessage(msg *pubsub.Message) {}
+func (m rawTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)       {}
+
+func (m rawTracer) RejectMessage(msg *pubsub.Message, reason string) {
+	if reason == pubsub.RejectValidationIgnored {
+		ignoredPubsubMeter.Mark(1)
+		return
+	}
+	rejectedPubsubMeter.Mark(1)
+	log.Debug("Rejected pubsub message", "topic", msg.GetTopic(), "peer", msg.ReceivedFrom, "reason", reason)
+}
+
+func (m rawTracer) ThrottlePeer(p peer.ID) {
+	throttledPubsubMeter.Mark(1)
+	log.Debug("Throttled pubsub peer", "peer", p)
+}
 
 func (m rawTracer) RecvRPC(rpc *pubsub.RPC) {
 	egressPubsubTrafficMeter.Mark(int64(rpc.Size()))
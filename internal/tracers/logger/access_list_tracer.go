@@ -18,10 +18,10 @@ package logger
 
 import (
 	"github.com/amazechain/amc/common/transaction"
-	"math/big"
 
 	common "github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/internal/vm"
+	"github.com/holiman/uint256"
 )
 
 // accessList is an accumulator for the set of accounts and storage slots an EVM
@@ -132,7 +132,7 @@ func NewAccessListTracer(acl transaction.AccessList, from, to common.Address, pr
 	}
 }
 
-func (a *AccessListTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+func (a *AccessListTracer) CaptureStart(env vm.VMInterface, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *uint256.Int) {
 }
 
 // CaptureState captures all opcodes that touch storage or addresses and adds them to the accesslist.
@@ -163,7 +163,7 @@ func (*AccessListTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64,
 
 func (*AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
 
-func (*AccessListTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+func (*AccessListTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *uint256.Int) {
 }
 
 func (*AccessListTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
@@ -19,9 +19,8 @@ package txspool
 import (
 	"context"
 	"fmt"
-	"github.com/amazechain/amc/utils"
-	"github.com/golang/protobuf/proto"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/amazechain/amc/api/protocol/sync_proto"
@@ -31,80 +30,139 @@ import (
 	"github.com/amazechain/amc/common/transaction"
 	"github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/log"
+	event "github.com/amazechain/amc/modules/event/v2"
+	"github.com/amazechain/amc/utils"
+	mapset "github.com/deckarep/golang-set"
+	"github.com/golang/protobuf/proto"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"go.uber.org/zap"
 )
 
 const (
-	BloomFetcherMaxTime      = 10 * time.Minute
-	BloomSendTransactionTime = 10 * time.Second
-	BloomSendMaxTransactions = 100
+	// txAnnounceLimit is the maximum number of hashes in one announcement.
+	txAnnounceLimit = 4096
+
+	// txRetrievalLimit is the maximum number of transactions requested from,
+	// or served to, a peer in one message.
+	txRetrievalLimit = 256
+
+	// txRetrievalSize is the soft size limit of the transactions served in one
+	// response.
+	txRetrievalSize = 128 * 1024
+
+	// txFetchTimeout is how long a requested transaction is waited for before
+	// it is requested from another peer that announced it.
+	txFetchTimeout = 5 * time.Second
+
+	// txFetchInterval is how often announced transactions are requested.
+	txFetchInterval = 100 * time.Millisecond
+
+	// txKnownLimit is the number of transaction hashes remembered per peer, so
+	// that transactions are not announced back to peers that have them.
+	txKnownLimit = 32768
+
+	// txAnnouncedLimit is the number of announced transactions waiting to be
+	// requested, announcements above it are dropped.
+	txAnnouncedLimit = 65536
+
+	// txPeerRateLimit is the number of hashes a peer may announce and the
+	// number of transactions it may request per txPeerRateInterval. Anything
+	// above is ignored.
+	txPeerRateLimit    = 8192
+	txPeerRateInterval = time.Second
 )
 
 var (
 	ErrBadPeer = fmt.Errorf("bad peer error")
 )
 
-// txsRequest
-type txsRequest struct {
-	hashes hashes       //
-	bloom  *types.Bloom //
-	peer   peer.ID      //
+// txsPeer is the transaction propagation state of a connected peer.
+type txsPeer struct {
+	id    peer.ID
+	known mapset.Set // hashes of the transactions the peer is known to have
+
+	fetching int // number of transactions requested from the peer and not delivered
+
+	announced rateWindow // announcements received from the peer
+	requested rateWindow // transactions requested by the peer
 }
 
-type hashes []types.Hash
+func newTxsPeer(id peer.ID) *txsPeer {
+	return &txsPeer{id: id, known: mapset.NewSet()}
+}
 
-func (h hashes) pop() types.Hash {
-	old := h
-	n := len(old)
-	x := old[n-1]
-	h = old[0 : n-1]
-	return x
+// markKnown records that the peer has the transaction, forgetting arbitrary
+// ones above txKnownLimit.
+func (p *txsPeer) markKnown(hash types.Hash) {
+	for p.known.Cardinality() >= txKnownLimit {
+		p.known.Pop()
+	}
+	p.known.Add(hash)
 }
 
-type TxsFetcher struct {
-	quit chan struct{}
+// rateWindow counts the items accepted from a peer during the current
+// txPeerRateInterval.
+type rateWindow struct {
+	start time.Time
+	used  int
+}
 
-	finished  bool
-	peers     common.PeerMap
-	p2pServer common.INetwork
+// allow returns how many of n items fit into the rest of the budget of the
+// current interval and takes them from the budget.
+func (w *rateWindow) allow(n int, now time.Time) int {
+	if now.Sub(w.start) >= txPeerRateInterval {
+		w.start, w.used = now, 0
+	}
+	if left := txPeerRateLimit - w.used; n > left {
+		n = left
+	}
+	w.used += n
+	return n
+}
 
-	peerRequests map[peer.ID]*txsRequest // other peer requests
+// txFetch is a transaction requested from a peer.
+type txFetch struct {
+	peer peer.ID
+	time time.Time
+}
 
-	bloom   *types.Bloom
-	fetched map[types.Hash]bool //
+// TxsFetcher propagates transactions between peers. New transactions of the
+// pool are announced by hash to the peers not known to have them, and the
+// announced transactions missing from the pool are requested by hash from one
+// of the peers that announced them. A request is retried with another of
+// these peers if it is not answered within txFetchTimeout.
+type TxsFetcher struct {
+	peers     common.PeerMap
+	p2pServer common.INetwork
 
 	getTx      func(hash types.Hash) *transaction.Transaction
 	addTxs     func([]*transaction.Transaction) []error
 	pendingTxs func(enforceTips bool) map[types.Address][]*transaction.Transaction
 
-	// fetchTxs
-	//fetchTxs func(string, []types.Hash)
-	// ch
-	peerJoinCh chan *common.PeerJoinEvent
-	peerDropCh chan *common.PeerDropEvent
-	//
+	lock      sync.Mutex
+	txsPeers  map[peer.ID]*txsPeer
+	announces map[types.Hash]map[peer.ID]struct{} // announced transactions waiting to be requested, by the peers announcing them
+	fetching  map[types.Hash]*txFetch             // requested transactions waiting to be delivered
+
+	fetchCh chan struct{}
 
 	ctx    context.Context
 	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-func NewTxsFetcher(ctx context.Context, getTx func(hash types.Hash) *transaction.Transaction, addTxs func([]*transaction.Transaction) []error, pendingTxs func(enforceTips bool) map[types.Address][]*transaction.Transaction, p2pServer common.INetwork, peers common.PeerMap, bloom *types.Bloom) *TxsFetcher {
+func NewTxsFetcher(ctx context.Context, getTx func(hash types.Hash) *transaction.Transaction, addTxs func([]*transaction.Transaction) []error, pendingTxs func(enforceTips bool) map[types.Address][]*transaction.Transaction, p2pServer common.INetwork, peers common.PeerMap) *TxsFetcher {
 
 	c, cancel := context.WithCancel(ctx)
 	f := &TxsFetcher{
-		quit:         make(chan struct{}),
-		peers:        peers,
-		peerRequests: make(map[peer.ID]*txsRequest),
-		fetched:      make(map[types.Hash]bool),
-		p2pServer:    p2pServer,
-		addTxs:       addTxs,
-		getTx:        getTx,
-		pendingTxs:   pendingTxs,
-		bloom:        bloom,
-		peerJoinCh:   make(chan *common.PeerJoinEvent, 10),
-		peerDropCh:   make(chan *common.PeerDropEvent, 10),
-		finished:     false,
+		peers:      peers,
+		p2pServer:  p2pServer,
+		addTxs:     addTxs,
+		getTx:      getTx,
+		pendingTxs: pendingTxs,
+		txsPeers:   make(map[peer.ID]*txsPeer),
+		announces:  make(map[types.Hash]map[peer.ID]struct{}),
+		fetching:   make(map[types.Hash]*txFetch),
+		fetchCh:    make(chan struct{}, 1),
 
 		ctx:    c,
 		cancel: cancel,
@@ -113,80 +171,237 @@ func NewTxsFetcher(ctx context.Context, getTx func(hash types.Hash) *transaction
 	return f
 }
 
-func (f TxsFetcher) Start() error {
-	go f.sendBloomTransactionLoop()
-	go f.bloomBroadcastLoop()
+func (f *TxsFetcher) Start() error {
+	f.lock.Lock()
+	for id := range f.peers {
+		f.txsPeers[id] = newTxsPeer(id)
+	}
+	f.lock.Unlock()
+
+	f.wg.Add(2)
+	go f.broadcastLoop()
+	go f.fetchLoop()
 	return nil
 }
 
-// sendBloomTransactionLoop
-func (f TxsFetcher) sendBloomTransactionLoop() {
-	tick := time.NewTicker(BloomSendTransactionTime)
+func (f *TxsFetcher) Stop() {
+	f.cancel()
+	f.wg.Wait()
+}
 
-	select {
-	case <-tick.C:
-		for ID, req := range f.peerRequests {
-			if p, ok := f.peers[ID]; ok == true {
+// broadcastLoop announces the new transactions of the pool, and the pending
+// transactions to newly connected peers.
+func (f *TxsFetcher) broadcastLoop() {
+	defer f.wg.Done()
+
+	txsCh := make(chan common.NewTxsEvent, 64)
+	txsSub := event.GlobalEvent.Subscribe(txsCh)
+	defer txsSub.Unsubscribe()
+	joinCh := make(chan common.PeerJoinEvent, 16)
+	joinSub := event.GlobalEvent.Subscribe(joinCh)
+	defer joinSub.Unsubscribe()
+	dropCh := make(chan common.PeerDropEvent, 16)
+	dropSub := event.GlobalEvent.Subscribe(dropCh)
+	defer dropSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-txsCh:
+			hashes := make([]types.Hash, 0, len(ev.Txs))
+			for _, tx := range ev.Txs {
+				hashes = append(hashes, tx.Hash())
+			}
+			f.announce(hashes, "")
 
-				var txs []*types_pb.Transaction
+		case ev := <-joinCh:
+			f.lock.Lock()
+			if _, ok := f.txsPeers[ev.Peer]; !ok {
+				f.txsPeers[ev.Peer] = newTxsPeer(ev.Peer)
+			}
+			f.lock.Unlock()
 
-				for i := 0; i < BloomSendMaxTransactions; i++ {
-					hash := req.hashes.pop()
-					tx := f.getTx(hash)
-					txs = append(txs, tx.ToProtoMessage().(*types_pb.Transaction))
-				}
-				msg := &sync_proto.SyncTask{
-					Id:       rand.Uint64(),
-					Ok:       true,
-					SyncType: sync_proto.SyncType_TransactionRes,
-					Payload: &sync_proto.SyncTask_SyncTransactionResponse{
-						SyncTransactionResponse: &sync_proto.SyncTransactionResponse{
-							Transactions: txs,
-						},
-					},
+			var hashes []types.Hash
+			for _, txs := range f.pendingTxs(false) {
+				for _, tx := range txs {
+					hashes = append(hashes, tx.Hash())
 				}
-				data, _ := proto.Marshal(msg)
-				p.WriteMsg(message.MsgTransaction, data)
 			}
+			f.announce(hashes, ev.Peer)
+
+		case ev := <-dropCh:
+			f.dropPeer(ev.Peer)
+
+		case <-txsSub.Err():
+			return
+		case <-f.ctx.Done():
+			return
 		}
-	case <-f.ctx.Done():
-		return
 	}
 }
 
-func (f TxsFetcher) bloomBroadcastLoop() {
-	if f.bloom == nil {
-		return
+// fetchLoop requests the announced transactions and retries the requests
+// that timed out.
+func (f *TxsFetcher) fetchLoop() {
+	defer f.wg.Done()
+
+	tick := time.NewTicker(txFetchInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			f.scheduleFetches()
+		case <-f.fetchCh:
+			f.scheduleFetches()
+		case <-f.ctx.Done():
+			return
+		}
 	}
-	tick := time.NewTicker(BloomFetcherMaxTime)
-	bloom, err := f.bloom.Marshal()
-	if err != nil {
-		log.Warn("txs fetcher bloom Marshal err", zap.Error(err))
+}
+
+// announce sends the hashes to the given peer, or to all peers if to is
+// empty, leaving out those the peers are known to have.
+func (f *TxsFetcher) announce(hashes []types.Hash, to peer.ID) {
+	if len(hashes) == 0 {
 		return
 	}
+	announces := make(map[peer.ID][]types.Hash)
 
-	msg := &sync_proto.SyncTask{
-		Id:       rand.Uint64(),
-		Ok:       true,
-		SyncType: sync_proto.SyncType_TransactionReq,
-		Payload: &sync_proto.SyncTask_SyncTransactionRequest{
-			SyncTransactionRequest: &sync_proto.SyncTransactionRequest{
-				Bloom: bloom,
+	f.lock.Lock()
+	for id, p := range f.txsPeers {
+		if to != "" && id != to {
+			continue
+		}
+		for _, hash := range hashes {
+			if !p.known.Contains(hash) {
+				p.markKnown(hash)
+				announces[id] = append(announces[id], hash)
+			}
+		}
+	}
+	f.lock.Unlock()
+
+	for id, hashes := range announces {
+		for len(hashes) > 0 {
+			n := len(hashes)
+			if n > txAnnounceLimit {
+				n = txAnnounceLimit
+			}
+			msg := &sync_proto.SyncTask{
+				Id:       rand.Uint64(),
+				Ok:       true,
+				SyncType: sync_proto.SyncType_TransactionAnnounce,
+				Payload: &sync_proto.SyncTask_SyncTransactionAnnounce{
+					SyncTransactionAnnounce: &sync_proto.SyncTransactionAnnounce{
+						Hashes: hashesToH256(hashes[:n]),
+					},
+				},
+			}
+			if err := f.send(id, msg); nil != err {
+				log.Debug("Failed to announce transactions", "peer", id, "count", n, "err", err)
+				break
+			}
+			hashes = hashes[n:]
+		}
+	}
+}
+
+// scheduleFetches requests the announced transactions not yet requested, each
+// from one of the peers that announced it, and makes the requests that timed
+// out available to be requested from another peer.
+func (f *TxsFetcher) scheduleFetches() {
+	now := time.Now()
+	requests := make(map[peer.ID][]types.Hash)
+
+	f.lock.Lock()
+	for hash, fetch := range f.fetching {
+		if now.Sub(fetch.time) > txFetchTimeout {
+			if p := f.txsPeers[fetch.peer]; p != nil {
+				p.fetching--
+			}
+			delete(f.fetching, hash)
+		}
+	}
+	for hash, announcers := range f.announces {
+		if _, ok := f.fetching[hash]; ok {
+			continue
+		}
+		if len(announcers) == 0 || f.getTx(hash) != nil {
+			delete(f.announces, hash)
+			continue
+		}
+		for id := range announcers {
+			p := f.txsPeers[id]
+			if p == nil {
+				delete(announcers, id)
+				continue
+			}
+			if p.fetching >= txRetrievalLimit {
+				continue
+			}
+			p.fetching++
+			delete(announcers, id)
+			f.fetching[hash] = &txFetch{peer: id, time: now}
+			requests[id] = append(requests[id], hash)
+			break
+		}
+	}
+	f.lock.Unlock()
+
+	for id, hashes := range requests {
+		msg := &sync_proto.SyncTask{
+			Id:       rand.Uint64(),
+			Ok:       true,
+			SyncType: sync_proto.SyncType_TransactionReq,
+			Payload: &sync_proto.SyncTask_SyncTransactionRequest{
+				SyncTransactionRequest: &sync_proto.SyncTransactionRequest{
+					Hashes: hashesToH256(hashes),
+				},
 			},
-		},
+		}
+		if err := f.send(id, msg); nil != err {
+			// Left to time out and be requested from another peer
+			log.Debug("Failed to request transactions", "peer", id, "count", len(hashes), "err", err)
+		}
 	}
-	request, _ := proto.Marshal(msg)
-	select {
-	case peerId := <-f.peerJoinCh:
-		f.peers[peerId.Peer].WriteMsg(message.MsgTransaction, request)
-	case <-tick.C:
-	case <-f.ctx.Done():
-		return
+}
+
+// dropPeer forgets a disconnected peer. The transactions requested from it are
+// requested again from other peers that announced them.
+func (f *TxsFetcher) dropPeer(id peer.ID) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	delete(f.txsPeers, id)
+	for hash, announcers := range f.announces {
+		delete(announcers, id)
+		if len(announcers) == 0 {
+			if _, ok := f.fetching[hash]; !ok {
+				delete(f.announces, hash)
+			}
+		}
+	}
+	for hash, fetch := range f.fetching {
+		if fetch.peer == id {
+			delete(f.fetching, hash)
+		}
+	}
+}
+
+func (f *TxsFetcher) send(id peer.ID, msg *sync_proto.SyncTask) error {
+	p, ok := f.peers[id]
+	if !ok {
+		return ErrBadPeer
 	}
+	data, err := proto.Marshal(msg)
+	if nil != err {
+		return err
+	}
+	return p.WriteMsg(message.MsgTransaction, data)
 }
 
 // ConnHandler handler peer message
-func (f TxsFetcher) ConnHandler(data []byte, ID peer.ID) error {
+func (f *TxsFetcher) ConnHandler(data []byte, ID peer.ID) error {
 	_, ok := f.peers[ID]
 	if !ok {
 		return ErrBadPeer
@@ -194,57 +409,192 @@ func (f TxsFetcher) ConnHandler(data []byte, ID peer.ID) error {
 
 	syncTask := sync_proto.SyncTask{}
 	if err := proto.Unmarshal(data, &syncTask); err != nil {
-		log.Errorf("receive sync task(headersResponse) msg err: %v", err)
+		log.Errorf("receive sync task(transaction) msg err: %v", err)
 		return err
 	}
 
 	//taskID := syncTask.Id
-	log.Debugf("receive synctask msg from :%v, task type: %v, ok:%v", ID, syncTask.SyncType, syncTask.Ok)
+	log.Tracef("receive synctask msg from :%v, task type: %v, ok:%v", ID, syncTask.SyncType, syncTask.Ok)
 
 	switch syncTask.SyncType {
+	case sync_proto.SyncType_TransactionAnnounce:
+		announce := syncTask.GetSyncTransactionAnnounce()
+		if announce == nil {
+			return ErrBadPeer
+		}
+		f.handleAnnounce(ID, h256ToHashes(announce.Hashes))
 
 	case sync_proto.SyncType_TransactionReq:
-		request := syncTask.Payload.(*sync_proto.SyncTask_SyncTransactionRequest).SyncTransactionRequest
-		if _, ok := f.peerRequests[ID]; ok == false {
-
-			bloom := new(types.Bloom)
-			err := bloom.UnMarshalBloom(request.Bloom)
-			if err == nil {
-				var txs []*transaction.Transaction
-				var hashes []types.Hash
-				pending := f.pendingTxs(false)
-				for _, batch := range pending {
-					txs = append(txs, batch...)
-				}
-				for _, tx := range txs {
-					hash := tx.Hash()
-					if !bloom.Contain(hash.Bytes()) {
-						hashes = append(hashes, hash)
-					}
-				}
-				f.peerRequests[ID] = &txsRequest{
-					bloom:  bloom,
-					hashes: hashes,
-					peer:   ID,
-				}
-			}
+		request := syncTask.GetSyncTransactionRequest()
+		if request == nil {
+			return ErrBadPeer
 		}
+		f.handleRequest(ID, syncTask.Id, h256ToHashes(request.Hashes))
 
 	case sync_proto.SyncType_TransactionRes:
-		response := syncTask.Payload.(*sync_proto.SyncTask_SyncTransactionResponse).SyncTransactionResponse
-		var txs []*transaction.Transaction
-		for _, tranPb := range response.Transactions {
-			if _, ok := f.fetched[utils.ConvertH256ToHash(tranPb.Hash)]; ok == false {
-				f.fetched[utils.ConvertH256ToHash(tranPb.Hash)] = true
-				tx, err := transaction.FromProtoMessage(tranPb)
-				if err == nil {
-					txs = append(txs, tx)
-				}
+		response := syncTask.GetSyncTransactionResponse()
+		if response == nil {
+			return ErrBadPeer
+		}
+		f.handleResponse(ID, response.Transactions)
+	}
+	return nil
+}
+
+// handleAnnounce queues the announced transactions missing from the pool to
+// be requested from the peer.
+func (f *TxsFetcher) handleAnnounce(id peer.ID, hashes []types.Hash) {
+	f.lock.Lock()
+	p := f.txsPeers[id]
+	if p == nil {
+		f.lock.Unlock()
+		return
+	}
+	if n := p.announced.allow(len(hashes), time.Now()); n < len(hashes) {
+		log.Debug("Dropping transaction announcements over the rate limit", "peer", id, "count", len(hashes)-n)
+		hashes = hashes[:n]
+	}
+	queued := 0
+	for _, hash := range hashes {
+		p.markKnown(hash)
+		if f.getTx(hash) != nil {
+			continue
+		}
+		announcers, ok := f.announces[hash]
+		if !ok {
+			if _, fetching := f.fetching[hash]; !fetching && len(f.announces) >= txAnnouncedLimit {
+				continue
 			}
+			announcers = make(map[peer.ID]struct{})
+			f.announces[hash] = announcers
 		}
-		if len(txs) > 0 {
-			f.addTxs(txs)
+		announcers[id] = struct{}{}
+		queued++
+	}
+	f.lock.Unlock()
+
+	if queued > 0 {
+		select {
+		case f.fetchCh <- struct{}{}:
+		default:
 		}
 	}
-	return nil
+}
+
+// handleRequest sends the peer the requested transactions the pool has.
+func (f *TxsFetcher) handleRequest(id peer.ID, taskID uint64, hashes []types.Hash) {
+	if len(hashes) > txRetrievalLimit {
+		hashes = hashes[:txRetrievalLimit]
+	}
+
+	f.lock.Lock()
+	p := f.txsPeers[id]
+	if p == nil {
+		f.lock.Unlock()
+		return
+	}
+	if n := p.requested.allow(len(hashes), time.Now()); n < len(hashes) {
+		log.Debug("Dropping transaction requests over the rate limit", "peer", id, "count", len(hashes)-n)
+		hashes = hashes[:n]
+	}
+	f.lock.Unlock()
+
+	var (
+		txs  []*types_pb.Transaction
+		sent []types.Hash
+		size int
+	)
+	for _, hash := range hashes {
+		if size >= txRetrievalSize {
+			break
+		}
+		tx := f.getTx(hash)
+		if tx == nil {
+			continue
+		}
+		pbTx := tx.ToProtoMessage().(*types_pb.Transaction)
+		txs = append(txs, pbTx)
+		sent = append(sent, hash)
+		size += proto.Size(pbTx)
+	}
+	if len(txs) == 0 {
+		return
+	}
+
+	f.lock.Lock()
+	for _, hash := range sent {
+		p.markKnown(hash)
+	}
+	f.lock.Unlock()
+
+	msg := &sync_proto.SyncTask{
+		Id:       taskID,
+		Ok:       true,
+		SyncType: sync_proto.SyncType_TransactionRes,
+		Payload: &sync_proto.SyncTask_SyncTransactionResponse{
+			SyncTransactionResponse: &sync_proto.SyncTransactionResponse{
+				Transactions: txs,
+			},
+		},
+	}
+	if err := f.send(id, msg); nil != err {
+		log.Debug("Failed to deliver transactions", "peer", id, "count", len(txs), "err", err)
+	}
+}
+
+// handleResponse adds the delivered transactions requested from the peer to
+// the pool. Transactions that were not requested from it are dropped.
+func (f *TxsFetcher) handleResponse(id peer.ID, pbTxs []*types_pb.Transaction) {
+	var (
+		txs       []*transaction.Transaction
+		unrequest int
+	)
+
+	f.lock.Lock()
+	p := f.txsPeers[id]
+	for _, pbTx := range pbTxs {
+		tx, err := transaction.FromProtoMessage(pbTx)
+		if nil != err {
+			log.Debug("Failed to decode delivered transaction", "peer", id, "err", err)
+			continue
+		}
+		hash := tx.Hash()
+		if fetch, ok := f.fetching[hash]; !ok || fetch.peer != id {
+			unrequest++
+			continue
+		}
+		delete(f.fetching, hash)
+		delete(f.announces, hash)
+		if p != nil {
+			p.fetching--
+			p.markKnown(hash)
+		}
+		txs = append(txs, tx)
+	}
+	f.lock.Unlock()
+
+	if unrequest > 0 {
+		log.Debug("Dropping unrequested transactions", "peer", id, "count", unrequest)
+	}
+	if len(txs) > 0 {
+		f.addTxs(txs)
+	}
+}
+
+func hashesToH256(hashes []types.Hash) []*types_pb.H256 {
+	h256s := make([]*types_pb.H256, len(hashes))
+	for i, hash := range hashes {
+		h256s[i] = utils.ConvertHashToH256(hash)
+	}
+	return h256s
+}
+
+func h256ToHashes(h256s []*types_pb.H256) []types.Hash {
+	hashes := make([]types.Hash, 0, len(h256s))
+	for _, h := range h256s {
+		if h != nil {
+			hashes = append(hashes, utils.ConvertH256ToHash(h))
+		}
+	}
+	return hashes
 }
@@ -0,0 +1,299 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package txspool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amazechain/amc/api/protocol/sync_proto"
+	"github.com/amazechain/amc/api/protocol/types_pb"
+	"github.com/amazechain/amc/common"
+	"github.com/amazechain/amc/common/message"
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+	"github.com/golang/protobuf/proto"
+	"github.com/holiman/uint256"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// testPeer records the sync tasks sent to it.
+type testPeer struct {
+	id peer.ID
+
+	lock sync.Mutex
+	sent []*sync_proto.SyncTask
+}
+
+func (p *testPeer) ID() peer.ID                                              { return p.id }
+func (p *testPeer) Write(msg message.IMessage) error                         { return nil }
+func (p *testPeer) SetHandler(message.MessageType, common.ConnHandler) error { return nil }
+func (p *testPeer) ClearHandler(message.MessageType) error                   { return nil }
+func (p *testPeer) Close() error                                             { return nil }
+
+func (p *testPeer) WriteMsg(messageType message.MessageType, payload []byte) error {
+	var task sync_proto.SyncTask
+	if err := proto.Unmarshal(payload, &task); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.sent = append(p.sent, &task)
+	p.lock.Unlock()
+	return nil
+}
+
+// requested returns the hashes requested from the peer since the last call.
+func (p *testPeer) requested() []types.Hash {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var hashes []types.Hash
+	for _, task := range p.sent {
+		if task.SyncType == sync_proto.SyncType_TransactionReq {
+			hashes = append(hashes, h256ToHashes(task.GetSyncTransactionRequest().Hashes)...)
+		}
+	}
+	p.sent = nil
+	return hashes
+}
+
+// testPool is a transaction pool holding the transactions added to it.
+type testPool struct {
+	lock sync.Mutex
+	txs  map[types.Hash]*transaction.Transaction
+}
+
+func (p *testPool) get(hash types.Hash) *transaction.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.txs[hash]
+}
+
+func (p *testPool) add(txs []*transaction.Transaction) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, tx := range txs {
+		p.txs[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+func (p *testPool) pending(bool) map[types.Address][]*transaction.Transaction { return nil }
+
+func newTestFetcher(t *testing.T, ids ...peer.ID) (*TxsFetcher, *testPool, map[peer.ID]*testPeer) {
+	pool := &testPool{txs: make(map[types.Hash]*transaction.Transaction)}
+	peers := make(common.PeerMap)
+	testPeers := make(map[peer.ID]*testPeer)
+	for _, id := range ids {
+		p := &testPeer{id: id}
+		peers[id] = common.Peer{IPeer: p}
+		testPeers[id] = p
+	}
+	f := NewTxsFetcher(context.Background(), pool.get, pool.add, pool.pending, nil, peers)
+	for _, id := range ids {
+		f.txsPeers[id] = newTxsPeer(id)
+	}
+	t.Cleanup(f.cancel)
+	return f, pool, testPeers
+}
+
+func newTestTx(nonce uint64) *transaction.Transaction {
+	to := types.Address{0x01}
+	return transaction.NewTx(&transaction.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: uint256.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		From:     &types.Address{0x02},
+		Value:    uint256.NewInt(1),
+		V:        uint256.NewInt(27),
+		R:        uint256.NewInt(1),
+		S:        uint256.NewInt(1),
+	})
+}
+
+// deliver sends the transactions to the fetcher as a response from the peer.
+func deliver(t *testing.T, f *TxsFetcher, from peer.ID, txs ...*transaction.Transaction) {
+	pbTxs := make([]*types_pb.Transaction, len(txs))
+	for i, tx := range txs {
+		pbTxs[i] = tx.ToProtoMessage().(*types_pb.Transaction)
+	}
+	data, err := proto.Marshal(&sync_proto.SyncTask{
+		Ok:       true,
+		SyncType: sync_proto.SyncType_TransactionRes,
+		Payload: &sync_proto.SyncTask_SyncTransactionResponse{
+			SyncTransactionResponse: &sync_proto.SyncTransactionResponse{Transactions: pbTxs},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ConnHandler(data, from); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxsFetcherAnnounceFetch(t *testing.T) {
+	f, pool, peers := newTestFetcher(t, "a")
+	tx := newTestTx(0)
+
+	f.handleAnnounce("a", []types.Hash{tx.Hash()})
+	f.scheduleFetches()
+	if have := peers["a"].requested(); len(have) != 1 || have[0] != tx.Hash() {
+		t.Fatalf("requested %v, want %v", have, tx.Hash())
+	}
+	// A second round does not request the transaction again
+	f.scheduleFetches()
+	if have := peers["a"].requested(); len(have) != 0 {
+		t.Fatalf("requested %v again", have)
+	}
+
+	deliver(t, f, "a", tx)
+	if pool.get(tx.Hash()) == nil {
+		t.Fatal("delivered transaction not added to the pool")
+	}
+	if len(f.announces) != 0 || len(f.fetching) != 0 {
+		t.Errorf("fetcher not cleaned up: %d announced, %d fetching", len(f.announces), len(f.fetching))
+	}
+	if f.txsPeers["a"].fetching != 0 {
+		t.Errorf("peer still has %d transactions in flight", f.txsPeers["a"].fetching)
+	}
+}
+
+func TestTxsFetcherTimeoutRetry(t *testing.T) {
+	f, pool, peers := newTestFetcher(t, "a", "b")
+	tx := newTestTx(0)
+
+	f.handleAnnounce("a", []types.Hash{tx.Hash()})
+	f.handleAnnounce("b", []types.Hash{tx.Hash()})
+	f.scheduleFetches()
+
+	first, second := peer.ID("a"), peer.ID("b")
+	if len(peers["b"].requested()) == 1 {
+		first, second = second, first
+	} else if len(peers["a"].requested()) != 1 {
+		t.Fatal("transaction not requested")
+	}
+
+	// Nothing is retried before the request times out
+	f.scheduleFetches()
+	if have := peers[second].requested(); len(have) != 0 {
+		t.Fatalf("retried %v before the timeout", have)
+	}
+
+	f.fetching[tx.Hash()].time = time.Now().Add(-txFetchTimeout - time.Second)
+	f.scheduleFetches()
+	if have := peers[second].requested(); len(have) != 1 || have[0] != tx.Hash() {
+		t.Fatalf("retried %v, want %v", have, tx.Hash())
+	}
+	if f.txsPeers[first].fetching != 0 {
+		t.Errorf("timed out peer still has %d transactions in flight", f.txsPeers[first].fetching)
+	}
+
+	// The late delivery of the timed out peer is dropped
+	deliver(t, f, first, tx)
+	if pool.get(tx.Hash()) != nil {
+		t.Fatal("late delivery added to the pool")
+	}
+	deliver(t, f, second, tx)
+	if pool.get(tx.Hash()) == nil {
+		t.Fatal("retried delivery not added to the pool")
+	}
+}
+
+func TestTxsFetcherAnnounceRateLimit(t *testing.T) {
+	f, _, _ := newTestFetcher(t, "a")
+
+	hashes := make([]types.Hash, txPeerRateLimit+10)
+	for i := range hashes {
+		hashes[i] = newTestTx(uint64(i)).Hash()
+	}
+	f.handleAnnounce("a", hashes)
+	if len(f.announces) != txPeerRateLimit {
+		t.Fatalf("queued %d announcements, want %d", len(f.announces), txPeerRateLimit)
+	}
+	if _, ok := f.announces[hashes[txPeerRateLimit]]; ok {
+		t.Fatal("announcement over the rate limit queued")
+	}
+
+	// The budget is restored once the interval passes
+	f.txsPeers["a"].announced.start = time.Now().Add(-txPeerRateInterval)
+	f.handleAnnounce("a", hashes[txPeerRateLimit:])
+	if len(f.announces) != len(hashes) {
+		t.Fatalf("queued %d announcements, want %d", len(f.announces), len(hashes))
+	}
+}
+
+func TestRateWindow(t *testing.T) {
+	var (
+		w   rateWindow
+		now = time.Now()
+	)
+	if n := w.allow(txPeerRateLimit-1, now); n != txPeerRateLimit-1 {
+		t.Fatalf("allowed %d, want %d", n, txPeerRateLimit-1)
+	}
+	if n := w.allow(2, now.Add(txPeerRateInterval/2)); n != 1 {
+		t.Fatalf("allowed %d over the budget, want 1", n)
+	}
+	if n := w.allow(1, now.Add(txPeerRateInterval)); n != 1 {
+		t.Fatalf("allowed %d in a new interval, want 1", n)
+	}
+}
+
+func TestTxsFetcherUnrequestedDelivery(t *testing.T) {
+	f, pool, peers := newTestFetcher(t, "a", "b")
+	requested, unrequested := newTestTx(0), newTestTx(1)
+
+	// Transactions never announced are dropped
+	deliver(t, f, "a", unrequested)
+	if pool.get(unrequested.Hash()) != nil {
+		t.Fatal("unrequested transaction added to the pool")
+	}
+
+	// Transactions requested from another peer are dropped
+	f.handleAnnounce("a", []types.Hash{requested.Hash()})
+	f.scheduleFetches()
+	if len(peers["a"].requested()) != 1 {
+		t.Fatal("transaction not requested")
+	}
+	deliver(t, f, "b", requested)
+	if pool.get(requested.Hash()) != nil {
+		t.Fatal("transaction delivered by another peer added to the pool")
+	}
+	if _, ok := f.fetching[requested.Hash()]; !ok {
+		t.Fatal("request cancelled by another peer's delivery")
+	}
+	deliver(t, f, "a", requested, unrequested)
+	if pool.get(requested.Hash()) == nil {
+		t.Fatal("requested transaction not added to the pool")
+	}
+	if pool.get(unrequested.Hash()) != nil {
+		t.Fatal("unrequested transaction added to the pool")
+	}
+}
+
+func TestTxsPeerKnownLimit(t *testing.T) {
+	p := newTxsPeer("a")
+	for i := 0; i < txKnownLimit+10; i++ {
+		p.markKnown(types.Hash{byte(i), byte(i >> 8), byte(i >> 16)})
+	}
+	if n := p.known.Cardinality(); n != txKnownLimit {
+		t.Fatalf("known %d hashes, want %d", n, txKnownLimit)
+	}
+}
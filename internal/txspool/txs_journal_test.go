@@ -0,0 +1,112 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package txspool
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amazechain/amc/common/transaction"
+	"github.com/amazechain/amc/common/types"
+)
+
+// replayJournal loads the journal at path into an empty pool.
+func replayJournal(t *testing.T, path string) (*testPool, error) {
+	t.Helper()
+	pool := &testPool{txs: make(map[types.Hash]*transaction.Transaction)}
+	journal := newTxJournal(path)
+	err := journal.load(pool.add)
+	if err := journal.insert(newTestTx(100)); !errors.Is(err, errNoActiveJournal) {
+		t.Errorf("insert after load: have %v, want %v", err, errNoActiveJournal)
+	}
+	return pool, err
+}
+
+// checkPool checks that the pool holds exactly the given transactions.
+func checkPool(t *testing.T, pool *testPool, want ...*transaction.Transaction) {
+	t.Helper()
+	if len(pool.txs) != len(want) {
+		t.Errorf("have %d transactions, want %d", len(pool.txs), len(want))
+	}
+	for _, tx := range want {
+		if pool.get(tx.Hash()) == nil {
+			t.Errorf("transaction %d missing", tx.Nonce())
+		}
+	}
+}
+
+func TestTxJournalRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.journal")
+	txs := []*transaction.Transaction{newTestTx(0), newTestTx(1), newTestTx(2)}
+
+	// A missing journal loads nothing
+	pool, err := replayJournal(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPool(t, pool)
+
+	journal := newTxJournal(path)
+	if err := journal.rotate(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range txs[:2] {
+		if err := journal.insert(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journal.close(); err != nil {
+		t.Fatal(err)
+	}
+	if pool, err = replayJournal(t, path); err != nil {
+		t.Fatal(err)
+	}
+	checkPool(t, pool, txs[0], txs[1])
+
+	// Rotating drops the transactions no longer in the pool
+	journal = newTxJournal(path)
+	if err := journal.rotate(map[types.Address][]*transaction.Transaction{{0x02}: {txs[1]}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.insert(txs[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.close(); err != nil {
+		t.Fatal(err)
+	}
+	if pool, err = replayJournal(t, path); err != nil {
+		t.Fatal(err)
+	}
+	checkPool(t, pool, txs[1], txs[2])
+
+	// The entries before a truncated one are still loaded
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0x10, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if pool, err = replayJournal(t, path); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated journal: have %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	checkPool(t, pool, txs[1], txs[2])
+}
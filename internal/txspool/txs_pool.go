@@ -84,8 +84,10 @@ type txspoolResetRequest struct {
 }
 
 type TxsPoolConfig struct {
-	Locals   []types.Address
-	NoLocals bool
+	Locals    []types.Address
+	NoLocals  bool
+	Journal   string        // Journal of local transactions to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
 
 	PriceLimit uint64
 	PriceBump  uint64
@@ -98,8 +100,49 @@ type TxsPoolConfig struct {
 	Lifetime time.Duration
 }
 
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *TxsPoolConfig) sanitize() TxsPoolConfig {
+	conf := *config
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
+	if conf.PriceLimit < 1 {
+		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
+		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
+	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
+		conf.PriceBump = DefaultTxPoolConfig.PriceBump
+	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool account slots", "provided", conf.AccountSlots, "updated", DefaultTxPoolConfig.AccountSlots)
+		conf.AccountSlots = DefaultTxPoolConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid txpool global slots", "provided", conf.GlobalSlots, "updated", DefaultTxPoolConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultTxPoolConfig.GlobalSlots
+	}
+	if conf.AccountQueue < 1 {
+		log.Warn("Sanitizing invalid txpool account queue", "provided", conf.AccountQueue, "updated", DefaultTxPoolConfig.AccountQueue)
+		conf.AccountQueue = DefaultTxPoolConfig.AccountQueue
+	}
+	if conf.GlobalQueue < 1 {
+		log.Warn("Sanitizing invalid txpool global queue", "provided", conf.GlobalQueue, "updated", DefaultTxPoolConfig.GlobalQueue)
+		conf.GlobalQueue = DefaultTxPoolConfig.GlobalQueue
+	}
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
+		conf.Lifetime = DefaultTxPoolConfig.Lifetime
+	}
+	return conf
+}
+
 // DefaultTxPoolConfig default blockchain
 var DefaultTxPoolConfig = TxsPoolConfig{
+	Journal:   "transactions.journal",
+	Rejournal: time.Hour,
 
 	PriceLimit: 1,
 	PriceBump:  10,
@@ -132,7 +175,8 @@ type TxsPool struct {
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 	shanghai bool // Fork indicator whether we are in the Shanghai stage.
 
-	locals   *accountSet
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	journal  *txJournal  // Journal of local transaction to back up to disk
 	pending  map[types.Address]*txsList
 	queue    map[types.Address]*txsList
 	beats    map[types.Address]time.Time
@@ -152,14 +196,15 @@ type TxsPool struct {
 	isRun uint32
 }
 
-func NewTxsPool(ctx context.Context, bc common.IBlockChain) (txs_pool.ITxsPool, error) {
+func NewTxsPool(ctx context.Context, config TxsPoolConfig, bc common.IBlockChain) (txs_pool.ITxsPool, error) {
+	config = config.sanitize()
 
 	c, cancel := context.WithCancel(ctx)
 	// for test
 	//log.Init(nil)
 	pool := &TxsPool{
 		chainconfig: bc.Config(),
-		config:      DefaultTxPoolConfig,
+		config:      config,
 		ctx:         c,
 		cancel:      cancel,
 
@@ -180,7 +225,11 @@ func NewTxsPool(ctx context.Context, bc common.IBlockChain) (txs_pool.ITxsPool,
 		queueTxEventCh:  make(chan *transaction.Transaction),
 		reorgDoneCh:     make(chan chan struct{}),
 		reorgShutdownCh: make(chan struct{}),
-		gasPrice:        uint256.NewInt(DefaultTxPoolConfig.PriceLimit),
+		gasPrice:        uint256.NewInt(config.PriceLimit),
+	}
+	for _, addr := range config.Locals {
+		log.Info("Setting new local account", "address", addr)
+		pool.locals.add(addr)
 	}
 
 	//
@@ -192,6 +241,20 @@ func NewTxsPool(ctx context.Context, bc common.IBlockChain) (txs_pool.ITxsPool,
 	pool.wg.Add(1)
 	go pool.scheduleLoop()
 
+	// If local transactions and journaling is enabled, load from disk
+	if !config.NoLocals && config.Journal != "" {
+		pool.journal = newTxJournal(config.Journal)
+
+		if err := pool.journal.load(pool.AddLocals); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		if err := pool.journal.rotate(pool.local()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+		pool.wg.Add(1)
+		go pool.journalLoop()
+	}
+
 	pool.wg.Add(1)
 	go pool.blockChangeLoop()
 
@@ -202,7 +265,7 @@ func NewTxsPool(ctx context.Context, bc common.IBlockChain) (txs_pool.ITxsPool,
 	//pool.wg.Add(1)
 	//go pool.ethImportTxPoolLoop()
 
-	pool.wg.Add(1)
+	//pool.wg.Add(1)
 	//go pool.ethTxPoolCheckLoop()
 
 	return pool, nil
@@ -460,6 +523,7 @@ func (pool *TxsPool) add(tx *transaction.Transaction, local bool) (replaced bool
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Debug("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To)
 
@@ -478,6 +542,7 @@ func (pool *TxsPool) add(tx *transaction.Transaction, local bool) (replaced bool
 		pool.locals.add(from)
 		pool.priced.Removed(pool.all.RemoteToLocals(pool.locals)) // Migrate the remotes if it's marked as local first time.
 	}
+	pool.journalTx(from, tx)
 
 	//log.Debug("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To)
 	return replaced, nil
@@ -1148,6 +1213,34 @@ func (pool *TxsPool) blockChangeLoop() {
 	}
 }
 
+// journalLoop regenerates the local transaction journal every Rejournal and
+// closes it once the pool is stopped.
+func (pool *TxsPool) journalLoop() {
+	defer pool.wg.Done()
+
+	journal := time.NewTicker(pool.config.Rejournal)
+	defer journal.Stop()
+
+	for {
+		select {
+		case <-journal.C:
+			pool.mu.Lock()
+			if err := pool.journal.rotate(pool.local()); err != nil {
+				log.Warn("Failed to rotate local tx journal", "err", err)
+			}
+			pool.mu.Unlock()
+
+		case <-pool.ctx.Done():
+			pool.mu.Lock()
+			if err := pool.journal.close(); err != nil {
+				log.Warn("Failed to close local tx journal", "err", err)
+			}
+			pool.mu.Unlock()
+			return
+		}
+	}
+}
+
 // Stop terminates the transaction pool.
 func (pool *TxsPool) Stop() {
 	pool.cancel()
@@ -1155,6 +1248,34 @@ func (pool *TxsPool) Stop() {
 	log.Info("Transaction pool stopped")
 }
 
+// local retrieves all currently known local transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxsPool) local() map[types.Address][]*transaction.Transaction {
+	txs := make(map[types.Address][]*transaction.Transaction)
+	for addr := range pool.locals.accounts {
+		if pending := pool.pending[addr]; pending != nil {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+		if queued := pool.queue[addr]; queued != nil {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
+// journalTx adds the specified transaction to the local disk journal if it is
+// deemed to have been sent from a local account.
+func (pool *TxsPool) journalTx(from types.Address, tx *transaction.Transaction) {
+	// Only journal if it's enabled and the transaction is local
+	if pool.journal == nil || !pool.locals.contains(from) {
+		return
+	}
+	if err := pool.journal.insert(tx); err != nil {
+		log.Warn("Failed to journal local transaction", "err", err)
+	}
+}
+
 // txDifference
 func (pool *TxsPool) txDifference(a, b []*transaction.Transaction) []*transaction.Transaction {
 	keep := make([]*transaction.Transaction, 0, len(a))
@@ -1206,6 +1327,34 @@ func (pool *TxsPool) Pending(enforceTips bool) map[types.Address][]*transaction.
 	return pending
 }
 
+// GasPrice returns the current gas price enforced by the transaction pool.
+func (pool *TxsPool) GasPrice() *uint256.Int {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return new(uint256.Int).Set(pool.gasPrice)
+}
+
+// SetGasPrice updates the minimum price required by the transaction pool for a
+// new transaction, and drops all remote transactions below this threshold.
+func (pool *TxsPool) SetGasPrice(price *uint256.Int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	old := pool.gasPrice
+	pool.gasPrice = new(uint256.Int).Set(price)
+	// if the min miner fee increased, remove transactions below the new threshold
+	if price.Cmp(old) > 0 {
+		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
+		drop := pool.all.RemotesBelowTip(*price)
+		for _, tx := range drop {
+			pool.removeTx(tx.Hash(), false)
+		}
+		pool.priced.Removed(len(drop))
+	}
+	log.Info("Transaction pool price threshold updated", "price", price)
+}
+
 // Has
 func (pool *TxsPool) Has(hash types.Hash) bool {
 	return pool.all.Get(hash) != nil
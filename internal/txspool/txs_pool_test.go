@@ -0,0 +1,50 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package txspool
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTxsPoolConfigSanitize(t *testing.T) {
+	// Unworkable limits fall back to the defaults
+	have := (&TxsPoolConfig{Journal: "journal", Rejournal: time.Millisecond}).sanitize()
+	want := DefaultTxPoolConfig
+	want.Journal = "journal"
+	want.Rejournal = time.Second
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have config %+v, want %+v", have, want)
+	}
+
+	// Workable limits are kept
+	config := TxsPoolConfig{
+		NoLocals:     true,
+		Rejournal:    time.Minute,
+		PriceLimit:   2,
+		PriceBump:    5,
+		AccountSlots: 1,
+		GlobalSlots:  2,
+		AccountQueue: 3,
+		GlobalQueue:  4,
+		Lifetime:     time.Minute,
+	}
+	if have := config.sanitize(); !reflect.DeepEqual(have, config) {
+		t.Errorf("have config %+v, want %+v", have, config)
+	}
+}
@@ -184,6 +184,31 @@ func Get(db kv.Tx, bucket string, key []byte, from, to uint32) (*roaring.Bitmap,
 	return roaring.FastOr(chunks...), nil
 }
 
+// TruncateLeft - deletes the chunks of the key's bitmap holding only values below `to`.
+// The chunk holding `to` is kept whole, readers must not rely on values below `to`.
+func TruncateLeft(db kv.RwTx, bucket string, key []byte, to uint32) error {
+	c, err := db.RwCursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(key); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) || len(k) != len(key)+4 {
+			return nil
+		}
+		if binary.BigEndian.Uint32(k[len(key):]) >= to {
+			return nil
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SeekInBitmap - returns value in bitmap which is >= n
 //
 //nolint:deadcode
@@ -342,6 +367,31 @@ func Get64(db kv.Tx, bucket string, key []byte, from, to uint64) (*roaring64.Bit
 	return roaring64.FastOr(chunks...), nil
 }
 
+// TruncateLeft64 - deletes the chunks of the key's bitmap holding only values below `to`.
+// The chunk holding `to` is kept whole, readers must not rely on values below `to`.
+func TruncateLeft64(db kv.RwTx, bucket string, key []byte, to uint64) error {
+	c, err := db.RwCursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(key); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) || len(k) != len(key)+8 {
+			return nil
+		}
+		if binary.BigEndian.Uint64(k[len(key):]) >= to {
+			return nil
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SeekInBitmap - returns value in bitmap which is >= n
 func SeekInBitmap64(m *roaring64.Bitmap, n uint64) (found uint64, ok bool) {
 	if m.IsEmpty() {
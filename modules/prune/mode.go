@@ -0,0 +1,106 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prune
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultDistance is the number of blocks kept for a kind of data enabled
+	// in the prune flags without an explicit distance.
+	DefaultDistance = 90000
+
+	// MinDistance is the smallest distance accepted, so that the chain can
+	// still be unwound through any reorg.
+	MinDistance = 128
+)
+
+// Distance is the number of most recent blocks whose data is kept. Zero keeps
+// the data of every block.
+type Distance uint64
+
+// Enabled reports whether data is pruned at all.
+func (d Distance) Enabled() bool {
+	return d != 0
+}
+
+// PruneTo returns the first block whose data is kept when the chain is at
+// the given head.
+func (d Distance) PruneTo(head uint64) uint64 {
+	if d == 0 || head+1 <= uint64(d) {
+		return 0
+	}
+	return head + 1 - uint64(d)
+}
+
+func (d Distance) String() string {
+	if d == 0 {
+		return "archive"
+	}
+	return fmt.Sprintf("%d", uint64(d))
+}
+
+// Mode selects the kinds of block data that are pruned and how many recent
+// blocks of each are kept. The zero Mode is an archive node.
+type Mode struct {
+	History  Distance // account, storage and deposit change sets, history indexes and state tries
+	Receipts Distance // receipts, logs and log indexes
+}
+
+// FromCli builds a Mode from the prune flags: flags holds a letter per kind of
+// data to prune ("h" history, "r" receipts and logs), and the older arguments
+// override the number of blocks kept, enabling pruning for that kind.
+func FromCli(flags string, olderHistory, olderReceipts uint64) (Mode, error) {
+	var mode Mode
+	for _, flag := range strings.ToLower(flags) {
+		switch flag {
+		case 'h':
+			mode.History = DefaultDistance
+		case 'r':
+			mode.Receipts = DefaultDistance
+		default:
+			return Mode{}, fmt.Errorf("unknown prune flag %q in %q", flag, flags)
+		}
+	}
+	if olderHistory != 0 {
+		mode.History = Distance(olderHistory)
+	}
+	if olderReceipts != 0 {
+		mode.Receipts = Distance(olderReceipts)
+	}
+	if mode.History.Enabled() && mode.History < MinDistance {
+		return Mode{}, fmt.Errorf("history prune distance %d is below the minimum of %d blocks", mode.History, MinDistance)
+	}
+	if mode.Receipts.Enabled() && mode.Receipts < MinDistance {
+		return Mode{}, fmt.Errorf("receipts prune distance %d is below the minimum of %d blocks", mode.Receipts, MinDistance)
+	}
+	return mode, nil
+}
+
+// Enabled reports whether any kind of data is pruned.
+func (m Mode) Enabled() bool {
+	return m.History.Enabled() || m.Receipts.Enabled()
+}
+
+func (m Mode) String() string {
+	if !m.Enabled() {
+		return "archive"
+	}
+	return fmt.Sprintf("history=%s, receipts=%s", m.History, m.Receipts)
+}
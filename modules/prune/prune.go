@@ -0,0 +1,77 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prune
+
+import (
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/amazechain/amc/modules/state"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// Prune removes the data of the kinds enabled in mode for the blocks that are
+// further than their distance from head, at most limit blocks of each kind, and
+// moves the recorded prune boundaries accordingly. It reports whether nothing
+// is left to prune at this head, so that large backlogs are pruned over several
+// transactions.
+func Prune(tx kv.RwTx, mode Mode, head, limit uint64) (done bool, err error) {
+	done = true
+	if mode.History.Enabled() {
+		caughtUp, err := pruneKind(tx, rawdb.PruneKindHistory, mode.History.PruneTo(head), limit, pruneHistory)
+		if nil != err {
+			return false, err
+		}
+		done = done && caughtUp
+	}
+	if mode.Receipts.Enabled() {
+		caughtUp, err := pruneKind(tx, rawdb.PruneKindReceipts, mode.Receipts.PruneTo(head), limit, rawdb.PruneReceipts)
+		if nil != err {
+			return false, err
+		}
+		done = done && caughtUp
+	}
+	return done, nil
+}
+
+// pruneHistory removes the state history, state tries and deposit change sets
+// of the blocks in [from, to).
+func pruneHistory(tx kv.RwTx, from, to uint64) error {
+	if err := state.PruneHistory(tx, from, to); nil != err {
+		return err
+	}
+	return rawdb.PruneDepositChanges(tx, from, to)
+}
+
+func pruneKind(tx kv.RwTx, kind string, to, limit uint64, prune func(tx kv.RwTx, from, to uint64) error) (bool, error) {
+	from, err := rawdb.ReadPruneBoundary(tx, kind)
+	if nil != err {
+		return false, err
+	}
+	if from >= to {
+		return true, nil
+	}
+	end := to
+	if end-from > limit {
+		end = from + limit
+	}
+	if err := prune(tx, from, end); nil != err {
+		return false, err
+	}
+	if err := rawdb.WritePruneBoundary(tx, kind, end); nil != err {
+		return false, err
+	}
+	return end == to, nil
+}
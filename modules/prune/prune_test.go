@@ -0,0 +1,106 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package prune
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/rawdb"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+func TestPruneHistoryBoundary(t *testing.T) {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	_, tx := memdb.NewTestTx(t)
+
+	sk, err := bls.SecretKeyFromRandom32Byte([32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pub types.PublicKey
+	copy(pub[:], sk.PublicKey().Marshal())
+
+	// Every block raises the deposit of addr by one
+	addr := types.Address{0x0a}
+	for number := uint64(0); number < 10; number++ {
+		if err := rawdb.WriteDepositChange(tx, number, addr); err != nil {
+			t.Fatal(err)
+		}
+		if err := rawdb.PutDeposit(tx, addr, pub, *uint256.NewInt(number + 1)); err != nil {
+			t.Fatal(err)
+		}
+		if err := rawdb.WriteDepositProgress(tx, number); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keeping 4 blocks at head 9 prunes up to block 6, 3 blocks at a time
+	mode := Mode{History: 4}
+	for i, want := range []struct {
+		done     bool
+		boundary uint64
+	}{{false, 3}, {true, 6}} {
+		done, err := Prune(tx, mode, 9, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		boundary, err := rawdb.ReadPruneBoundary(tx, rawdb.PruneKindHistory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != want.done || boundary != want.boundary {
+			t.Fatalf("round %d: have done %v at %d, want %v at %d", i, done, boundary, want.done, want.boundary)
+		}
+	}
+
+	// Change sets from the boundary on are kept
+	for number := uint64(0); number < 10; number++ {
+		v, err := tx.GetOne(modules.DepositChangeSet, modules.EncodeBlockNumber(number))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := v != nil; kept != (number >= 6) {
+			t.Errorf("change set of block %d kept %v", number, kept)
+		}
+	}
+	if err := rawdb.CheckPruned(tx, rawdb.PruneKindHistory, 5); !errors.Is(err, rawdb.ErrPruned) {
+		t.Errorf("block below the boundary: have %v, want %v", err, rawdb.ErrPruned)
+	}
+	if err := rawdb.CheckPruned(tx, rawdb.PruneKindHistory, 6); err != nil {
+		t.Errorf("block at the boundary: %v", err)
+	}
+
+	// The deposits after a block need the change sets of the later blocks
+	if _, _, err := rawdb.GetDepositAt(tx, addr, 4); !errors.Is(err, rawdb.ErrPruned) {
+		t.Errorf("deposit after block 4: have %v, want %v", err, rawdb.ErrPruned)
+	}
+	if _, amount, err := rawdb.GetDepositAt(tx, addr, 5); err != nil || amount.Uint64() != 6 {
+		t.Errorf("deposit after block 5: have %v (err %v), want 6", amount, err)
+	}
+
+	// Receipts are pruned on their own
+	if boundary, err := rawdb.ReadPruneBoundary(tx, rawdb.PruneKindReceipts); err != nil || boundary != 0 {
+		t.Errorf("receipts boundary %d (err %v), want 0", boundary, err)
+	}
+}
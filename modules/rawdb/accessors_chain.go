@@ -735,10 +735,10 @@ func ReadReceipts(db kv.Tx, block *block.Block, senders []types.Address) block.R
 	if len(senders) > 0 {
 		block.SendersToTxs(senders)
 	}
-	//if err := receipts.DeriveFields(block.Hash(), block.NumberU64(), block.Transactions(), senders); err != nil {
-	//	log.Error("Failed to derive block receipts fields", "hash", block.Hash(), "number", block.NumberU64(), "err", err, "stack", dbg.Stack())
-	//	return nil
-	//}
+	if err := receipts.DeriveFields(block.Hash(), block.Number64().Uint64(), block.Transactions()); err != nil {
+		log.Error("Failed to derive block receipts fields", "hash", block.Hash(), "number", block.Number64().Uint64(), "err", err)
+		return nil
+	}
 	return receipts
 }
 
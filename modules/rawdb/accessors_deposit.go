@@ -1,14 +1,23 @@
 package rawdb
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math"
+
+	"github.com/RoaringBitmap/roaring"
 	"github.com/amazechain/amc/common/crypto/bls"
 	"github.com/amazechain/amc/common/types"
 	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 )
 
+// ErrNoDeposit is returned when an account held no deposit.
+var ErrNoDeposit = errors.New("no deposit")
+
 //// PutDeposit
 //func PutDeposit(db kv.Putter, key []byte, val []byte) error {
 //	return db.Put(modules.Deposit, key, val)
@@ -32,15 +41,19 @@ func GetDeposit(db kv.Getter, addr types.Address) (types.PublicKey, *uint256.Int
 	if err != nil {
 		return types.PublicKey{}, nil, err
 	}
+	return decodeDeposit(valBytes)
+}
+
+// decodeDeposit splits a stored deposit into its public key and amount.
+func decodeDeposit(valBytes []byte) (types.PublicKey, *uint256.Int, error) {
 	if len(valBytes) < types.PublicKeyLength {
 		return types.PublicKey{}, nil, fmt.Errorf("the data length wrong")
 	}
-	_, err = bls.PublicKeyFromBytes(valBytes[:types.PublicKeyLength])
-	if err != nil {
+	if _, err := bls.PublicKeyFromBytes(valBytes[:types.PublicKeyLength]); err != nil {
 		return types.PublicKey{}, nil, fmt.Errorf("cannot unmarshal pubkey from bytes")
 	}
 	pubkey := new(types.PublicKey)
-	if err = pubkey.SetBytes(valBytes[:types.PublicKeyLength]); err != nil {
+	if err := pubkey.SetBytes(valBytes[:types.PublicKeyLength]); err != nil {
 		return types.PublicKey{}, nil, fmt.Errorf("cannot unmarshal pubkey from bytes")
 	}
 	amount := uint256.NewInt(0).SetBytes(valBytes[types.PublicKeyLength:])
@@ -67,3 +80,260 @@ func DepositNum(tx kv.Tx) (uint64, error) {
 	defer cur.Close()
 	return cur.Count()
 }
+
+// checkDepositsAt returns an error if the deposits as they were after the given
+// block cannot be read.
+func checkDepositsAt(tx kv.Tx, number uint64) error {
+	progress, ok, err := ReadDepositProgress(tx)
+	if err != nil {
+		return err
+	}
+	if !ok || progress < number {
+		return fmt.Errorf("deposits of block %d not available, applied up to %d", number, progress)
+	}
+	// The deposit change sets are pruned with the state history
+	return CheckPruned(tx, PruneKindHistory, number+1)
+}
+
+// depositChangesAfter returns the deposits, as they were after the given block,
+// of the accounts whose deposit changed in a later block. An empty value means
+// the account held no deposit.
+func depositChangesAfter(tx kv.Tx, number uint64) (map[types.Address][]byte, error) {
+	if err := checkDepositsAt(tx, number); err != nil {
+		return nil, err
+	}
+	changes := make(map[types.Address][]byte)
+	if err := tx.ForEach(modules.DepositChangeSet, modules.EncodeBlockNumber(number+1), func(k, v []byte) error {
+		if len(v) < types.AddressLength {
+			return fmt.Errorf("deposit changes corrupted for block %x", k)
+		}
+		addr := types.BytesToAddress(v[:types.AddressLength])
+		// Change sets are visited in block order, the first one of an
+		// account holds its deposit after the given block.
+		if _, ok := changes[addr]; !ok {
+			changes[addr] = types.CopyBytes(v[types.AddressLength:])
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetDepositAt returns the deposit of addr as it was after the given block,
+// ErrNoDeposit if it held none. The first later block changing the deposit is
+// looked up in the DepositHistory index, its change set holds the deposit.
+func GetDepositAt(tx kv.Tx, addr types.Address, number uint64) (types.PublicKey, *uint256.Int, error) {
+	if err := checkDepositsAt(tx, number); err != nil {
+		return types.PublicKey{}, nil, err
+	}
+	index, err := bitmapdb.Get(tx, modules.DepositHistory, addr[:], uint32(number+1), math.MaxUint32)
+	if err != nil {
+		return types.PublicKey{}, nil, err
+	}
+	changed, ok := bitmapdb.SeekInBitmap(index, uint32(number+1))
+	if !ok {
+		if !IsDeposit(tx, addr) {
+			return types.PublicKey{}, nil, ErrNoDeposit
+		}
+		return GetDeposit(tx, addr)
+	}
+
+	c, err := tx.CursorDupSort(modules.DepositChangeSet)
+	if err != nil {
+		return types.PublicKey{}, nil, err
+	}
+	defer c.Close()
+	v, err := c.SeekBothRange(modules.EncodeBlockNumber(uint64(changed)), addr[:])
+	if err != nil {
+		return types.PublicKey{}, nil, err
+	}
+	if !bytes.HasPrefix(v, addr[:]) {
+		return types.PublicKey{}, nil, fmt.Errorf("deposit change of %s missing for block %d", addr, changed)
+	}
+	if len(v) == types.AddressLength {
+		return types.PublicKey{}, nil, ErrNoDeposit
+	}
+	return decodeDeposit(v[types.AddressLength:])
+}
+
+// DepositNumAt returns the number of accounts that held a deposit after the
+// given block.
+func DepositNumAt(tx kv.Tx, number uint64) (uint64, error) {
+	count, err := DepositNum(tx)
+	if err != nil {
+		return 0, err
+	}
+	changes, err := depositChangesAfter(tx, number)
+	if err != nil {
+		return 0, err
+	}
+	for addr, prev := range changes {
+		now, err := tx.Has(modules.Deposit, addr[:])
+		if err != nil {
+			return 0, err
+		}
+		switch then := len(prev) > 0; {
+		case then && !now:
+			count++
+		case !then && now:
+			count--
+		}
+	}
+	return count, nil
+}
+
+// depositProgressKey tracks the last block whose deposit events were applied.
+var depositProgressKey = []byte("DepositProgress")
+
+// ReadDepositProgress retrieves the number of the last block whose deposit
+// events were applied to the Deposit table. ok is false if the table has never
+// been built from the chain.
+func ReadDepositProgress(db kv.Getter) (number uint64, ok bool, err error) {
+	data, err := db.GetOne(modules.DatabaseInfo, depositProgressKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(data) != modules.NumberLength {
+		return 0, false, nil
+	}
+	number, err = modules.DecodeBlockNumber(data)
+	return number, err == nil, err
+}
+
+// WriteDepositProgress stores the number of the last block whose deposit events
+// were applied to the Deposit table.
+func WriteDepositProgress(db kv.Putter, number uint64) error {
+	return db.Put(modules.DatabaseInfo, depositProgressKey, modules.EncodeBlockNumber(number))
+}
+
+// WriteDepositChange records the deposit of addr as it was before the given
+// block, so that it can be restored if the block is unwound. Only the first
+// change of an address within a block is recorded.
+func WriteDepositChange(tx kv.RwTx, number uint64, addr types.Address) error {
+	c, err := tx.CursorDupSort(modules.DepositChangeSet)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	key := modules.EncodeBlockNumber(number)
+	v, err := c.SeekBothRange(key, addr[:])
+	if err != nil {
+		return err
+	}
+	if len(v) >= types.AddressLength && bytes.Equal(v[:types.AddressLength], addr[:]) {
+		return nil
+	}
+
+	prev, err := tx.GetOne(modules.Deposit, addr[:])
+	if err != nil {
+		return err
+	}
+	val := make([]byte, types.AddressLength+len(prev))
+	copy(val, addr[:])
+	copy(val[types.AddressLength:], prev)
+	if err := tx.Put(modules.DepositChangeSet, key, val); err != nil {
+		return err
+	}
+	return addDepositHistory(tx, addr, number)
+}
+
+// addDepositHistory adds the block number to the last chunks of the
+// DepositHistory bitmap of addr.
+func addDepositHistory(tx kv.RwTx, addr types.Address, number uint64) error {
+	index, err := bitmapdb.Get(tx, modules.DepositHistory, addr[:], uint32(number), math.MaxUint32)
+	if err != nil {
+		return err
+	}
+	index.Add(uint32(number))
+	buf := bytes.NewBuffer(nil)
+	return bitmapdb.WalkChunkWithKeys(addr[:], index, bitmapdb.ChunkLimit, func(chunkKey []byte, chunk *roaring.Bitmap) error {
+		buf.Reset()
+		if _, err := chunk.WriteTo(buf); err != nil {
+			return err
+		}
+		return tx.Put(modules.DepositHistory, chunkKey, types.CopyBytes(buf.Bytes()))
+	})
+}
+
+// UnwindDeposits restores the Deposit table to its state before the given block
+// and removes the block's change set.
+func UnwindDeposits(tx kv.RwTx, number uint64) error {
+	key := modules.EncodeBlockNumber(number)
+	var changes [][]byte
+	if err := tx.ForPrefix(modules.DepositChangeSet, key, func(k, v []byte) error {
+		changes = append(changes, types.CopyBytes(v))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if len(change) < types.AddressLength {
+			return fmt.Errorf("deposit changes corrupted for block %d", number)
+		}
+		addr, prev := change[:types.AddressLength], change[types.AddressLength:]
+		if err := bitmapdb.TruncateRange(tx, modules.DepositHistory, addr, uint32(number)); err != nil {
+			return err
+		}
+		if len(prev) == 0 {
+			if err := tx.Delete(modules.Deposit, addr); err != nil {
+				return err
+			}
+		} else if err := tx.Put(modules.Deposit, addr, prev); err != nil {
+			return err
+		}
+	}
+	if err := tx.Delete(modules.DepositChangeSet, key); err != nil {
+		return err
+	}
+	if number > 0 {
+		return WriteDepositProgress(tx, number-1)
+	}
+	return nil
+}
+
+// PruneDepositChanges removes the deposit change sets of the blocks in
+// [from, to) and their DepositHistory entries. The deposits after the blocks
+// before to can no longer be read and these blocks can no longer be unwound.
+func PruneDepositChanges(tx kv.RwTx, from, to uint64) error {
+	if from >= to {
+		return nil
+	}
+	c, err := tx.RwCursorDupSort(modules.DepositChangeSet)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	addrs := make(map[types.Address]struct{})
+	// Seek again after each deletion rather than relying on the position of
+	// the cursor on the removed key
+	start := modules.EncodeBlockNumber(from)
+	for k, _, err := c.Seek(start); k != nil; k, _, err = c.Seek(start) {
+		if err != nil {
+			return err
+		}
+		if number, _ := modules.DecodeBlockNumber(k); number >= to {
+			break
+		}
+		for v, err := c.FirstDup(); ; _, v, err = c.NextDup() {
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				break
+			}
+			addrs[types.BytesToAddress(v[:types.AddressLength])] = struct{}{}
+		}
+		if err := c.DeleteCurrentDuplicates(); err != nil {
+			return err
+		}
+	}
+	for addr := range addrs {
+		if err := bitmapdb.TruncateLeft(tx, modules.DepositHistory, addr[:], uint32(to)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package rawdb
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/amazechain/amc/common/crypto/bls"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+func newDepositTestTx(t *testing.T) kv.RwTx {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	_, tx := memdb.NewTestTx(t)
+	return tx
+}
+
+func testPublicKey(t *testing.T, seed byte) types.PublicKey {
+	sk, err := bls.SecretKeyFromRandom32Byte([32]byte{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pub types.PublicKey
+	copy(pub[:], sk.PublicKey().Marshal())
+	return pub
+}
+
+// changeDeposit sets, or removes if amount is zero, the deposit of addr in the
+// given block the way the deposit processor does.
+func changeDeposit(t *testing.T, tx kv.RwTx, number uint64, addr types.Address, pub types.PublicKey, amount uint64) {
+	if err := WriteDepositChange(tx, number, addr); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if amount == 0 {
+		err = DeleteDeposit(tx, addr)
+	} else {
+		err = PutDeposit(tx, addr, pub, *uint256.NewInt(amount))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDepositProgress(tx, number); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDepositHistory(t *testing.T) {
+	tx := newDepositTestTx(t)
+	a, b := types.Address{0x0a}, types.Address{0x0b}
+	pubA, pubB := testPublicKey(t, 1), testPublicKey(t, 2)
+
+	if err := WriteDepositProgress(tx, 0); err != nil {
+		t.Fatal(err)
+	}
+	changeDeposit(t, tx, 1, a, pubA, 10)
+	changeDeposit(t, tx, 2, b, pubB, 10)
+	changeDeposit(t, tx, 2, a, pubA, 0)
+	changeDeposit(t, tx, 3, a, pubA, 100)
+
+	for number, want := range map[uint64]uint64{0: 0, 1: 1, 2: 1, 3: 2} {
+		if have, err := DepositNumAt(tx, number); err != nil || have != want {
+			t.Errorf("block %d: have %d deposits (err %v), want %d", number, have, err, want)
+		}
+	}
+	if _, err := DepositNumAt(tx, 4); err == nil {
+		t.Error("deposits of a block not applied yet returned")
+	}
+
+	tests := []struct {
+		addr   types.Address
+		number uint64
+		amount uint64 // zero if there is no deposit
+	}{
+		{a, 0, 0}, {a, 1, 10}, {a, 2, 0}, {a, 3, 100},
+		{b, 1, 0}, {b, 2, 10}, {b, 3, 10},
+	}
+	for _, tt := range tests {
+		pub, amount, err := GetDepositAt(tx, tt.addr, tt.number)
+		if tt.amount == 0 {
+			if !errors.Is(err, ErrNoDeposit) {
+				t.Errorf("%s at block %d: have deposit %v (err %v), want none", tt.addr, tt.number, amount, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s at block %d: %v", tt.addr, tt.number, err)
+		} else if amount.Uint64() != tt.amount || (pub != pubA && pub != pubB) {
+			t.Errorf("%s at block %d: have deposit %v, want %d", tt.addr, tt.number, amount, tt.amount)
+		}
+	}
+
+	// Unwinding restores the earlier deposits
+	if err := UnwindDeposits(tx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if IsDeposit(tx, a) {
+		t.Error("unwound deposit still present")
+	}
+	if err := UnwindDeposits(tx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, amount, err := GetDeposit(tx, a); err != nil || amount.Uint64() != 10 {
+		t.Errorf("withdrawn deposit not restored: %v, %v", amount, err)
+	}
+	if IsDeposit(tx, b) {
+		t.Error("unwound deposit still present")
+	}
+	if progress, ok, err := ReadDepositProgress(tx); err != nil || !ok || progress != 1 {
+		t.Errorf("progress %d (%v, %v), want 1", progress, ok, err)
+	}
+	if _, amount, err := GetDepositAt(tx, a, 1); err != nil || amount.Uint64() != 10 {
+		t.Errorf("deposit after unwinding: have %v (err %v), want 10", amount, err)
+	}
+	if index, err := bitmapdb.Get(tx, modules.DepositHistory, a[:], 0, math.MaxUint32); err != nil || index.Contains(2) || index.Contains(3) {
+		t.Errorf("unwound blocks still indexed: %v (err %v)", index, err)
+	}
+}
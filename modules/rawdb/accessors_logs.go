@@ -0,0 +1,231 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+var logIndexProgressKey = []byte("LogIndexProgress")
+
+// ReadLogIndexProgress retrieves the number of the last block added to the log
+// indexes. ok is false if the indexes have never been built.
+func ReadLogIndexProgress(db kv.Getter) (number uint64, ok bool, err error) {
+	data, err := db.GetOne(modules.DatabaseInfo, logIndexProgressKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(data) != modules.NumberLength {
+		return 0, false, nil
+	}
+	number, err = modules.DecodeBlockNumber(data)
+	return number, err == nil, err
+}
+
+// WriteLogIndexProgress stores the number of the last block added to the log
+// indexes.
+func WriteLogIndexProgress(db kv.Putter, number uint64) error {
+	return db.Put(modules.DatabaseInfo, logIndexProgressKey, modules.EncodeBlockNumber(number))
+}
+
+// logIndexKeys returns the addresses and topics of the logs stored for the
+// blocks in [from, to].
+func logIndexKeys(tx kv.Tx, from, to uint64) (addresses, topics map[string]struct{}, err error) {
+	c, err := tx.Cursor(modules.Log)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+
+	addresses, topics = make(map[string]struct{}), make(map[string]struct{})
+	for k, v, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, nil, err
+		}
+		if number, _ := modules.DecodeBlockNumber(k[:modules.NumberLength]); number > to {
+			break
+		}
+		var logs block.Logs
+		if err := logs.Unmarshal(v); err != nil {
+			return nil, nil, fmt.Errorf("decode logs %x: %w", k, err)
+		}
+		for _, l := range logs {
+			addresses[string(l.Address.Bytes())] = struct{}{}
+			for _, topic := range l.Topics {
+				topics[string(topic.Bytes())] = struct{}{}
+			}
+		}
+	}
+	return addresses, topics, nil
+}
+
+// WriteLogIndex adds the block to the LogAddressIndex and LogTopicIndex bitmaps
+// of the addresses and topics of its logs. It must be called for canonical
+// blocks in increasing order, after their receipts are written. A block already
+// indexed is refused, the indexes must be unwound before the logs of its number
+// are replaced.
+func WriteLogIndex(tx kv.RwTx, number uint64) error {
+	progress, ok, err := ReadLogIndexProgress(tx)
+	if err != nil {
+		return err
+	}
+	if ok && number <= progress {
+		return fmt.Errorf("block %d already in the log indexes, indexed up to %d", number, progress)
+	}
+	addresses, topics, err := logIndexKeys(tx, number, number)
+	if err != nil {
+		return err
+	}
+	for addr := range addresses {
+		if err := addLogIndex(tx, modules.LogAddressIndex, []byte(addr), number); err != nil {
+			return err
+		}
+	}
+	for topic := range topics {
+		if err := addLogIndex(tx, modules.LogTopicIndex, []byte(topic), number); err != nil {
+			return err
+		}
+	}
+	return WriteLogIndexProgress(tx, number)
+}
+
+// WriteHeadLogIndex adds a new head block to the log indexes if they have
+// caught up with its parent. Otherwise the block is left to RebuildLogIndex,
+// which is still building the indexes.
+func WriteHeadLogIndex(tx kv.RwTx, number uint64) error {
+	progress, ok, err := ReadLogIndexProgress(tx)
+	if err != nil || !ok || progress+1 != number {
+		return err
+	}
+	return WriteLogIndex(tx, number)
+}
+
+// addLogIndex adds the block number to the last chunks of the key's bitmap.
+func addLogIndex(tx kv.RwTx, bucket string, key []byte, number uint64) error {
+	index, err := bitmapdb.Get(tx, bucket, key, uint32(number), math.MaxUint32)
+	if err != nil {
+		return fmt.Errorf("find chunk failed: %w", err)
+	}
+	index.Add(uint32(number))
+	buf := bytes.NewBuffer(nil)
+	return bitmapdb.WalkChunkWithKeys(key, index, bitmapdb.ChunkLimit, func(chunkKey []byte, chunk *roaring.Bitmap) error {
+		buf.Reset()
+		if _, err := chunk.WriteTo(buf); err != nil {
+			return err
+		}
+		return tx.Put(bucket, chunkKey, types.CopyBytes(buf.Bytes()))
+	})
+}
+
+// UnwindLogIndex removes the blocks from the given number on from the log
+// index bitmaps. The keys to remove are read from the logs of these blocks, so
+// it must be called before the logs are removed or replaced, i.e. before their
+// receipts are truncated.
+func UnwindLogIndex(tx kv.RwTx, from uint64) error {
+	progress, ok, err := ReadLogIndexProgress(tx)
+	if err != nil || !ok || progress < from {
+		return err
+	}
+	addresses, topics, err := logIndexKeys(tx, from, progress)
+	if err != nil {
+		return err
+	}
+	for addr := range addresses {
+		if err := bitmapdb.TruncateRange(tx, modules.LogAddressIndex, []byte(addr), uint32(from)); err != nil {
+			return err
+		}
+	}
+	for topic := range topics {
+		if err := bitmapdb.TruncateRange(tx, modules.LogTopicIndex, []byte(topic), uint32(from)); err != nil {
+			return err
+		}
+	}
+	if from > 0 {
+		return WriteLogIndexProgress(tx, from-1)
+	}
+	return tx.Delete(modules.DatabaseInfo, logIndexProgressKey)
+}
+
+// RebuildLogIndex adds at most limit canonical blocks above the last indexed
+// block to the log indexes and reports whether the indexes have caught up with
+// the head. If the indexes have never been built, they are built from the first
+// block whose receipts are stored. The progress is stored with every block, so
+// that large backlogs are indexed over several transactions.
+func RebuildLogIndex(tx kv.RwTx, limit uint64) (done bool, err error) {
+	head := ReadHeaderNumber(tx, ReadHeadBlockHash(tx))
+	if head == nil {
+		return true, nil
+	}
+
+	progress, ok, err := ReadLogIndexProgress(tx)
+	if err != nil {
+		return false, err
+	}
+	from := progress + 1
+	if !ok {
+		if err := tx.ClearBucket(modules.LogAddressIndex); err != nil {
+			return false, err
+		}
+		if err := tx.ClearBucket(modules.LogTopicIndex); err != nil {
+			return false, err
+		}
+		// The logs of pruned blocks are gone, these are not indexed
+		if from, err = ReadPruneBoundary(tx, PruneKindReceipts); err != nil {
+			return false, err
+		}
+	}
+	if from > *head {
+		return true, nil
+	}
+
+	to := *head
+	if to-from >= limit {
+		to = from + limit - 1
+	}
+	for number := from; number <= to; number++ {
+		if err := WriteLogIndex(tx, number); err != nil {
+			return false, err
+		}
+	}
+	log.Info("Built log indexes", "from", from, "to", to, "head", *head)
+	return to == *head, nil
+}
+
+// ReadLogIndex returns the blocks in [from, to] that have logs with the given
+// address or topic, read from the LogAddressIndex or LogTopicIndex table.
+func ReadLogIndex(tx kv.Tx, bucket string, key []byte, from, to uint64) (*roaring.Bitmap, error) {
+	if to > math.MaxUint32 {
+		to = math.MaxUint32
+	}
+	index, err := bitmapdb.Get(tx, bucket, key, uint32(from), uint32(to))
+	if err != nil {
+		return nil, err
+	}
+	index.RemoveRange(0, from)
+	index.RemoveRange(to+1, uint64(math.MaxUint32)+1)
+	return index, nil
+}
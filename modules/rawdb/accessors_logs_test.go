@@ -0,0 +1,147 @@
+package rawdb
+
+import (
+	"testing"
+
+	"github.com/amazechain/amc/common/block"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+func newLogTestTx(t *testing.T) kv.RwTx {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	_, tx := memdb.NewTestTx(t)
+	return tx
+}
+
+// writeLogBlock stores a receipt with a log of each address for the block and
+// adds the block to the log indexes, the way blocks are executed.
+func writeLogBlock(t *testing.T, tx kv.RwTx, number uint64, addrs ...types.Address) {
+	t.Helper()
+	logs := make([]*block.Log, len(addrs))
+	for i, addr := range addrs {
+		logs[i] = &block.Log{Address: addr, BlockNumber: uint256.NewInt(number)}
+	}
+	if err := AppendReceipts(tx, number, block.Receipts{{Logs: logs, BlockNumber: uint256.NewInt(number)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLogIndex(tx, number); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkLogIndex(t *testing.T, tx kv.Tx, addr types.Address, want ...uint32) {
+	t.Helper()
+	index, err := ReadLogIndex(tx, modules.LogAddressIndex, addr[:], 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := index.ToArray(); len(have) != len(want) {
+		t.Errorf("%s: have blocks %v, want %v", addr, have, want)
+	} else {
+		for i := range have {
+			if have[i] != want[i] {
+				t.Errorf("%s: have blocks %v, want %v", addr, have, want)
+				break
+			}
+		}
+	}
+}
+
+func TestUnwindLogIndex(t *testing.T) {
+	tx := newLogTestTx(t)
+	a, b, c := types.Address{0x0a}, types.Address{0x0b}, types.Address{0x0c}
+
+	writeLogBlock(t, tx, 1, a)
+	writeLogBlock(t, tx, 2, a, b)
+	writeLogBlock(t, tx, 3, b)
+	checkLogIndex(t, tx, a, 1, 2)
+	checkLogIndex(t, tx, b, 2, 3)
+
+	// A block is not indexed twice
+	if err := WriteLogIndex(tx, 3); err == nil {
+		t.Fatal("indexed block indexed again")
+	}
+
+	// The indexes are unwound before the logs of the old chain are removed,
+	// then the new chain is indexed from its own logs
+	if err := UnwindLogIndex(tx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if progress, ok, err := ReadLogIndexProgress(tx); err != nil || !ok || progress != 1 {
+		t.Errorf("progress %d (%v, %v), want 1", progress, ok, err)
+	}
+	if err := TruncateReceipts(tx, 2); err != nil {
+		t.Fatal(err)
+	}
+	writeLogBlock(t, tx, 2, c)
+	checkLogIndex(t, tx, a, 1)
+	checkLogIndex(t, tx, b)
+	checkLogIndex(t, tx, c, 2)
+
+	// Unwinding above the indexed blocks leaves them alone
+	if err := UnwindLogIndex(tx, 3); err != nil {
+		t.Fatal(err)
+	}
+	checkLogIndex(t, tx, c, 2)
+}
+
+func TestRebuildLogIndex(t *testing.T) {
+	tx := newLogTestTx(t)
+	a := types.Address{0x0a}
+
+	for number := uint64(0); number < 5; number++ {
+		logs := []*block.Log{{Address: a, BlockNumber: uint256.NewInt(number)}}
+		if err := AppendReceipts(tx, number, block.Receipts{{Logs: logs, BlockNumber: uint256.NewInt(number)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	head := types.Hash{0x01}
+	if err := WriteHeaderNumber(tx, head, 4); err != nil {
+		t.Fatal(err)
+	}
+	WriteHeadBlockHash(tx, head)
+
+	// Five blocks are indexed two at a time
+	for i, want := range []bool{false, false, true} {
+		done, err := RebuildLogIndex(tx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != want {
+			t.Fatalf("round %d: have done %v, want %v", i, done, want)
+		}
+	}
+	checkLogIndex(t, tx, a, 0, 1, 2, 3, 4)
+	if done, err := RebuildLogIndex(tx, 2); err != nil || !done {
+		t.Errorf("caught up indexes: have done %v (err %v)", done, err)
+	}
+}
+
+func TestWriteHeadLogIndex(t *testing.T) {
+	tx := newLogTestTx(t)
+	a := types.Address{0x0a}
+	for number := uint64(0); number < 3; number++ {
+		writeLogBlock(t, tx, number, a)
+	}
+	for number := uint64(3); number < 5; number++ {
+		logs := []*block.Log{{Address: a, BlockNumber: uint256.NewInt(number)}}
+		if err := AppendReceipts(tx, number, block.Receipts{{Logs: logs, BlockNumber: uint256.NewInt(number)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A block above a gap is left to the build of the indexes
+	if err := WriteHeadLogIndex(tx, 4); err != nil {
+		t.Fatal(err)
+	}
+	checkLogIndex(t, tx, a, 0, 1, 2)
+	if err := WriteHeadLogIndex(tx, 3); err != nil {
+		t.Fatal(err)
+	}
+	checkLogIndex(t, tx, a, 0, 1, 2, 3)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// The kinds of block data that can be pruned. Each names the DatabaseInfo key
+// holding the first block whose data of that kind is still stored.
+const (
+	PruneKindHistory  = "PruneHistory"  // account, storage and deposit change sets, history indexes and state tries
+	PruneKindReceipts = "PruneReceipts" // receipts, logs and log indexes
+)
+
+// ErrPruned is returned when the data of a block has been pruned.
+var ErrPruned = errors.New("pruned")
+
+// ReadPruneBoundary retrieves the first block whose data of the given kind is
+// stored. It is 0 if the data has never been pruned.
+func ReadPruneBoundary(db kv.Getter, kind string) (uint64, error) {
+	data, err := db.GetOne(modules.DatabaseInfo, []byte(kind))
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != modules.NumberLength {
+		return 0, nil
+	}
+	return modules.DecodeBlockNumber(data)
+}
+
+// WritePruneBoundary stores the first block whose data of the given kind is
+// stored.
+func WritePruneBoundary(db kv.Putter, kind string, number uint64) error {
+	return db.Put(modules.DatabaseInfo, []byte(kind), modules.EncodeBlockNumber(number))
+}
+
+// CheckPruned returns an error wrapping ErrPruned if the data of the given kind
+// of the block has been pruned.
+func CheckPruned(db kv.Getter, kind string, number uint64) error {
+	boundary, err := ReadPruneBoundary(db, kind)
+	if err != nil {
+		return err
+	}
+	if number < boundary {
+		switch kind {
+		case PruneKindHistory:
+			return fmt.Errorf("%w: state history of block %d is not available, history starts at block %d", ErrPruned, number, boundary)
+		case PruneKindReceipts:
+			return fmt.Errorf("%w: receipts of block %d are not available, receipts start at block %d", ErrPruned, number, boundary)
+		}
+		return fmt.Errorf("%w: block %d is below %d", ErrPruned, number, boundary)
+	}
+	return nil
+}
+
+// PruneReceipts removes the receipts and logs of the blocks in [from, to) and
+// drops these blocks from the log indexes. Index chunks also holding blocks
+// from to on are kept whole.
+func PruneReceipts(tx kv.RwTx, from, to uint64) error {
+	if from >= to {
+		return nil
+	}
+	addresses, topics, err := logIndexKeys(tx, from, to-1)
+	if err != nil {
+		return err
+	}
+	for addr := range addresses {
+		if err := bitmapdb.TruncateLeft(tx, modules.LogAddressIndex, []byte(addr), uint32(to)); err != nil {
+			return err
+		}
+	}
+	for topic := range topics {
+		if err := bitmapdb.TruncateLeft(tx, modules.LogTopicIndex, []byte(topic), uint32(to)); err != nil {
+			return err
+		}
+	}
+	if err := pruneBlockRange(tx, modules.Log, from, to); err != nil {
+		return err
+	}
+	return pruneBlockRange(tx, modules.Receipts, from, to)
+}
+
+// pruneBlockRange deletes the entries of a table keyed by block number first
+// whose block is in [from, to).
+func pruneBlockRange(tx kv.RwTx, table string, from, to uint64) error {
+	c, err := tx.RwCursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if number, _ := modules.DecodeBlockNumber(k[:modules.NumberLength]); number >= to {
+			return nil
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
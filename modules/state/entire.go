@@ -19,6 +19,7 @@ type EntireCode struct {
 	Codes    []*HashCode     `json:"codes"`
 	Headers  []*block.Header `json:"headers"`
 	Rewards  []*block.Reward `json:"rewards"`
+	Trie     *TrieWitness    `json:"trie,omitempty"`
 }
 
 type HashCode struct {
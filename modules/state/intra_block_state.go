@@ -96,6 +96,8 @@ type IntraBlockState struct {
 	snap    *Snapshot
 	codeMap map[types.Hash][]byte
 	height  uint64
+
+	stateTrie *stateTrie // Merkle Patricia state root, see UseStateTrie
 }
 
 // Create a new state from a given trie
@@ -1022,7 +1024,16 @@ func (s *IntraBlockState) GenerateRootHash() types.Hash {
 
 // IntermediateRoot root
 func (s *IntraBlockState) IntermediateRoot() types.Hash {
-	return s.GenerateRootHash()
+	if s.stateTrie == nil {
+		return s.GenerateRootHash()
+	}
+	root, err := s.stateTrie.commit(s)
+	if nil != err {
+		log.Error("Failed to commit state trie", "err", err)
+		s.setErrorUnsafe(err)
+		return types.Hash{}
+	}
+	return root
 }
 
 func (sdb *IntraBlockState) HasSelfdestructed(addr types.Address) bool {
@@ -0,0 +1,91 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/binary"
+
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/changeset"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// PruneHistory removes the account and storage change sets of the blocks in
+// [from, to), drops these blocks from the history indexes and removes the nodes
+// of their state tries no later block shares. The state of the blocks before
+// to can no longer be read, proven or unwound to afterwards.
+func PruneHistory(tx kv.RwTx, from, to uint64) error {
+	if from >= to {
+		return nil
+	}
+	if err := releaseTrieRoots(tx, from, to); nil != err {
+		return err
+	}
+
+	accounts, storage := make(map[string]struct{}), make(map[string]struct{})
+	if err := changeset.ForRange(tx, modules.AccountChangeSet, from, to, func(_ uint64, k, _ []byte) error {
+		accounts[string(k)] = struct{}{}
+		return nil
+	}); nil != err {
+		return err
+	}
+	if err := changeset.ForRange(tx, modules.StorageChangeSet, from, to, func(_ uint64, k, _ []byte) error {
+		storage[string(modules.CompositeKeyWithoutIncarnation(k))] = struct{}{}
+		return nil
+	}); nil != err {
+		return err
+	}
+
+	for key := range accounts {
+		if err := bitmapdb.TruncateLeft64(tx, modules.AccountsHistory, []byte(key), to); nil != err {
+			return err
+		}
+	}
+	for key := range storage {
+		if err := bitmapdb.TruncateLeft64(tx, modules.StorageHistory, []byte(key), to); nil != err {
+			return err
+		}
+	}
+
+	for _, table := range []string{modules.AccountChangeSet, modules.StorageChangeSet} {
+		if err := pruneChangeSet(tx, table, from, to); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func pruneChangeSet(tx kv.RwTx, table string, from, to uint64) error {
+	c, err := tx.RwCursorDupSort(table)
+	if nil != err {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, _, err = c.NextNoDup() {
+		if nil != err {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= to {
+			return nil
+		}
+		if err := c.DeleteCurrentDuplicates(); nil != err {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,512 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amazechain/amc/common/account"
+	"github.com/amazechain/amc/common/crypto"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/log"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/params"
+	gcommon "github.com/ethereum/go-ethereum/common"
+	gtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// EmptyTrieRoot is the root of an empty Merkle Patricia trie.
+var EmptyTrieRoot = types.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// ErrTrieNotStored is returned when the state trie a block is computed on is
+// not stored. It has to be built with BuildStateTrie first.
+var ErrTrieNotStored = errors.New("state trie not stored")
+
+// stateTrie computes the Merkle Patricia state root of a block on top of the
+// trie of its parent block. The account and storage tries use the Ethereum
+// layout, so their proofs can be checked by standard tooling. Trie nodes are
+// content addressed and written to the TrieNode table, so the tries of earlier
+// blocks stay readable. Each block refers to its trie until the block is
+// pruned or unwound, which removes the nodes no other trie shares.
+//
+// The root is computed once, the state must not change after the first call
+// to IntermediateRoot.
+type stateTrie struct {
+	tx      kv.RwTx
+	number  uint64
+	parent  types.Hash
+	rules   *params.Rules
+	root    *types.Hash
+	witness *TrieWitness
+}
+
+// TrieWitness holds the nodes of a parent state trie that are read to compute
+// the state root of a block. Applying the block's changes to them gives the
+// block's root without the rest of the trie.
+type TrieWitness struct {
+	ParentRoot types.Hash `json:"parentRoot"`
+	Nodes      [][]byte   `json:"nodes"`
+}
+
+// UseStateTrie makes IntermediateRoot return the Merkle Patricia root of the
+// whole state instead of the hash of the accounts changed by the block with
+// the given number. parentRoot is the Merkle Patricia root of the parent
+// block's state, EmptyTrieRoot for the genesis block. Its trie must be stored;
+// at the fork block the parent has none, so it is built with BuildStateTrie
+// first. rules must be the rules the block's state is committed with.
+func (sdb *IntraBlockState) UseStateTrie(tx kv.RwTx, number uint64, parentRoot types.Hash, rules *params.Rules) {
+	sdb.stateTrie = &stateTrie{tx: tx, number: number, parent: parentRoot, rules: rules}
+}
+
+// commit applies the accounts and storage slots changed in sdb to the parent
+// trie, writes the new nodes and returns the new root.
+func (t *stateTrie) commit(sdb *IntraBlockState) (types.Hash, error) {
+	if t.root != nil {
+		return *t.root, nil
+	}
+	store := newTrieNodeStore(t.tx, t.tx)
+	store.reads = make(map[string][]byte)
+	db := trie.NewDatabase(store)
+
+	// The empty trie has no nodes, any other parent trie must be stored
+	if t.parent != EmptyTrieRoot {
+		stored, err := t.tx.Has(modules.TrieNode, t.parent[:])
+		if nil != err {
+			return types.Hash{}, err
+		}
+		if !stored {
+			return types.Hash{}, fmt.Errorf("%w: %x", ErrTrieNotStored, t.parent)
+		}
+	}
+	accounts, err := trie.NewStateTrie(gcommon.Hash{}, gcommon.Hash(t.parent), db)
+	if nil != err {
+		return types.Hash{}, err
+	}
+
+	// Same as CommitBlock, pending balance increases are applied to their
+	// accounts, which become dirty.
+	for addr, bi := range sdb.balanceInc {
+		if !bi.transferred {
+			sdb.getStateObject(addr)
+		}
+	}
+	dirty := make(map[types.Address]struct{}, len(sdb.stateObjectsDirty)+len(sdb.journal.dirties))
+	for addr := range sdb.stateObjectsDirty {
+		dirty[addr] = struct{}{}
+	}
+	for addr := range sdb.journal.dirties {
+		dirty[addr] = struct{}{}
+	}
+
+	nodes := trie.NewMergedNodeSet()
+	for addr := range dirty {
+		so, ok := sdb.stateObjects[addr]
+		if !ok {
+			continue
+		}
+		// Same as updateAccount, which decides what CommitBlock writes.
+		emptyRemoval := t.rules.IsSpuriousDragon && so.empty() && (!t.rules.IsAura || addr != SystemAddress)
+		if so.deleted || emptyRemoval || (so.selfdestructed && !so.created) {
+			if err := accounts.TryDeleteAccount(addr[:]); nil != err {
+				return types.Hash{}, err
+			}
+			continue
+		}
+
+		// Storage only lives in contract incarnations, a new incarnation
+		// starts with empty storage.
+		storageRoot := EmptyTrieRoot
+		if !so.created && so.data.Incarnation > 0 {
+			prev, err := accounts.TryGetAccount(addr[:])
+			if nil != err {
+				return types.Hash{}, err
+			}
+			if prev != nil {
+				storageRoot = types.Hash(prev.Root)
+			}
+		}
+		if len(so.dirtyStorage) > 0 {
+			if storageRoot, err = updateStorageTrie(db, nodes, addr, storageRoot, so.dirtyStorage); nil != err {
+				return types.Hash{}, err
+			}
+		}
+		if err := accounts.TryUpdateAccount(addr[:], trieAccount(&so.data, storageRoot)); nil != err {
+			return types.Hash{}, err
+		}
+	}
+
+	root, set, err := accounts.Commit(true)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	if set != nil {
+		if err := nodes.Merge(set); nil != err {
+			return types.Hash{}, err
+		}
+	}
+	if err := db.Update(nodes); nil != err {
+		return types.Hash{}, err
+	}
+	if err := db.Commit(root, false, nil); nil != err {
+		return types.Hash{}, err
+	}
+	newRoot := types.Hash(root)
+	if err := writeTrieRoot(t.tx, t.number, newRoot); nil != err {
+		return types.Hash{}, err
+	}
+	t.witness = &TrieWitness{ParentRoot: t.parent, Nodes: make([][]byte, 0, len(store.reads))}
+	for _, node := range store.reads {
+		t.witness.Nodes = append(t.witness.Nodes, node)
+	}
+	t.root = &newRoot
+	return newRoot, nil
+}
+
+// TrieWitness returns the witness of the Merkle Patricia root computed by
+// IntermediateRoot, nil if no such root has been computed.
+func (sdb *IntraBlockState) TrieWitness() *TrieWitness {
+	if sdb.stateTrie == nil {
+		return nil
+	}
+	return sdb.stateTrie.witness
+}
+
+// WriteTrieWitness stores the nodes of a witness, so that the state root of
+// its block can be computed on top of the witness' parent root. The nodes are
+// keyed by their hashes, a node that does not belong to the parent trie is
+// never read.
+func WriteTrieWitness(tx kv.RwTx, w *TrieWitness) error {
+	for _, node := range w.Nodes {
+		if err := tx.Put(modules.TrieNode, crypto.Keccak256(node), node); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTrieRoot makes the block with the given number refer to the state trie
+// with the given root, in place of the trie it referred to before.
+func writeTrieRoot(tx kv.RwTx, number uint64, root types.Hash) error {
+	key := modules.EncodeBlockNumber(number)
+	prev, err := tx.GetOne(modules.TrieRoot, key)
+	if nil != err {
+		return err
+	}
+	prevRoot := types.BytesToHash(prev)
+	if root == EmptyTrieRoot {
+		err = tx.Delete(modules.TrieRoot, key)
+	} else if err = addTrieNodeRef(tx, tx, root[:]); nil == err {
+		err = tx.Put(modules.TrieRoot, key, root[:])
+	}
+	if nil != err {
+		return err
+	}
+	// The new reference is taken first, the tries may share nodes
+	if len(prev) == types.HashLength {
+		return releaseTrie(tx, prevRoot)
+	}
+	return nil
+}
+
+// releaseTrieRoots drops the references of the blocks in [from, to) to their
+// state tries.
+func releaseTrieRoots(tx kv.RwTx, from, to uint64) error {
+	var (
+		keys  [][]byte
+		roots []types.Hash
+	)
+	c, err := tx.Cursor(modules.TrieRoot)
+	if nil != err {
+		return err
+	}
+	for k, v, err := c.Seek(modules.EncodeBlockNumber(from)); k != nil; k, v, err = c.Next() {
+		if nil != err {
+			c.Close()
+			return err
+		}
+		if number, _ := modules.DecodeBlockNumber(k); number >= to {
+			break
+		}
+		keys = append(keys, types.CopyBytes(k))
+		roots = append(roots, types.BytesToHash(v))
+	}
+	c.Close()
+
+	for i, key := range keys {
+		if err := tx.Delete(modules.TrieRoot, key); nil != err {
+			return err
+		}
+		if err := releaseTrie(tx, roots[i]); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStorageTrie applies the changed slots of an account to its storage trie
+// and returns the new storage root. The changed nodes are added to nodes.
+func updateStorageTrie(db *trie.Database, nodes *trie.MergedNodeSet, addr types.Address, root types.Hash, changes Storage) (types.Hash, error) {
+	st, err := trie.NewStateTrie(gcommon.Hash(crypto.Keccak256Hash(addr[:])), gcommon.Hash(root), db)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	for key, value := range changes {
+		if value.IsZero() {
+			err = st.TryDelete(key[:])
+		} else {
+			err = st.TryUpdate(key[:], storageValue(value.Bytes()))
+		}
+		if nil != err {
+			return types.Hash{}, err
+		}
+	}
+	newRoot, set, err := st.Commit(false)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	if set != nil {
+		if err := nodes.Merge(set); nil != err {
+			return types.Hash{}, err
+		}
+	}
+	return types.Hash(newRoot), nil
+}
+
+// BuildStateTrie builds and stores the state trie of the plain state, which
+// must be the state after the block with the given number, and returns its
+// root. The block refers to the trie, so that the next block's trie is built
+// on top of it.
+func BuildStateTrie(tx kv.RwTx, number uint64) (types.Hash, error) {
+	db := trie.NewDatabase(newTrieNodeStore(tx, tx))
+	accounts, err := buildStateTrie(tx, db)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	root, set, err := accounts.Commit(true)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	if set != nil {
+		if err := db.Update(trie.NewWithNodeSet(set)); nil != err {
+			return types.Hash{}, err
+		}
+		if err := db.Commit(root, false, nil); nil != err {
+			return types.Hash{}, err
+		}
+	}
+	newRoot := types.Hash(root)
+	if err := writeTrieRoot(tx, number, newRoot); nil != err {
+		return types.Hash{}, err
+	}
+	return newRoot, nil
+}
+
+// ParentTrieRoot returns the root of the state trie the block with the given
+// number is computed on, given the state root of its parent. Blocks before the
+// Merkle fork have no state trie, so at the fork block the trie of the parent
+// is built from the plain state, which must be the state of the parent.
+func ParentTrieRoot(tx kv.RwTx, config *params.ChainConfig, number uint64, parentRoot types.Hash) (types.Hash, error) {
+	if number > 0 && !config.IsMerkle(number-1) {
+		return BuildStateTrie(tx, number-1)
+	}
+	return parentRoot, nil
+}
+
+// buildStateTrie builds the account trie of the plain state. The storage tries
+// are written out as they are completed, the account trie is left uncommitted.
+func buildStateTrie(tx kv.RwTx, db *trie.Database) (*trie.StateTrie, error) {
+	log.Info("Building state trie from the plain state")
+
+	accounts, err := trie.NewStateTrie(gcommon.Hash{}, gcommon.Hash{}, db)
+	if nil != err {
+		return nil, err
+	}
+	count := 0
+	err = tx.ForEach(modules.Account, nil, func(k, v []byte) error {
+		var acc account.StateAccount
+		if err := acc.DecodeForStorage(v); nil != err {
+			return err
+		}
+		addr := types.BytesToAddress(k)
+		if acc.Incarnation > 0 && (acc.IsEmptyCodeHash() || acc.CodeHash == (types.Hash{})) {
+			codeHash, err := tx.GetOne(modules.PlainContractCode, modules.PlainGenerateStoragePrefix(addr[:], acc.Incarnation))
+			if nil != err {
+				return err
+			}
+			if len(codeHash) > 0 {
+				acc.CodeHash = types.BytesToHash(codeHash)
+			}
+		}
+
+		storageRoot := EmptyTrieRoot
+		if acc.Incarnation > 0 {
+			if storageRoot, err = buildStorageTrie(tx, db, addr, acc.Incarnation); nil != err {
+				return err
+			}
+		}
+		if count++; count%100000 == 0 {
+			log.Info("Building state trie", "accounts", count)
+		}
+		return accounts.TryUpdateAccount(addr[:], trieAccount(&acc, storageRoot))
+	})
+	if nil != err {
+		return nil, err
+	}
+	log.Info("Built state trie", "accounts", count)
+	return accounts, nil
+}
+
+// buildStorageTrie builds and writes out the storage trie of an account
+// incarnation.
+func buildStorageTrie(tx kv.Tx, db *trie.Database, addr types.Address, incarnation uint16) (types.Hash, error) {
+	st, err := trie.NewStateTrie(gcommon.Hash(crypto.Keccak256Hash(addr[:])), gcommon.Hash{}, db)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	prefix := modules.PlainGenerateCompositeStorageKey(addr[:], incarnation, nil)[:types.AddressLength+types.IncarnationLength]
+	if err := tx.ForPrefix(modules.Storage, prefix, func(k, v []byte) error {
+		if len(k) != len(prefix)+types.HashLength || len(types.TrimLeftZeroes(v)) == 0 {
+			return nil
+		}
+		return st.TryUpdate(k[len(prefix):], storageValue(v))
+	}); nil != err {
+		return types.Hash{}, err
+	}
+
+	root, set, err := st.Commit(false)
+	if nil != err {
+		return types.Hash{}, err
+	}
+	if set == nil {
+		return types.Hash(root), nil
+	}
+	if err := db.Update(trie.NewWithNodeSet(set)); nil != err {
+		return types.Hash{}, err
+	}
+	if err := db.Commit(root, false, nil); nil != err {
+		return types.Hash{}, err
+	}
+	return types.Hash(root), nil
+}
+
+// trieAccount returns the Ethereum trie representation of an account.
+func trieAccount(acc *account.StateAccount, storageRoot types.Hash) *gtypes.StateAccount {
+	codeHash := acc.CodeHash
+	if codeHash == (types.Hash{}) {
+		codeHash = emptyCodeHashH
+	}
+	return &gtypes.StateAccount{
+		Nonce:    acc.Nonce,
+		Balance:  acc.Balance.ToBig(),
+		Root:     gcommon.Hash(storageRoot),
+		CodeHash: codeHash.Bytes(),
+	}
+}
+
+// storageValue returns the trie representation of a storage value.
+func storageValue(v []byte) []byte {
+	enc, _ := rlp.EncodeToBytes(types.TrimLeftZeroes(v))
+	return enc
+}
+
+// AccountProof is the Merkle proof of an account and some of its storage slots.
+type AccountProof struct {
+	Nonce       uint64
+	Balance     *uint256.Int
+	CodeHash    types.Hash
+	StorageHash types.Hash
+	Proof       [][]byte
+	Storage     []StorageProof
+}
+
+// StorageProof is the Merkle proof of a storage slot.
+type StorageProof struct {
+	Key   types.Hash
+	Value *uint256.Int
+	Proof [][]byte
+}
+
+// ProveAccount returns the proof of the account at addr and of the given
+// storage slots in the state trie with the given root. Accounts and slots that
+// do not exist are proven absent.
+func ProveAccount(tx kv.Getter, root types.Hash, addr types.Address, keys []types.Hash) (*AccountProof, error) {
+	db := trie.NewDatabase(newTrieNodeStore(tx, nil))
+	accounts, err := trie.NewStateTrie(gcommon.Hash{}, gcommon.Hash(root), db)
+	if nil != err {
+		return nil, err
+	}
+
+	res := &AccountProof{
+		Balance:     new(uint256.Int),
+		CodeHash:    emptyCodeHashH,
+		StorageHash: EmptyTrieRoot,
+		Storage:     make([]StorageProof, 0, len(keys)),
+	}
+	var proof proofList
+	if err := accounts.Prove(addr[:], 0, &proof); nil != err {
+		return nil, err
+	}
+	res.Proof = proof
+
+	acc, err := accounts.TryGetAccount(addr[:])
+	if nil != err {
+		return nil, err
+	}
+	var storage *trie.StateTrie
+	if acc != nil {
+		res.Nonce = acc.Nonce
+		res.Balance, _ = uint256.FromBig(acc.Balance)
+		res.CodeHash = types.BytesToHash(acc.CodeHash)
+		res.StorageHash = types.Hash(acc.Root)
+		if res.StorageHash != EmptyTrieRoot {
+			if storage, err = trie.NewStateTrie(gcommon.Hash(crypto.Keccak256Hash(addr[:])), acc.Root, db); nil != err {
+				return nil, err
+			}
+		}
+	}
+
+	for _, key := range keys {
+		key := key
+		sp := StorageProof{Key: key, Value: new(uint256.Int), Proof: [][]byte{}}
+		if storage != nil {
+			var proof proofList
+			if err := storage.Prove(key[:], 0, &proof); nil != err {
+				return nil, err
+			}
+			sp.Proof = proof
+
+			enc, err := storage.TryGet(key[:])
+			if nil != err {
+				return nil, err
+			}
+			if len(enc) > 0 {
+				_, content, _, err := rlp.Split(enc)
+				if nil != err {
+					return nil, err
+				}
+				sp.Value.SetBytes(content)
+			}
+		}
+		res.Storage = append(res.Storage, sp)
+	}
+	return res, nil
+}
@@ -0,0 +1,333 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+var (
+	errTrieNodeNotFound = errors.New("trie node not found")
+	errTrieNodeReadOnly = errors.New("trie node store is read-only")
+	errNotSupported     = errors.New("not supported by the trie node store")
+)
+
+// trieNodeStore exposes the TrieNode table of a transaction as the key-value
+// store backing a trie database. Only the operations used to resolve and
+// commit trie nodes are supported.
+type trieNodeStore struct {
+	getter kv.Getter
+	putter kv.Putter         // nil for a read-only store
+	reads  map[string][]byte // nodes read, if recorded
+}
+
+func newTrieNodeStore(getter kv.Getter, putter kv.Putter) *trieNodeStore {
+	return &trieNodeStore{getter: getter, putter: putter}
+}
+
+func (s *trieNodeStore) Has(key []byte) (bool, error) {
+	return s.getter.Has(modules.TrieNode, key)
+}
+
+// Get returns a copy of the node, as the trie keeps references to it beyond
+// the lifetime of the transaction's memory map.
+func (s *trieNodeStore) Get(key []byte) ([]byte, error) {
+	v, err := s.getter.GetOne(modules.TrieNode, key)
+	if nil != err {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errTrieNodeNotFound
+	}
+	v = types.CopyBytes(v)
+	if s.reads != nil {
+		s.reads[string(key)] = v
+	}
+	return v, nil
+}
+
+// Put stores a node and counts its references to other nodes. A node already
+// stored is left as it is, its content is given by its hash.
+func (s *trieNodeStore) Put(key []byte, value []byte) error {
+	if s.putter == nil {
+		return errTrieNodeReadOnly
+	}
+	stored, err := s.getter.Has(modules.TrieNode, key)
+	if nil != err || stored {
+		return err
+	}
+	if err := s.putter.Put(modules.TrieNode, key, value); nil != err {
+		return err
+	}
+	return trieNodeRefs(value, func(ref []byte) error {
+		return addTrieNodeRef(s.getter, s.putter, ref)
+	})
+}
+
+// Delete is not supported, trie nodes are shared by the tries of many blocks
+// and only removed by releaseTrie once nothing refers to them.
+func (s *trieNodeStore) Delete(key []byte) error {
+	return errNotSupported
+}
+
+func (s *trieNodeStore) Stat(property string) (string, error) {
+	return "", errNotSupported
+}
+
+func (s *trieNodeStore) NewBatch() ethdb.Batch {
+	return &trieNodeBatch{store: s}
+}
+
+func (s *trieNodeStore) NewBatchWithSize(size int) ethdb.Batch {
+	return &trieNodeBatch{store: s, writes: make([]trieNodeWrite, 0, size)}
+}
+
+func (s *trieNodeStore) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return errIterator{}
+}
+
+func (s *trieNodeStore) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
+func (s *trieNodeStore) NewSnapshot() (ethdb.Snapshot, error) {
+	return nil, errNotSupported
+}
+
+func (s *trieNodeStore) Close() error {
+	return nil
+}
+
+type trieNodeWrite struct {
+	key   []byte
+	value []byte
+}
+
+// trieNodeBatch buffers trie node writes until Write.
+type trieNodeBatch struct {
+	store  *trieNodeStore
+	writes []trieNodeWrite
+	size   int
+}
+
+func (b *trieNodeBatch) Put(key []byte, value []byte) error {
+	b.writes = append(b.writes, trieNodeWrite{types.CopyBytes(key), types.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+func (b *trieNodeBatch) Delete(key []byte) error {
+	return errNotSupported
+}
+
+func (b *trieNodeBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *trieNodeBatch) Write() error {
+	for _, w := range b.writes {
+		if err := b.store.Put(w.key, w.value); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *trieNodeBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+func (b *trieNodeBatch) Replay(w ethdb.KeyValueWriter) error {
+	for _, write := range b.writes {
+		if err := w.Put(write.key, write.value); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// errIterator is the empty iterator returned by the unsupported NewIterator.
+type errIterator struct{}
+
+func (errIterator) Next() bool    { return false }
+func (errIterator) Error() error  { return errNotSupported }
+func (errIterator) Key() []byte   { return nil }
+func (errIterator) Value() []byte { return nil }
+func (errIterator) Release()      {}
+
+func readTrieNodeRefs(db kv.Getter, hash []byte) (uint64, error) {
+	v, err := db.GetOne(modules.TrieNodeRefs, hash)
+	if nil != err || len(v) != 8 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func addTrieNodeRef(getter kv.Getter, putter kv.Putter, hash []byte) error {
+	refs, err := readTrieNodeRefs(getter, hash)
+	if nil != err {
+		return err
+	}
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], refs+1)
+	return putter.Put(modules.TrieNodeRefs, hash, v[:])
+}
+
+// releaseTrie drops a reference to the node with the given hash. A node no
+// longer referenced is removed, releasing the nodes it refers to in turn.
+// Hashes never referenced, like the state roots of blocks before the merkle
+// fork, are ignored.
+func releaseTrie(tx kv.RwTx, hash types.Hash) error {
+	pending := [][]byte{types.CopyBytes(hash[:])}
+	for len(pending) > 0 {
+		key := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		refs, err := readTrieNodeRefs(tx, key)
+		if nil != err {
+			return err
+		}
+		switch {
+		case refs == 0:
+			continue
+		case refs > 1:
+			var v [8]byte
+			binary.BigEndian.PutUint64(v[:], refs-1)
+			if err := tx.Put(modules.TrieNodeRefs, key, v[:]); nil != err {
+				return err
+			}
+			continue
+		}
+
+		enc, err := tx.GetOne(modules.TrieNode, key)
+		if nil != err {
+			return err
+		}
+		if err := trieNodeRefs(enc, func(ref []byte) error {
+			pending = append(pending, types.CopyBytes(ref))
+			return nil
+		}); nil != err {
+			return err
+		}
+		if err := tx.Delete(modules.TrieNodeRefs, key); nil != err {
+			return err
+		}
+		if err := tx.Delete(modules.TrieNode, key); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// trieNodeRefs calls f with the hashes of the stored nodes a trie node refers
+// to: its children and, for a leaf of the account trie, the root of the
+// account's storage trie. Nodes embedded in their parent are shorter than a
+// hash and refer to no stored node.
+func trieNodeRefs(enc []byte, f func(hash []byte) error) error {
+	if len(enc) == 0 {
+		return nil
+	}
+	elems, _, err := rlp.SplitList(enc)
+	if nil != err {
+		return err
+	}
+	n, err := rlp.CountValues(elems)
+	if nil != err {
+		return err
+	}
+	switch n {
+	case 2:
+		key, rest, err := rlp.SplitString(elems)
+		if nil != err {
+			return err
+		}
+		kind, val, _, err := rlp.Split(rest)
+		if nil != err {
+			return err
+		}
+		// The flag nibble of the compact key tells leaves from extensions
+		if len(key) > 0 && key[0]>>4 >= 2 {
+			if kind == rlp.String {
+				return accountStorageRef(val, f)
+			}
+			return nil
+		}
+		if kind == rlp.String && len(val) == types.HashLength {
+			return f(val)
+		}
+		return nil
+	case 17:
+		for i := 0; i < 16; i++ {
+			kind, val, rest, err := rlp.Split(elems)
+			if nil != err {
+				return err
+			}
+			if kind == rlp.String && len(val) == types.HashLength {
+				if err := f(val); nil != err {
+					return err
+				}
+			}
+			elems = rest
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid trie node with %d elements", n)
+}
+
+// accountStorageRef calls f with the storage root held by an account leaf.
+// Leaves of storage tries hold plain values and are skipped.
+func accountStorageRef(val []byte, f func(hash []byte) error) error {
+	fields, _, err := rlp.SplitList(val)
+	if nil != err {
+		return nil
+	}
+	if n, err := rlp.CountValues(fields); nil != err || n != 4 {
+		return nil
+	}
+	// Nonce and balance come before the storage root
+	for i := 0; i < 2; i++ {
+		if _, _, fields, err = rlp.Split(fields); nil != err {
+			return nil
+		}
+	}
+	kind, root, _, err := rlp.Split(fields)
+	if nil != err || kind != rlp.String || len(root) != types.HashLength || types.BytesToHash(root) == EmptyTrieRoot {
+		return nil
+	}
+	return f(root)
+}
+
+// proofList collects the nodes of a Merkle proof in root-to-leaf order.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	return errNotSupported
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/params"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+func newTrieTestTx(t *testing.T) kv.RwTx {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	_, tx := memdb.NewTestTx(t)
+	return tx
+}
+
+// commitTrieBlock applies f to the plain state as the given block and returns
+// the state root computed on top of the parent trie.
+func commitTrieBlock(t *testing.T, tx kv.RwTx, number uint64, parent types.Hash, f func(s *IntraBlockState)) types.Hash {
+	t.Helper()
+	rules := &params.Rules{}
+	ibs := New(NewPlainStateReader(tx))
+	ibs.UseStateTrie(tx, number, parent, rules)
+	f(ibs)
+	if err := ibs.CommitBlock(rules, NewPlainStateWriter(tx, tx, number)); err != nil {
+		t.Fatal(err)
+	}
+	root := ibs.IntermediateRoot()
+	if err := ibs.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func countEntries(t *testing.T, tx kv.Tx, table string) int {
+	t.Helper()
+	n := 0
+	if err := tx.ForEach(table, nil, func(k, v []byte) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestReleaseTrie(t *testing.T) {
+	tx := newTrieTestTx(t)
+	a, b, c := types.Address{0x0a}, types.Address{0x0b}, types.Address{0x0c}
+	slot := types.Hash{0x01}
+
+	root1 := commitTrieBlock(t, tx, 1, EmptyTrieRoot, func(s *IntraBlockState) {
+		s.AddBalance(a, uint256.NewInt(10))
+		s.CreateAccount(c, true)
+		s.SetNonce(c, 1)
+		s.SetState(c, &slot, *uint256.NewInt(1))
+	})
+	// The storage trie of c is shared by both blocks
+	root2 := commitTrieBlock(t, tx, 2, root1, func(s *IntraBlockState) {
+		s.AddBalance(a, uint256.NewInt(5))
+		s.AddBalance(b, uint256.NewInt(1))
+	})
+	if root1 == root2 {
+		t.Fatal("state roots of different states are equal")
+	}
+
+	// Pruning block 1 keeps the nodes of block 2
+	if err := releaseTrieRoots(tx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProveAccount(tx, root1, a, nil); err == nil {
+		t.Error("released trie still readable")
+	}
+	proof, err := ProveAccount(tx, root2, c, []types.Hash{slot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Nonce != 1 || proof.Storage[0].Value.Uint64() != 1 {
+		t.Errorf("have nonce %d and storage %v, want 1 and 1", proof.Nonce, proof.Storage[0].Value)
+	}
+	if proof, err := ProveAccount(tx, root2, a, nil); err != nil || proof.Balance.Uint64() != 15 {
+		t.Errorf("have balance %v (err %v), want 15", proof.Balance, err)
+	}
+
+	// Releasing block 2 leaves no node behind
+	if err := releaseTrieRoots(tx, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{modules.TrieNode, modules.TrieNodeRefs, modules.TrieRoot} {
+		if n := countEntries(t, tx, table); n != 0 {
+			t.Errorf("%d entries left in %s", n, table)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/params"
+	"github.com/holiman/uint256"
+)
+
+var (
+	trieAddrA = types.Address{0x0a}
+	trieAddrB = types.Address{0x0b}
+	trieAddrC = types.Address{0x0c}
+	trieSlot1 = types.Hash{0x01}
+	trieSlot2 = types.Hash{0x02}
+)
+
+func trieBlock1(s *IntraBlockState) {
+	s.AddBalance(trieAddrA, uint256.NewInt(10))
+	s.AddBalance(trieAddrB, uint256.NewInt(20))
+	s.CreateAccount(trieAddrC, true)
+	s.SetNonce(trieAddrC, 1)
+	s.SetCode(trieAddrC, []byte{0x60, 0x00})
+	s.SetState(trieAddrC, &trieSlot1, *uint256.NewInt(1))
+	s.SetState(trieAddrC, &trieSlot2, *uint256.NewInt(2))
+}
+
+func trieBlock2(s *IntraBlockState) {
+	s.AddBalance(trieAddrA, uint256.NewInt(5))
+	s.SetState(trieAddrC, &trieSlot1, *uint256.NewInt(3))
+	s.SetState(trieAddrC, &trieSlot2, uint256.Int{})
+	s.AddBalance(types.Address{0x0d}, uint256.NewInt(1))
+}
+
+func TestStateTrieRoot(t *testing.T) {
+	tx := newTrieTestTx(t)
+
+	// The roots computed block by block match the trie built from the state
+	root1 := commitTrieBlock(t, tx, 1, EmptyTrieRoot, trieBlock1)
+	if built, err := BuildStateTrie(tx, 1); err != nil || built != root1 {
+		t.Fatalf("block 1: have root %x (err %v), built %x", root1, err, built)
+	}
+	root2 := commitTrieBlock(t, tx, 2, root1, trieBlock2)
+	if built, err := BuildStateTrie(tx, 2); err != nil || built != root2 {
+		t.Fatalf("block 2: have root %x (err %v), built %x", root2, err, built)
+	}
+	if root1 == root2 {
+		t.Fatal("state roots of different states are equal")
+	}
+}
+
+func TestStateTrieNotStored(t *testing.T) {
+	tx := newTrieTestTx(t)
+	commitTrieBlock(t, tx, 1, EmptyTrieRoot, trieBlock1)
+
+	// The trie of the parent is never built on the fly
+	rules := &params.Rules{}
+	ibs := New(NewPlainStateReader(tx))
+	ibs.UseStateTrie(tx, 2, types.Hash{0x01}, rules)
+	trieBlock2(ibs)
+	if root := ibs.IntermediateRoot(); root != (types.Hash{}) {
+		t.Errorf("have root %x, want none", root)
+	}
+	if err := ibs.Error(); !errors.Is(err, ErrTrieNotStored) {
+		t.Errorf("have %v, want %v", err, ErrTrieNotStored)
+	}
+}
+
+func TestTrieWitness(t *testing.T) {
+	tx := newTrieTestTx(t)
+	root1 := commitTrieBlock(t, tx, 1, EmptyTrieRoot, trieBlock1)
+
+	// A copy of the plain state after block 1, without its trie
+	vtx := newTrieTestTx(t)
+	for _, table := range []string{modules.Account, modules.Storage, modules.Code, modules.PlainContractCode} {
+		if err := tx.ForEach(table, nil, func(k, v []byte) error {
+			return vtx.Put(table, k, v)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := &params.Rules{}
+	ibs := New(NewPlainStateReader(tx))
+	ibs.UseStateTrie(tx, 2, root1, rules)
+	trieBlock2(ibs)
+	root2 := ibs.IntermediateRoot()
+	if err := ibs.Error(); err != nil {
+		t.Fatal(err)
+	}
+	witness := ibs.TrieWitness()
+	if witness == nil || witness.ParentRoot != root1 || len(witness.Nodes) == 0 {
+		t.Fatalf("have witness %v, want nodes of %x", witness, root1)
+	}
+
+	// The witness alone gives the root of block 2
+	if err := WriteTrieWitness(vtx, witness); err != nil {
+		t.Fatal(err)
+	}
+	vibs := New(NewPlainStateReader(vtx))
+	vibs.UseStateTrie(vtx, 2, witness.ParentRoot, rules)
+	trieBlock2(vibs)
+	if root := vibs.IntermediateRoot(); root != root2 {
+		t.Errorf("have root %x (err %v), want %x", root, vibs.Error(), root2)
+	}
+}
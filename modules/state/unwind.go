@@ -0,0 +1,116 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/amazechain/amc/common/account"
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/changeset"
+	"github.com/amazechain/amc/modules/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// UnwindState reverts the plain state from block current back to the state
+// after block to, using the account and storage change sets of the blocks in
+// between. The change sets and history indices of the unwound blocks are
+// removed, and so are the nodes of their state tries no other block shares.
+func UnwindState(tx kv.RwTx, current, to uint64) error {
+	if to >= current {
+		return nil
+	}
+	for number := current; number > to; number-- {
+		if err := unwindBlock(tx, number); nil != err {
+			return fmt.Errorf("unwind state of block %d: %w", number, err)
+		}
+	}
+	if err := releaseTrieRoots(tx, to+1, current+1); nil != err {
+		return err
+	}
+	return changeset.Truncate(tx, to+1)
+}
+
+// unwindBlock restores the values the accounts and storage slots changed by
+// the given block had before it, and drops the block from their history.
+func unwindBlock(tx kv.RwTx, number uint64) error {
+	prefix := modules.EncodeBlockNumber(number)
+
+	var accounts, storage []changeset.Change
+	if err := changeset.ForPrefix(tx, modules.AccountChangeSet, prefix, func(_ uint64, k, v []byte) error {
+		accounts = append(accounts, changeset.Change{Key: types.CopyBytes(k), Value: types.CopyBytes(v)})
+		return nil
+	}); nil != err {
+		return err
+	}
+	if err := changeset.ForPrefix(tx, modules.StorageChangeSet, prefix, func(_ uint64, k, v []byte) error {
+		storage = append(storage, changeset.Change{Key: types.CopyBytes(k), Value: types.CopyBytes(v)})
+		return nil
+	}); nil != err {
+		return err
+	}
+
+	for _, change := range accounts {
+		if err := restoreAccount(tx, change.Key, change.Value); nil != err {
+			return err
+		}
+		if err := bitmapdb.TruncateRange64(tx, modules.AccountsHistory, change.Key, number); nil != err {
+			return err
+		}
+	}
+	for _, change := range storage {
+		if len(change.Value) == 0 {
+			if err := tx.Delete(modules.Storage, change.Key); nil != err {
+				return err
+			}
+		} else if err := tx.Put(modules.Storage, change.Key, change.Value); nil != err {
+			return err
+		}
+		if err := bitmapdb.TruncateRange64(tx, modules.StorageHistory, modules.CompositeKeyWithoutIncarnation(change.Key), number); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreAccount writes back the encoded account found in a change set. Change
+// sets omit the code hash of contracts, so it is recovered from the contract's
+// incarnation.
+func restoreAccount(tx kv.RwTx, addr, enc []byte) error {
+	if len(enc) == 0 {
+		return tx.Delete(modules.Account, addr)
+	}
+
+	var acc account.StateAccount
+	if err := acc.DecodeForStorage(enc); nil != err {
+		return err
+	}
+	if acc.Incarnation > 0 && acc.IsEmptyCodeHash() {
+		codeHash, err := tx.GetOne(modules.PlainContractCode, modules.PlainGenerateStoragePrefix(addr, acc.Incarnation))
+		if nil != err {
+			return err
+		}
+		if len(codeHash) > 0 {
+			acc.CodeHash = types.BytesToHash(codeHash)
+		}
+	}
+
+	data := make([]byte, acc.EncodingLengthForStorage())
+	acc.EncodeForStorage(data)
+	return tx.Put(modules.Account, addr, data)
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 The AmazeChain Authors
+// This file is part of the AmazeChain library.
+//
+// The AmazeChain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The AmazeChain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the AmazeChain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/amazechain/amc/common/types"
+	"github.com/amazechain/amc/modules"
+	"github.com/amazechain/amc/modules/changeset"
+	"github.com/amazechain/amc/params"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+)
+
+var (
+	unwindAddrA = types.Address{0x0a}
+	unwindAddrB = types.Address{0x0b}
+	unwindAddrC = types.Address{0x0c}
+	unwindSlot  = types.Hash{0x01}
+)
+
+// commitBlock applies f to the plain state and writes it with the change sets
+// of the given block, the way blocks are executed.
+func commitBlock(t *testing.T, tx kv.RwTx, number uint64, f func(s *IntraBlockState)) {
+	t.Helper()
+	ibs := New(NewPlainStateReader(tx))
+	f(ibs)
+	w := NewPlainStateWriter(tx, tx, number)
+	if err := ibs.CommitBlock(&params.Rules{}, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteChangeSets(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHistory(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkUnwindState(t *testing.T, tx kv.Tx, balanceA uint64, existsB bool, slotC uint64) {
+	t.Helper()
+	s := New(NewPlainStateReader(tx))
+	if have := s.GetBalance(unwindAddrA).Uint64(); have != balanceA {
+		t.Errorf("balance %d, want %d", have, balanceA)
+	}
+	if have := s.Exist(unwindAddrB); have != existsB {
+		t.Errorf("account exists %v, want %v", have, existsB)
+	}
+	var value uint256.Int
+	s.GetState(unwindAddrC, &unwindSlot, &value)
+	if have := value.Uint64(); have != slotC {
+		t.Errorf("storage %d, want %d", have, slotC)
+	}
+}
+
+func TestUnwindState(t *testing.T) {
+	modules.AmcInit()
+	kv.ChaindataTablesCfg = modules.AmcTableCfg
+	db := memdb.NewTestDB(t)
+	ctx := context.Background()
+
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		commitBlock(t, tx, 1, func(s *IntraBlockState) {
+			s.AddBalance(unwindAddrA, uint256.NewInt(10))
+			s.CreateAccount(unwindAddrC, true)
+			s.SetState(unwindAddrC, &unwindSlot, *uint256.NewInt(1))
+		})
+		commitBlock(t, tx, 2, func(s *IntraBlockState) {
+			s.AddBalance(unwindAddrA, uint256.NewInt(5))
+			s.AddBalance(unwindAddrB, uint256.NewInt(1))
+			s.SetState(unwindAddrC, &unwindSlot, *uint256.NewInt(2))
+		})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fork block failing on top of the unwound state rolls the unwind back
+	errBadBlock := errors.New("bad block")
+	err := db.Update(ctx, func(tx kv.RwTx) error {
+		if err := UnwindState(tx, 2, 1); err != nil {
+			return err
+		}
+		checkUnwindState(t, tx, 10, false, 1)
+		commitBlock(t, tx, 2, func(s *IntraBlockState) {
+			s.AddBalance(unwindAddrA, uint256.NewInt(100))
+		})
+		return errBadBlock
+	})
+	if !errors.Is(err, errBadBlock) {
+		t.Fatalf("have error %v, want %v", err, errBadBlock)
+	}
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		checkUnwindState(t, tx, 15, true, 2)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unwinding restores the state after block 1 and drops the change sets
+	// of block 2
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		if err := UnwindState(tx, 2, 1); err != nil {
+			return err
+		}
+		checkUnwindState(t, tx, 10, false, 1)
+		for _, table := range []string{modules.AccountChangeSet, modules.StorageChangeSet} {
+			if err := changeset.ForPrefix(tx, table, modules.EncodeBlockNumber(2), func(_ uint64, k, _ []byte) error {
+				t.Errorf("change of %x in block 2 left in %s", k, table)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
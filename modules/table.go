@@ -34,6 +34,10 @@ const (
 
 	// IncarnationMap "incarnation" - uint16 number - how much times given account was SelfDestruct'ed
 	IncarnationMap = "IncarnationMap" // address -> incarnation of account when it was last deleted
+
+	TrieNode     = "TrieNode"     // node hash -> rlp encoded node of the account and storage tries (from the merkle fork on)
+	TrieNodeRefs = "TrieNodeRefs" // node hash -> u64 number of references to the node from stored nodes and block state roots
+	TrieRoot     = "TrieRoot"     // block_num_u64 -> state root referenced by the block until it is pruned or unwound
 )
 
 // HistoryState
@@ -43,6 +47,9 @@ const (
 
 	StorageChangeSet = "StorageChangeSet" // blockNum_u64 + address + incarnation_u64 ->  plain_storage_key + value
 	StorageHistory   = "StorageHistory"   // address + storage_key + shard_id_u64 -> roaring bitmap - list of block where it changed
+
+	DepositChangeSet = "DepositChangeSet" // blockNum_u64 -> address + deposit info before the block (empty if there was none)
+	DepositHistory   = "DepositHistory"   // address + shard_id_u32 -> roaring bitmap - list of blocks whose change set holds the address
 )
 
 // Block
@@ -109,6 +116,9 @@ var AmcTables = []string{
 	Storage,
 	PlainContractCode,
 	IncarnationMap,
+	TrieNode,
+	TrieNodeRefs,
+	TrieRoot,
 
 	DatabaseInfo,
 	ChainConfig,
@@ -117,6 +127,8 @@ var AmcTables = []string{
 	AccountChangeSet,
 	StorageHistory,
 	StorageChangeSet,
+	DepositChangeSet,
+	DepositHistory,
 
 	Headers,
 	HeaderTD,
@@ -147,6 +159,7 @@ var AmcTables = []string{
 var AmcTableCfg = kv.TableCfg{
 	AccountChangeSet: {Flags: kv.DupSort},
 	StorageChangeSet: {Flags: kv.DupSort},
+	DepositChangeSet: {Flags: kv.DupSort},
 	Storage: {
 		Flags:                     kv.DupSort,
 		AutoDupSortKeysConversion: true,
@@ -308,6 +308,7 @@ type ChainConfig struct {
 	NanoBlock    *big.Int `json:"nanoBlock,omitempty" toml:",omitempty"`    // nanoBlock switch block (nil = no fork, 0 = already activated)
 	MoranBlock   *big.Int `json:"moranBlock,omitempty" toml:",omitempty"`   // moranBlock switch block (nil = no fork, 0 = already activated)
 	BeijingBlock *big.Int `json:"beijingBlock,omitempty" toml:",omitempty"` // beijingBlock switch block (nil = no fork, 0 = already activated)
+	MerkleBlock  *big.Int `json:"merkleBlock,omitempty" toml:",omitempty"`  // merkleBlock switch block to the Merkle Patricia state root (nil = no fork, 0 = already activated)
 	//Apos         *AposConfig `json:"apos,omitempty"`
 
 	// Gnosis Chain fork blocks
@@ -739,6 +740,11 @@ func (c *ChainConfig) IsBeijing(num uint64) bool {
 	return isForked(c.BeijingBlock, num)
 }
 
+// IsMerkle returns whether num is either equal to the Merkle fork block or greater.
+func (c *ChainConfig) IsMerkle(num uint64) bool {
+	return isForked(c.MerkleBlock, num)
+}
+
 func (c *ChainConfig) IsEip1559FeeCollector(num uint64) bool {
 	return c.Eip1559FeeCollector != nil && isForked(c.Eip1559FeeCollectorTransition, num)
 }
@@ -887,6 +893,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head uint64) *ConfigC
 	if isForkIncompatible(c.CancunBlock, newcfg.CancunBlock, head) {
 		return newCompatError("Cancun fork block", c.CancunBlock, newcfg.CancunBlock)
 	}
+	if isForkIncompatible(c.MerkleBlock, newcfg.MerkleBlock, head) {
+		return newCompatError("Merkle fork block", c.MerkleBlock, newcfg.MerkleBlock)
+	}
 
 	// Parlia forks
 	//if isForkIncompatible(c.RamanujanBlock, newcfg.RamanujanBlock, head) {